@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"pr-review-manager/internal/service"
+)
+
+// commandUsage описание подкоманд для вывода справки
+const commandUsage = `Usage: app [command] [flags]
+
+Commands:
+  serve    запустить HTTP-сервер (по умолчанию)
+  export   выгрузить команды, пользователей и членства
+  import   загрузить команды, пользователей и членства
+  backup   создать резервную копию БД
+  restore  восстановить резервную копию в пустую БД
+`
+
+// isCommand сообщает, поддерживается ли подкоманда
+func isCommand(name string) bool {
+	switch name {
+	case "serve", "export", "import", "backup", "restore":
+		return true
+	}
+	return false
+}
+
+// runCommand выполняет подкоманду CLI и возвращает код завершения процесса
+func runCommand(name string, args []string, svc *service.Service) int {
+	var err error
+	switch name {
+	case "export":
+		err = runExport(args, svc)
+	case "import":
+		err = runImport(args, svc)
+	case "backup":
+		err = runBackup(args, svc)
+	case "restore":
+		err = runRestore(args, svc)
+	}
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// runExport выгружает команды в файл или stdout (app export -format csv -o teams.csv)
+func runExport(args []string, svc *service.Service) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "json", "формат выгрузки: json или csv")
+	output := fs.String("o", "", "файл для выгрузки (по умолчанию stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := svc.ExportTeams()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return service.EncodeTeamsExport(*format, w, data)
+}
+
+// runImport загружает команды из файла или stdin (app import -format csv -dry-run teams.csv)
+func runImport(args []string, svc *service.Service) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "json", "формат данных: json или csv")
+	dryRun := fs.Bool("dry-run", false, "только проверить данные и показать изменения")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := service.DecodeTeamsExport(*format, r)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.ImportTeams(data, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("%d validation errors, nothing imported", len(resp.Errors))
+	}
+	return nil
+}
+
+// runBackup записывает резервную копию в файл или stdout (app backup -o backup.json.gz);
+// сводка по таблицам выводится в stderr
+func runBackup(args []string, svc *service.Service) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := fs.String("o", "", "файл резервной копии (по умолчанию stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	summary, err := svc.Backup(w)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
+
+// runRestore загружает резервную копию из файла или stdin (app restore backup.json.gz)
+func runRestore(args []string, svc *service.Service) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	summary, err := svc.Restore(r)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
@@ -2,19 +2,39 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"pr-review-manager/internal/codehost"
 	"pr-review-manager/internal/handlers"
+	"pr-review-manager/internal/models"
 	"pr-review-manager/internal/repository"
 	"pr-review-manager/internal/service"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	command := "serve"
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+	}
+	if !isCommand(command) {
+		fmt.Fprint(os.Stderr, commandUsage)
+		os.Exit(2)
+	}
+
+	// подкоманды CLI пишут данные в stdout, поэтому логи уходят в stderr
+	logOut := os.Stdout
+	if command != "serve" {
+		logOut = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
 
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -37,22 +57,154 @@ func main() {
 	}
 	logger.Info("Tables creation completed")
 
-	svc := service.NewService(storage, logger)
+	var opts []service.Option
+	// ASSIGNMENT_SEED делает выбор ревьюверов воспроизводимым
+	if seed := os.Getenv("ASSIGNMENT_SEED"); seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			logger.Error("Invalid ASSIGNMENT_SEED", "err", err)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithRandSource(rand.NewSource(n)))
+	}
+	// ASSIGNMENT_EXPLAIN включает отладочное объяснение выбора ревьюверов в ответах
+	if os.Getenv("ASSIGNMENT_EXPLAIN") == "true" {
+		opts = append(opts, service.WithExplain(true))
+	}
+
+	// IDEMPOTENCY_TTL задаёт время хранения ответов на запросы с Idempotency-Key
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			logger.Error("Invalid IDEMPOTENCY_TTL", "value", v, "err", err)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithIdempotencyTTL(ttl))
+	}
+
+	// истёкшие ключи идемпотентности удаляются фоновой задачей, а не на каждом запросе
+	idempotencyPurgeInterval := 10 * time.Minute
+
+	// ARCHIVE_AFTER_DAYS включает перенос в архив закрытых PR старше заданного числа дней,
+	// ARCHIVE_INTERVAL задаёт период запуска переноса
+	var archiveRetention time.Duration
+	archiveInterval := time.Hour
+	if v := os.Getenv("ARCHIVE_AFTER_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			logger.Error("Invalid ARCHIVE_AFTER_DAYS", "value", v, "err", err)
+			os.Exit(1)
+		}
+		archiveRetention = time.Duration(days) * 24 * time.Hour
+	}
+	if v := os.Getenv("ARCHIVE_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			logger.Error("Invalid ARCHIVE_INTERVAL", "value", v, "err", err)
+			os.Exit(1)
+		}
+		archiveInterval = interval
+	}
+
+	// WEBHOOK_DISPATCH_INTERVAL задаёт период публикации событий из outbox и отправки исходящих webhook
+	dispatchInterval := 5 * time.Second
+	if v := os.Getenv("WEBHOOK_DISPATCH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			logger.Error("Invalid WEBHOOK_DISPATCH_INTERVAL", "value", v, "err", err)
+			os.Exit(1)
+		}
+		dispatchInterval = interval
+	}
+
+	// GITHUB_TOKEN включает передачу назначенных ревьюверов в GitHub,
+	// GITHUB_API_URL задаёт адрес API для GitHub Enterprise
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		apiURL := os.Getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = codehost.DefaultGitHubAPIURL
+		}
+		opts = append(opts, service.WithCodeHost(models.CodeHostGitHub, codehost.NewGitHub(apiURL, token)))
+	}
+
+	svc := service.NewService(storage, logger, opts...)
+
+	if command != "serve" {
+		args := os.Args[2:]
+		code := runCommand(command, args, svc)
+		if err := storage.Close(); err != nil {
+			logger.Warn("Database close error", "err", err)
+		}
+		os.Exit(code)
+	}
+
 	h := handlers.NewHandler(svc, logger)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/stats/users", h.StatsUsersHandler)
 	mux.HandleFunc("/team/add", h.AddHandler)
 	mux.HandleFunc("/team/get", h.GetHandler)
+	mux.HandleFunc("/team/update", h.UpdateTeamHandler)
+	mux.HandleFunc("/team/addMember", h.AddMemberHandler)
+	mux.HandleFunc("/team/removeMember", h.RemoveMemberHandler)
+	mux.HandleFunc("/team/delete", h.DeleteTeamHandler)
+	mux.HandleFunc("/team/setParent", h.SetParentHandler)
+	mux.HandleFunc("/team/setPolicy", h.SetPolicyHandler)
+	mux.HandleFunc("/team/policy", h.PolicyHandler)
+	mux.HandleFunc("/team/setOrganization", h.SetOrganizationHandler)
+	mux.HandleFunc("/org/add", h.AddOrganizationHandler)
+	mux.HandleFunc("/org/get", h.GetOrganizationHandler)
+	mux.HandleFunc("/org/setPolicy", h.SetOrganizationPolicyHandler)
+	mux.HandleFunc("/team/rebalance", h.RebalanceHandler)
+	mux.HandleFunc("/team/export", h.ExportTeamsHandler)
+	mux.HandleFunc("/team/import", h.ImportTeamsHandler)
+	mux.HandleFunc("/team/setNotificationChannel", h.SetNotificationChannelHandler)
+	mux.HandleFunc("/team/deleteNotificationChannel", h.DeleteNotificationChannelHandler)
+	mux.HandleFunc("/team/notificationChannel", h.NotificationChannelHandler)
+	mux.HandleFunc("/users/get", h.GetUserHandler)
+	mux.HandleFunc("/users/update", h.UpdateUserHandler)
+	mux.HandleFunc("/users/delete", h.DeleteUserHandler)
 	mux.HandleFunc("/users/setIsActive", h.SetIsActiveHandler)
+	mux.HandleFunc("/users/setReviewWeight", h.SetReviewWeightHandler)
+	mux.HandleFunc("/users/moveTeam", h.MoveTeamHandler)
 	mux.HandleFunc("/users/getReview", h.GetReviewHandler)
+	mux.HandleFunc("/users/linkAccount", h.LinkAccountHandler)
+	mux.HandleFunc("/users/unlinkAccount", h.UnlinkAccountHandler)
+	mux.HandleFunc("/users/setNotifications", h.SetNotificationsHandler)
+	mux.HandleFunc("/pullRequest/get", h.GetPRHandler)
+	mux.HandleFunc("/pullRequest/list", h.ListPRHandler)
+	mux.HandleFunc("/pullRequest/archived", h.ListArchivedPRHandler)
 	mux.HandleFunc("/pullRequest/create", h.CreateHandler)
+	mux.HandleFunc("/pullRequest/batchCreate", h.BatchCreateHandler)
+	mux.HandleFunc("/pullRequest/update", h.UpdatePRHandler)
 	mux.HandleFunc("/pullRequest/merge", h.MergeHandler)
+	mux.HandleFunc("/pullRequest/close", h.ClosePRHandler)
+	mux.HandleFunc("/pullRequest/reopen", h.ReopenPRHandler)
+	mux.HandleFunc("/pullRequest/review", h.ReviewHandler)
 	mux.HandleFunc("/pullRequest/reassign", h.ReassignHandler)
+	mux.HandleFunc("/webhooks/subscribe", h.SubscribeWebhookHandler)
+	mux.HandleFunc("/webhooks/unsubscribe", h.UnsubscribeWebhookHandler)
+	mux.HandleFunc("/webhooks/list", h.ListWebhooksHandler)
+	mux.HandleFunc("/webhooks/deliveries", h.WebhookDeliveriesHandler)
+	mux.HandleFunc("/webhooks/deadLetters", h.DeadLettersHandler)
+	mux.HandleFunc("/webhooks/redeliver", h.RedeliverWebhookHandler)
+
+	// GITHUB_WEBHOOK_SECRET включает приём событий GitHub; без секрета подпись проверить нельзя
+	if secret := os.Getenv("GITHUB_WEBHOOK_SECRET"); secret != "" {
+		mux.Handle("/webhooks/github", handlers.NewGitHubWebhook(svc, logger, secret))
+	} else {
+		logger.Info("GITHUB_WEBHOOK_SECRET is not set, GitHub webhook disabled")
+	}
+	// GITLAB_WEBHOOK_TOKEN включает приём событий GitLab
+	if token := os.Getenv("GITLAB_WEBHOOK_TOKEN"); token != "" {
+		mux.Handle("/webhooks/gitlab", handlers.NewGitLabWebhook(svc, logger, token))
+	} else {
+		logger.Info("GITLAB_WEBHOOK_TOKEN is not set, GitLab webhook disabled")
+	}
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: h.Idempotency(mux),
 	}
 
 	go func() {
@@ -63,11 +215,23 @@ func main() {
 
 	logger.Info("Server started on :8080")
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if archiveRetention > 0 {
+		go svc.RunArchiver(jobsCtx, archiveRetention, archiveInterval)
+		logger.Info("PR archiver started", "retention", archiveRetention.String(), "interval", archiveInterval.String())
+	}
+	go svc.RunIdempotencyPurger(jobsCtx, idempotencyPurgeInterval)
+	go svc.RunOutboxDispatcher(jobsCtx, dispatchInterval)
+	go svc.RunWebhookDispatcher(jobsCtx, dispatchInterval)
+	go svc.RunEventJobDispatcher(jobsCtx, dispatchInterval)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("Shutdown signal received")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
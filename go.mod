@@ -2,4 +2,4 @@ module pr-review-manager
 
 go 1.22.2
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
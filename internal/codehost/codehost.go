@@ -0,0 +1,21 @@
+// Package codehost содержит клиенты code host (GitHub и т.п.), через которые сервис
+// сообщает code host о назначенных ревьюверах.
+package codehost
+
+import (
+	"context"
+	"errors"
+
+	"pr-review-manager/internal/models"
+)
+
+// ErrRejected возвращается, если code host отклонил запрос (ответ 4xx, кроме 429):
+// повтор не поможет, нужно исправить токен, права или привязку логина
+var ErrRejected = errors.New("request rejected by code host")
+
+// Client запрашивает и снимает ревью на PR code host. Логины передаются в том виде,
+// в каком они привязаны через /users/linkAccount.
+type Client interface {
+	RequestReviewers(ctx context.Context, ref models.ExternalPullRequestRef, logins []string) error
+	RemoveReviewers(ctx context.Context, ref models.ExternalPullRequestRef, logins []string) error
+}
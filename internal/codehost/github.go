@@ -0,0 +1,139 @@
+package codehost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+// DefaultGitHubAPIURL адрес REST API github.com
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHub клиент REST API GitHub. Запросы, завершившиеся сетевой ошибкой, 429 или 5xx,
+// повторяются с экспоненциальной задержкой; прочие ответы 4xx возвращают ErrRejected.
+type GitHub struct {
+	baseURL     string
+	token       string
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// GitHubOption настраивает клиент GitHub при создании
+type GitHubOption func(*GitHub)
+
+// WithHTTPClient задаёт HTTP-клиент вместо клиента по умолчанию
+func WithHTTPClient(c *http.Client) GitHubOption {
+	return func(g *GitHub) {
+		g.client = c
+	}
+}
+
+// WithRetry задаёт число попыток запроса и задержку перед первым повтором
+func WithRetry(maxAttempts int, backoff time.Duration) GitHubOption {
+	return func(g *GitHub) {
+		g.maxAttempts = maxAttempts
+		g.backoff = backoff
+	}
+}
+
+// NewGitHub создаёт клиент GitHub; baseURL — адрес API (для GitHub Enterprise вида https://host/api/v3)
+func NewGitHub(baseURL, token string, opts ...GitHubOption) *GitHub {
+	g := &GitHub{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		token:       token,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 3,
+		backoff:     time.Second,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// RequestReviewers запрашивает ревью у пользователей GitHub
+func (g *GitHub) RequestReviewers(ctx context.Context, ref models.ExternalPullRequestRef, logins []string) error {
+	return g.requestedReviewers(ctx, http.MethodPost, ref, logins)
+}
+
+// RemoveReviewers отменяет запрос ревью у пользователей GitHub
+func (g *GitHub) RemoveReviewers(ctx context.Context, ref models.ExternalPullRequestRef, logins []string) error {
+	return g.requestedReviewers(ctx, http.MethodDelete, ref, logins)
+}
+
+// requestedReviewers вызывает /repos/{owner}/{repo}/pulls/{number}/requested_reviewers
+func (g *GitHub) requestedReviewers(ctx context.Context, method string, ref models.ExternalPullRequestRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string][]string{"reviewers": logins})
+	if err != nil {
+		return fmt.Errorf("marshal reviewers: %w", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", g.baseURL, ref.Repository, ref.Number)
+	return g.do(ctx, method, url, body)
+}
+
+// do выполняет запрос с повторами; ответы 4xx, кроме 429, не повторяются
+func (g *GitHub) do(ctx context.Context, method, url string, body []byte) error {
+	delay := g.backoff
+	var lastErr error
+	for attempt := 1; attempt <= g.maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("github %s %s: %w (last error: %v)", method, url, ctx.Err(), lastErr)
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		retry, err := g.send(ctx, method, url, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return fmt.Errorf("github %s %s: %w", method, url, lastErr)
+}
+
+// send выполняет одну попытку запроса и сообщает, имеет ли смысл её повторить
+func (g *GitHub) send(ctx context.Context, method, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return false, fmt.Errorf("%w: status %d: %s", ErrRejected, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
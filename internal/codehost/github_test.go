@@ -0,0 +1,133 @@
+package codehost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+var testRef = models.ExternalPullRequestRef{Provider: models.CodeHostGitHub, Repository: "acme/api", Number: 42}
+
+func TestGitHubRequestPayload(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		method string
+		call   func(*GitHub) error
+	}{
+		{"request", http.MethodPost, func(g *GitHub) error {
+			return g.RequestReviewers(context.Background(), testRef, []string{"alice", "bob"})
+		}},
+		{"remove", http.MethodDelete, func(g *GitHub) error {
+			return g.RemoveReviewers(context.Background(), testRef, []string{"alice", "bob"})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tc.method {
+					t.Errorf("method = %s, want %s", r.Method, tc.method)
+				}
+				if r.URL.Path != "/repos/acme/api/pulls/42/requested_reviewers" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				for header, want := range map[string]string{
+					"Accept":               "application/vnd.github+json",
+					"Content-Type":         "application/json",
+					"X-GitHub-Api-Version": "2022-11-28",
+					"Authorization":        "Bearer ghp_test",
+				} {
+					if got := r.Header.Get(header); got != want {
+						t.Errorf("%s = %q, want %q", header, got, want)
+					}
+				}
+				var body map[string][]string
+				raw, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(raw, &body); err != nil {
+					t.Errorf("decode body %s: %v", raw, err)
+				}
+				if got := body["reviewers"]; len(got) != 2 || got[0] != "alice" || got[1] != "bob" {
+					t.Errorf("reviewers = %v", got)
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			if err := tc.call(NewGitHub(srv.URL+"/", "ghp_test")); err != nil {
+				t.Fatalf("call: %v", err)
+			}
+		})
+	}
+}
+
+func TestGitHubSkipsEmptyLogins(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	if err := NewGitHub(srv.URL, "").RequestReviewers(context.Background(), testRef, nil); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("calls = %d, want 0", calls.Load())
+	}
+}
+
+func TestGitHubRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		statuses  []int
+		wantCalls int32
+		wantErr   bool
+		rejected  bool
+	}{
+		{"429 then success", []int{http.StatusTooManyRequests, http.StatusCreated}, 2, false, false},
+		{"5xx then success", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusCreated}, 3, false, false},
+		{"5xx exhausts attempts", []int{http.StatusInternalServerError}, 3, true, false},
+		{"4xx not retried", []int{http.StatusUnprocessableEntity}, 1, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				w.WriteHeader(tc.statuses[min(n, len(tc.statuses))-1])
+			}))
+			defer srv.Close()
+
+			g := NewGitHub(srv.URL, "ghp_test", WithRetry(3, time.Millisecond))
+			err := g.RequestReviewers(context.Background(), testRef, []string{"alice"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if errors.Is(err, ErrRejected) != tc.rejected {
+				t.Fatalf("err = %v, want ErrRejected %v", err, tc.rejected)
+			}
+			if calls.Load() != tc.wantCalls {
+				t.Fatalf("calls = %d, want %d", calls.Load(), tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGitHubStopsOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	g := NewGitHub(srv.URL, "", WithRetry(5, time.Hour))
+	err := g.RequestReviewers(ctx, testRef, []string{"alice"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+}
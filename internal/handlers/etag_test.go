@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+	"pr-review-manager/internal/service"
+)
+
+// newDBHandler создаёт обработчик поверх тестовой БД из TEST_DATABASE_URL с пустыми таблицами.
+// Без TEST_DATABASE_URL тест пропускается.
+func newDBHandler(t *testing.T) *Handler {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st, err := repository.NewStorage(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if err := st.CreateTables(logger); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	var tables sql.NullString
+	err = db.QueryRow(`
+        SELECT string_agg(quote_ident(tablename), ', ')
+        FROM pg_tables WHERE schemaname = current_schema()
+    `).Scan(&tables)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	if tables.Valid {
+		if _, err := db.Exec(`TRUNCATE ` + tables.String + ` RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+	}
+	return NewHandler(service.NewService(st, nil), logger)
+}
+
+// call выполняет запрос к обработчику; ifMatch передаётся в заголовке If-Match, если не пуст
+func call(handler http.HandlerFunc, method, target, ifMatch, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// errorCode код ошибки из тела ответа
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var er models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &er); err != nil {
+		t.Fatalf("decode error %s: %v", rec.Body, err)
+	}
+	return er.ErrDetail.Code
+}
+
+// expectError проверяет статус и код ошибки ответа
+func expectError(t *testing.T, name string, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("%s: status %d, want %d: %s", name, rec.Code, status, rec.Body)
+	}
+	if got := errorCode(t, rec); got != code {
+		t.Errorf("%s: code %s, want %s", name, got, code)
+	}
+}
+
+func TestTeamETagAndIfMatch(t *testing.T) {
+	h := newDBHandler(t)
+
+	rec := call(h.AddHandler, http.MethodPost, "/team/add", "",
+		`{"team_name":"backend","members":[{"user_id":"u1","username":"alice","is_active":true}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add team: status %d: %s", rec.Code, rec.Body)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("add team: no ETag")
+	}
+	if got := call(h.GetHandler, http.MethodGet, "/team/get?team_name=backend", "", "").Header().Get("ETag"); got != etag {
+		t.Errorf("get team: ETag %q, want %q", got, etag)
+	}
+
+	expectError(t, "malformed If-Match", call(h.UpdateTeamHandler, http.MethodPost, "/team/update", "abc",
+		`{"team_name":"backend","new_team_name":"core"}`), http.StatusBadRequest, models.ErrorCodeValidation)
+	expectError(t, "stale If-Match", call(h.UpdateTeamHandler, http.MethodPost, "/team/update", `"999"`,
+		`{"team_name":"backend","new_team_name":"core"}`), http.StatusPreconditionFailed, models.ErrorCodePrecondition)
+
+	rec = call(h.UpdateTeamHandler, http.MethodPost, "/team/update", etag, `{"team_name":"backend","new_team_name":"core"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update team: status %d: %s", rec.Code, rec.Body)
+	}
+	if next := rec.Header().Get("ETag"); next == "" || next == etag {
+		t.Fatalf("update team: ETag %q, want a new version after %q", next, etag)
+	}
+
+	// прежний ETag устарел для всех изменений команды
+	expectError(t, "add member with stale If-Match", call(h.AddMemberHandler, http.MethodPost, "/team/addMember", etag,
+		`{"team_name":"core","member":{"user_id":"u2","username":"bob","is_active":true}}`), http.StatusPreconditionFailed, models.ErrorCodePrecondition)
+}
+
+func TestPullRequestETagAndVersion(t *testing.T) {
+	h := newDBHandler(t)
+
+	rec := call(h.AddHandler, http.MethodPost, "/team/add", "",
+		`{"team_name":"backend","members":[{"user_id":"author","username":"author","is_active":true},{"user_id":"r1","username":"r1","is_active":true}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add team: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = call(h.CreateHandler, http.MethodPost, "/pullRequest/create", "",
+		`{"pull_request_id":"pr-1","pull_request_name":"Add cache","author_id":"author"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create pr: status %d: %s", rec.Code, rec.Body)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("create pr: no ETag")
+	}
+	version := strings.Trim(etag, `"`)
+
+	expectError(t, "stale If-Match", call(h.UpdatePRHandler, http.MethodPost, "/pullRequest/update", `"999"`,
+		`{"pull_request_id":"pr-1","pull_request_name":"Add LRU cache"}`), http.StatusPreconditionFailed, models.ErrorCodePrecondition)
+	expectError(t, "stale body version", call(h.UpdatePRHandler, http.MethodPost, "/pullRequest/update", "",
+		`{"pull_request_id":"pr-1","pull_request_name":"Add LRU cache","version":999}`), http.StatusConflict, models.ErrorCodeVersionConflict)
+
+	rec = call(h.UpdatePRHandler, http.MethodPost, "/pullRequest/update", etag,
+		`{"pull_request_id":"pr-1","pull_request_name":"Add LRU cache","version":`+version+`}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update pr: status %d: %s", rec.Code, rec.Body)
+	}
+	next := rec.Header().Get("ETag")
+	if next == "" || next == etag {
+		t.Fatalf("update pr: ETag %q, want a new version after %q", next, etag)
+	}
+
+	// версия из ответа на update принимается остальными изменениями PR, прежняя — нет
+	expectError(t, "merge with stale If-Match", call(h.MergeHandler, http.MethodPost, "/pullRequest/merge", etag,
+		`{"pull_request_id":"pr-1"}`), http.StatusPreconditionFailed, models.ErrorCodePrecondition)
+	rec = call(h.MergeHandler, http.MethodPost, "/pullRequest/merge", next, `{"pull_request_id":"pr-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge pr: status %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("ETag"); got == "" || got == next {
+		t.Errorf("merge pr: ETag %q, want a new version after %q", got, next)
+	}
+}
+
+func TestVersionedHandlersRejectStaleIfMatch(t *testing.T) {
+	h := newDBHandler(t)
+
+	rec := call(h.AddHandler, http.MethodPost, "/team/add", "",
+		`{"team_name":"backend","members":[{"user_id":"author","username":"author","is_active":true},{"user_id":"r1","username":"r1","is_active":true},{"user_id":"r2","username":"r2","is_active":true}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add team: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = call(h.CreateHandler, http.MethodPost, "/pullRequest/create", "",
+		`{"pull_request_id":"pr-1","pull_request_name":"Add cache","author_id":"author"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create pr: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		body    string
+	}{
+		{"remove member", h.RemoveMemberHandler, "/team/removeMember", `{"team_name":"backend","user_id":"r2"}`},
+		{"delete team", h.DeleteTeamHandler, "/team/delete", `{"team_name":"backend"}`},
+		{"set parent", h.SetParentHandler, "/team/setParent", `{"team_name":"backend","parent_team":""}`},
+		{"set policy", h.SetPolicyHandler, "/team/setPolicy", `{"team_name":"backend","policy":{}}`},
+		{"set organization", h.SetOrganizationHandler, "/team/setOrganization", `{"team_name":"backend","org_name":""}`},
+		{"rebalance", h.RebalanceHandler, "/team/rebalance", `{"team_name":"backend","dry_run":true}`},
+		{"close pr", h.ClosePRHandler, "/pullRequest/close", `{"pull_request_id":"pr-1"}`},
+		{"reopen pr", h.ReopenPRHandler, "/pullRequest/reopen", `{"pull_request_id":"pr-1"}`},
+		{"review", h.ReviewHandler, "/pullRequest/review", `{"pull_request_id":"pr-1","user_id":"r1","state":"APPROVED"}`},
+		{"reassign", h.ReassignHandler, "/pullRequest/reassign", `{"pull_request_id":"pr-1","old_user_id":"r1"}`},
+	} {
+		expectError(t, tc.name, call(tc.handler, http.MethodPost, tc.target, `"999"`, tc.body),
+			http.StatusPreconditionFailed, models.ErrorCodePrecondition)
+	}
+
+	// отклонённые запросы ничего не изменили
+	if rec := call(h.GetHandler, http.MethodGet, "/team/get?team_name=backend", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("get team after rejected changes: status %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/service"
+)
+
+// maxWebhookBody максимальный размер тела webhook (ограничение GitHub — 25 МБ)
+const maxWebhookBody = 25 << 20
+
+// GitHubWebhook принимает события pull request и ревью от GitHub (POST /webhooks/github).
+// Подпись X-Hub-Signature-256 проверяется по общему секрету webhook.
+type GitHubWebhook struct {
+	service *service.Service
+	logger  *slog.Logger
+	secret  []byte
+}
+
+func NewGitHubWebhook(s *service.Service, logger *slog.Logger, secret string) *GitHubWebhook {
+	return &GitHubWebhook{service: s, logger: logger, secret: []byte(secret)}
+}
+
+// githubUser пользователь GitHub в payload
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// githubPullRequest поля pull request из payload GitHub
+type githubPullRequest struct {
+	Number int64      `json:"number"`
+	Title  string     `json:"title"`
+	Merged bool       `json:"merged"`
+	User   githubUser `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// githubPayload поля событий pull_request и pull_request_review
+type githubPayload struct {
+	Action      string            `json:"action"`
+	PullRequest githubPullRequest `json:"pull_request"`
+	Review      struct {
+		State string     `json:"state"`
+		User  githubUser `json:"user"`
+	} `json:"review"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// githubReviewStates состояния ревью GitHub; commented не меняет состояние
+var githubReviewStates = map[string]models.ReviewState{
+	"approved":          models.ReviewStateApproved,
+	"changes_requested": models.ReviewStateChangesRequested,
+	"dismissed":         models.ReviewStatePending,
+}
+
+func (h *GitHubWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event := r.Header.Get("X-GitHub-Event")
+	h.logger.Info("GitHub webhook called", slog.String("event", event), slog.String("delivery", r.Header.Get("X-GitHub-Delivery")))
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "VALIDATION_ERROR", "method not allowed")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+	if !h.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		h.logger.Warn("invalid GitHub webhook signature", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid signature")
+		return
+	}
+
+	var payload githubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		h.logger.Error("invalid GitHub webhook payload", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ev, reason := githubEvent(event, &payload)
+	if ev == nil {
+		writeJSON(w, http.StatusOK, &models.CodeHostEventResponse{Action: models.CodeHostActionIgnored, Reason: reason})
+		return
+	}
+
+	resp, err := h.service.ApplyCodeHostEvent(ev)
+	if err != nil {
+		h.logger.Error("ApplyCodeHostEvent failed", slog.Any("err", err), slog.String("event", event))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("GitHub event processed", slog.String("action", resp.Action), slog.String("pr_id", resp.PullRequestID))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validSignature проверяет заголовок X-Hub-Signature-256 вида sha256=<hex HMAC тела>
+func (h *GitHubWebhook) validSignature(header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// githubEvent приводит событие GitHub к событию сервиса; для неподдерживаемых событий
+// возвращает nil и причину
+func githubEvent(event string, p *githubPayload) (*models.CodeHostEvent, string) {
+	ev := &models.CodeHostEvent{
+		Ref: models.ExternalPullRequestRef{
+			Provider:   models.CodeHostGitHub,
+			Repository: p.Repository.FullName,
+			Number:     p.PullRequest.Number,
+		},
+	}
+	switch event {
+	case "pull_request":
+		switch p.Action {
+		case "opened":
+			ev.Type = models.CodeHostEventOpened
+			ev.Title = p.PullRequest.Title
+			ev.AuthorLogin = p.PullRequest.User.Login
+			for _, l := range p.PullRequest.Labels {
+				ev.Labels = append(ev.Labels, l.Name)
+			}
+		case "closed":
+			ev.Type = models.CodeHostEventClosed
+			if p.PullRequest.Merged {
+				ev.Type = models.CodeHostEventMerged
+			}
+		case "reopened":
+			ev.Type = models.CodeHostEventReopened
+		default:
+			return nil, "unsupported pull_request action " + p.Action
+		}
+	case "pull_request_review":
+		state, ok := githubReviewStates[p.Review.State]
+		if !ok || (p.Action != "submitted" && p.Action != "dismissed") {
+			return nil, "unsupported review " + p.Action + "/" + p.Review.State
+		}
+		ev.Type = models.CodeHostEventReview
+		ev.ReviewerLogin = p.Review.User.Login
+		ev.ReviewState = state
+	default:
+		return nil, "unsupported event " + event
+	}
+	if ev.Ref.Repository == "" || ev.Ref.Number <= 0 {
+		return nil, "payload has no repository or pull request number"
+	}
+	return ev, ""
+}
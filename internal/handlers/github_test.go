@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/service"
+)
+
+const testGitHubSecret = "It's a Secret to Everybody"
+
+// readPayload читает записанный payload из testdata
+func readPayload(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return body
+}
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestGitHubWebhook() *GitHubWebhook {
+	return NewGitHubWebhook(service.NewService(nil, nil), slog.New(slog.NewTextHandler(io.Discard, nil)), testGitHubSecret)
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	body := readPayload(t, "github/pull_request_synchronize.json")
+	cases := map[string]struct {
+		signature string
+		status    int
+	}{
+		"valid":        {githubSignature(testGitHubSecret, body), http.StatusOK},
+		"other secret": {githubSignature("wrong", body), http.StatusUnauthorized},
+		"no prefix":    {githubSignature(testGitHubSecret, body)[len("sha256="):], http.StatusUnauthorized},
+		"not hex":      {"sha256=zz", http.StatusUnauthorized},
+		"missing":      {"", http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		if tc.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", tc.signature)
+		}
+		rec := httptest.NewRecorder()
+		newTestGitHubWebhook().ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", name, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestGitHubWebhookIgnoresUnsupportedAction(t *testing.T) {
+	body := readPayload(t, "github/pull_request_synchronize.json")
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-Hub-Signature-256", githubSignature(testGitHubSecret, body))
+	rec := httptest.NewRecorder()
+	newTestGitHubWebhook().ServeHTTP(rec, req)
+
+	var resp models.CodeHostEventResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Action != models.CodeHostActionIgnored {
+		t.Errorf("status %d, response %+v; want an ignored event", rec.Code, resp)
+	}
+}
+
+func TestGitHubEventMapping(t *testing.T) {
+	ref := func(number int64) models.ExternalPullRequestRef {
+		return models.ExternalPullRequestRef{Provider: models.CodeHostGitHub, Repository: "acme/api", Number: number}
+	}
+	cases := []struct {
+		event, payload string
+		want           *models.CodeHostEvent
+	}{
+		{"pull_request", "pull_request_opened.json", &models.CodeHostEvent{
+			Type: models.CodeHostEventOpened, Ref: ref(42), Title: "Add rate limiting to public endpoints",
+			AuthorLogin: "alice", Labels: []string{"backend", "security"},
+		}},
+		{"pull_request", "pull_request_closed_merged.json", &models.CodeHostEvent{Type: models.CodeHostEventMerged, Ref: ref(42)}},
+		{"pull_request", "pull_request_closed.json", &models.CodeHostEvent{Type: models.CodeHostEventClosed, Ref: ref(43)}},
+		{"pull_request", "pull_request_reopened.json", &models.CodeHostEvent{Type: models.CodeHostEventReopened, Ref: ref(43)}},
+		{"pull_request_review", "pull_request_review_submitted.json", &models.CodeHostEvent{
+			Type: models.CodeHostEventReview, Ref: ref(42), ReviewerLogin: "bob", ReviewState: models.ReviewStateApproved,
+		}},
+		{"pull_request_review", "pull_request_review_commented.json", nil},
+		{"pull_request", "pull_request_synchronize.json", nil},
+		{"issues", "pull_request_opened.json", nil},
+	}
+	for _, tc := range cases {
+		var p githubPayload
+		if err := json.Unmarshal(readPayload(t, "github/"+tc.payload), &p); err != nil {
+			t.Fatalf("%s: decode: %v", tc.payload, err)
+		}
+		got, reason := githubEvent(tc.event, &p)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %s: event %+v, want %+v", tc.event, tc.payload, got, tc.want)
+		}
+		if tc.want == nil && reason == "" {
+			t.Errorf("%s %s: ignored without a reason", tc.event, tc.payload)
+		}
+	}
+}
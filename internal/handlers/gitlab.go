@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/service"
+)
+
+// GitLabWebhook принимает события merge request от GitLab (POST /webhooks/gitlab).
+// Заголовок X-Gitlab-Token должен совпадать с секретным токеном webhook.
+type GitLabWebhook struct {
+	service *service.Service
+	logger  *slog.Logger
+	token   []byte
+}
+
+func NewGitLabWebhook(s *service.Service, logger *slog.Logger, token string) *GitLabWebhook {
+	return &GitLabWebhook{service: s, logger: logger, token: []byte(token)}
+}
+
+// gitlabPayload поля события merge_request. user — автор действия: создатель MR
+// для open и утверждающий для approved/unapproved.
+type gitlabPayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID    int64  `json:"iid"`
+		Title  string `json:"title"`
+		Action string `json:"action"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+}
+
+func (h *GitLabWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event := r.Header.Get("X-Gitlab-Event")
+	h.logger.Info("GitLab webhook called", slog.String("event", event), slog.String("uuid", r.Header.Get("X-Gitlab-Event-UUID")))
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "VALIDATION_ERROR", "method not allowed")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), h.token) != 1 {
+		h.logger.Warn("invalid GitLab webhook token", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid token")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	var payload gitlabPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		h.logger.Error("invalid GitLab webhook payload", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ev, reason := gitlabEvent(&payload)
+	if ev == nil {
+		writeJSON(w, http.StatusOK, &models.CodeHostEventResponse{Action: models.CodeHostActionIgnored, Reason: reason})
+		return
+	}
+
+	resp, err := h.service.ApplyCodeHostEvent(ev)
+	if err != nil {
+		h.logger.Error("ApplyCodeHostEvent failed", slog.Any("err", err), slog.String("event", event))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("GitLab event processed", slog.String("action", resp.Action), slog.String("pr_id", resp.PullRequestID))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// gitlabEvent приводит событие GitLab к событию сервиса; для неподдерживаемых событий
+// возвращает nil и причину
+func gitlabEvent(p *gitlabPayload) (*models.CodeHostEvent, string) {
+	if p.ObjectKind != "merge_request" {
+		return nil, "unsupported event " + p.ObjectKind
+	}
+	ev := &models.CodeHostEvent{
+		Ref: models.ExternalPullRequestRef{
+			Provider:   models.CodeHostGitLab,
+			Repository: p.Project.PathWithNamespace,
+			Number:     p.ObjectAttributes.IID,
+		},
+	}
+	switch action := p.ObjectAttributes.Action; action {
+	case "open":
+		ev.Type = models.CodeHostEventOpened
+		ev.Title = p.ObjectAttributes.Title
+		ev.AuthorLogin = p.User.Username
+		for _, l := range p.Labels {
+			ev.Labels = append(ev.Labels, l.Title)
+		}
+	case "merge":
+		ev.Type = models.CodeHostEventMerged
+	case "close":
+		ev.Type = models.CodeHostEventClosed
+	case "reopen":
+		ev.Type = models.CodeHostEventReopened
+	case "approved", "approval":
+		ev.Type = models.CodeHostEventReview
+		ev.ReviewerLogin = p.User.Username
+		ev.ReviewState = models.ReviewStateApproved
+	case "unapproved", "unapproval":
+		ev.Type = models.CodeHostEventReview
+		ev.ReviewerLogin = p.User.Username
+		ev.ReviewState = models.ReviewStatePending
+	default:
+		return nil, "unsupported merge_request action " + action
+	}
+	if ev.Ref.Repository == "" || ev.Ref.Number <= 0 {
+		return nil, "payload has no project or merge request iid"
+	}
+	return ev, ""
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/service"
+)
+
+const testGitLabToken = "gl-webhook-token"
+
+func newTestGitLabWebhook() *GitLabWebhook {
+	return NewGitLabWebhook(service.NewService(nil, nil), slog.New(slog.NewTextHandler(io.Discard, nil)), testGitLabToken)
+}
+
+func TestGitLabWebhookToken(t *testing.T) {
+	body := readPayload(t, "gitlab/merge_request_update.json")
+	cases := map[string]struct {
+		token  string
+		status int
+	}{
+		"valid":   {testGitLabToken, http.StatusOK},
+		"other":   {"gl-webhook-tokem", http.StatusUnauthorized},
+		"prefix":  {testGitLabToken[:4], http.StatusUnauthorized},
+		"missing": {"", http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(body))
+		req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+		if tc.token != "" {
+			req.Header.Set("X-Gitlab-Token", tc.token)
+		}
+		rec := httptest.NewRecorder()
+		newTestGitLabWebhook().ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", name, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestGitLabWebhookIgnoresUnsupportedEvent(t *testing.T) {
+	for _, name := range []string{"gitlab/merge_request_update.json", "gitlab/note.json"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(readPayload(t, name)))
+		req.Header.Set("X-Gitlab-Token", testGitLabToken)
+		rec := httptest.NewRecorder()
+		newTestGitLabWebhook().ServeHTTP(rec, req)
+
+		var resp models.CodeHostEventResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode response: %v", name, err)
+		}
+		if rec.Code != http.StatusOK || resp.Action != models.CodeHostActionIgnored || resp.Reason == "" {
+			t.Errorf("%s: status %d, response %+v; want an ignored event with a reason", name, rec.Code, resp)
+		}
+	}
+}
+
+func TestGitLabEventMapping(t *testing.T) {
+	ref := models.ExternalPullRequestRef{Provider: models.CodeHostGitLab, Repository: "acme/api", Number: 7}
+	cases := []struct {
+		payload string
+		want    *models.CodeHostEvent
+	}{
+		{"merge_request_open.json", &models.CodeHostEvent{
+			Type: models.CodeHostEventOpened, Ref: ref, Title: "Add rate limiting to public endpoints",
+			AuthorLogin: "alice", Labels: []string{"backend", "security"},
+		}},
+		{"merge_request_merge.json", &models.CodeHostEvent{Type: models.CodeHostEventMerged, Ref: ref}},
+		{"merge_request_close.json", &models.CodeHostEvent{Type: models.CodeHostEventClosed, Ref: ref}},
+		{"merge_request_reopen.json", &models.CodeHostEvent{Type: models.CodeHostEventReopened, Ref: ref}},
+		{"merge_request_approved.json", &models.CodeHostEvent{
+			Type: models.CodeHostEventReview, Ref: ref, ReviewerLogin: "bob", ReviewState: models.ReviewStateApproved,
+		}},
+		{"merge_request_unapproved.json", &models.CodeHostEvent{
+			Type: models.CodeHostEventReview, Ref: ref, ReviewerLogin: "bob", ReviewState: models.ReviewStatePending,
+		}},
+		{"merge_request_update.json", nil},
+		{"note.json", nil},
+	}
+	for _, tc := range cases {
+		var p gitlabPayload
+		if err := json.Unmarshal(readPayload(t, "gitlab/"+tc.payload), &p); err != nil {
+			t.Fatalf("%s: decode: %v", tc.payload, err)
+		}
+		got, reason := gitlabEvent(&p)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: event %+v, want %+v", tc.payload, got, tc.want)
+		}
+		if tc.want == nil && reason == "" {
+			t.Errorf("%s: ignored without a reason", tc.payload)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"log/slog"
 	"pr-review-manager/internal/models"
@@ -36,24 +40,100 @@ func writeError(w http.ResponseWriter, status int, code, msg string) {
 	writeJSON(w, status, errResp)
 }
 
+// parseBoolQuery читает необязательный булев query-параметр
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// parseIntQuery читает необязательный целочисленный query-параметр (0, если не задан)
+func parseIntQuery(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// parseTimeQuery читает необязательный query-параметр времени в формате RFC3339
+func parseTimeQuery(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// parsePage читает параметры страницы limit и cursor; при ошибке отвечает 400 и возвращает false
+func (h *Handler) parsePage(w http.ResponseWriter, r *http.Request) (models.PageRequest, bool) {
+	limit, err := parseIntQuery(r, "limit")
+	if err != nil {
+		h.logger.Warn("invalid limit", slog.String("path", r.URL.Path), slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be an integer")
+		return models.PageRequest{}, false
+	}
+	return models.PageRequest{Limit: limit, Cursor: r.URL.Query().Get("cursor")}, true
+}
+
+// parseIfMatch читает ожидаемую версию ресурса из заголовка If-Match (0 — без проверки,
+// в том числе для "*"); при некорректном значении отвечает 400 и возвращает false
+func (h *Handler) parseIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		h.logger.Warn("invalid If-Match", slog.String("path", r.URL.Path), slog.String("if_match", v))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "If-Match must be an ETag returned by the API")
+		return 0, false
+	}
+	return version, true
+}
+
+// setETag выставляет заголовок ETag по версии ресурса
+func setETag(w http.ResponseWriter, version int64) {
+	if version > 0 {
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+	}
+}
+
 // getStatusByCode преобразует код ошибки в HTTP статус
 func getStatusByCode(code string) int {
 	switch code {
 	case models.ErrorCodeNotFound:
 		return http.StatusNotFound
-	case models.ErrorCodeTeamExists, models.ErrorCodePRExists:
+	case models.ErrorCodePrecondition:
+		return http.StatusPreconditionFailed
+	case models.ErrorCodeTeamExists, models.ErrorCodeOrgExists, models.ErrorCodePRExists, models.ErrorCodeUserInOtherTeam, models.ErrorCodeUserHasPRs,
+		models.ErrorCodeVersionConflict, models.ErrorCodeKeyInProgress, models.ErrorCodeAccountLinked:
 		return http.StatusConflict
-	case models.ErrorCodePRMerged, models.ErrorCodeNotAssigned, models.ErrorCodeNoCandidate:
+	case models.ErrorCodeKeyReused:
+		return http.StatusUnprocessableEntity
+	case models.ErrorCodePRMerged, models.ErrorCodePRClosed, models.ErrorCodeNotAssigned, models.ErrorCodeNoCandidate,
+		models.ErrorCodeNotApproved:
 		return http.StatusConflict
+	case models.ErrorCodeValidation:
+		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
-// AddHandler создаёт новую команду (POST /team/add)
+// AddHandler создаёт новую команду (POST /team/add[?allow_move=true])
 func (h *Handler) AddHandler(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("AddHandler called", slog.String("remote", r.RemoteAddr))
 
+	allowMove, err := parseBoolQuery(r, "allow_move")
+	if err != nil {
+		h.logger.Warn("invalid allow_move in AddHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "allow_move must be a boolean")
+		return
+	}
+
 	var team models.Team
 	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
 		h.logger.Error("invalid request body in AddHandler", slog.Any("err", err))
@@ -61,7 +141,7 @@ func (h *Handler) AddHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	teamResp, err := h.service.AddTeam(&team)
+	teamResp, err := h.service.AddTeam(&team, allowMove)
 	if err != nil {
 		h.logger.Error("AddTeam failed", slog.Any("err", err))
 		code := service.ParseCodeFromError(err)
@@ -75,6 +155,7 @@ func (h *Handler) AddHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info("team created", slog.Any("team", teamResp))
+	setETag(w, teamResp.Team.Version)
 	writeJSON(w, http.StatusCreated, teamResp)
 }
 
@@ -87,8 +168,13 @@ func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+
 	h.logger.Info("GetHandler called", slog.String("team_name", teamName))
-	teamResp, err := h.service.GetTeam(teamName)
+	teamResp, err := h.service.GetTeam(teamName, page)
 	if err != nil {
 		h.logger.Error("GetTeam failed", slog.Any("err", err))
 		code := service.ParseCodeFromError(err)
@@ -101,23 +187,29 @@ func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, teamResp.Team.Version)
 	writeJSON(w, http.StatusOK, teamResp)
 }
 
-// SetIsActiveHandler изменяет статус активности пользователя (POST /users/setIsActive)
-func (h *Handler) SetIsActiveHandler(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("SetIsActiveHandler called", slog.String("remote", r.RemoteAddr))
+// UpdateTeamHandler переименовывает команду (POST /team/update)
+func (h *Handler) UpdateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UpdateTeamHandler called", slog.String("remote", r.RemoteAddr))
 
-	var req models.SetUserActiveRequest
+	var req models.UpdateTeamRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("invalid request body in SetIsActiveHandler", slog.Any("err", err))
+		h.logger.Error("invalid request body in UpdateTeamHandler", slog.Any("err", err))
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
 		return
 	}
 
-	userResp, err := h.service.SetUserActive(req.UserID, req.IsActive)
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.UpdateTeam(&req, ifMatch)
 	if err != nil {
-		h.logger.Error("SetUserActive failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		h.logger.Error("UpdateTeam failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
 		code := service.ParseCodeFromError(err)
 		status := getStatusByCode(code)
 		if er, ok := err.(*models.ErrorResponse); ok {
@@ -128,23 +220,30 @@ func (h *Handler) SetIsActiveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("user active state changed", slog.String("user_id", req.UserID), slog.Bool("is_active", req.IsActive))
-	writeJSON(w, http.StatusOK, userResp)
+	h.logger.Info("team renamed", slog.String("team_name", req.TeamName))
+	setETag(w, resp.Team.Version)
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// GetReviewHandler получает список PR'ов для ревью пользователя (GET /users/getReview?user_id=...)
-func (h *Handler) GetReviewHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		h.logger.Warn("GetReviewHandler missing user_id", slog.String("remote", r.RemoteAddr))
-		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "user_id is required")
+// AddMemberHandler добавляет участника в команду (POST /team/addMember)
+func (h *Handler) AddMemberHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("AddMemberHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.AddTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in AddMemberHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
 		return
 	}
 
-	h.logger.Info("GetReviewHandler called", slog.String("user_id", userID))
-	resp, err := h.service.GetReviewPRs(userID)
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.AddTeamMember(&req, ifMatch)
 	if err != nil {
-		h.logger.Error("GetReviewPRs failed", slog.Any("err", err))
+		h.logger.Error("AddTeamMember failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
 		code := service.ParseCodeFromError(err)
 		status := getStatusByCode(code)
 		if er, ok := err.(*models.ErrorResponse); ok {
@@ -155,23 +254,30 @@ func (h *Handler) GetReviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.logger.Info("team member added", slog.String("team_name", req.TeamName))
+	setETag(w, resp.Team.Version)
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// CreateHandler создаёт новый pull request (POST /pullRequest/create)
-func (h *Handler) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("CreateHandler called", slog.String("remote", r.RemoteAddr))
+// RemoveMemberHandler исключает участника из команды (POST /team/removeMember)
+func (h *Handler) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("RemoveMemberHandler called", slog.String("remote", r.RemoteAddr))
 
-	var req models.CreatePullRequestRequest
+	var req models.RemoveTeamMemberRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("invalid request body in CreateHandler", slog.Any("err", err))
+		h.logger.Error("invalid request body in RemoveMemberHandler", slog.Any("err", err))
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
 		return
 	}
 
-	prResp, err := h.service.CreatePullRequest(&req)
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.RemoveTeamMember(&req, ifMatch)
 	if err != nil {
-		h.logger.Error("CreatePullRequest failed", slog.Any("err", err))
+		h.logger.Error("RemoveTeamMember failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
 		code := service.ParseCodeFromError(err)
 		status := getStatusByCode(code)
 		if er, ok := err.(*models.ErrorResponse); ok {
@@ -182,24 +288,30 @@ func (h *Handler) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("pull request created", slog.Any("pr", prResp))
-	writeJSON(w, http.StatusCreated, prResp)
+	h.logger.Info("team member removed", slog.String("team_name", req.TeamName))
+	setETag(w, resp.Team.Version)
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// MergeHandler объединяет pull request (POST /pullRequest/merge)
-func (h *Handler) MergeHandler(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("MergeHandler called", slog.String("remote", r.RemoteAddr))
+// DeleteTeamHandler удаляет команду (POST /team/delete)
+func (h *Handler) DeleteTeamHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("DeleteTeamHandler called", slog.String("remote", r.RemoteAddr))
 
-	var req models.MergePullRequestRequest
+	var req models.DeleteTeamRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("invalid request body in MergeHandler", slog.Any("err", err))
+		h.logger.Error("invalid request body in DeleteTeamHandler", slog.Any("err", err))
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
 		return
 	}
 
-	prResp, err := h.service.MergePullRequest(req.PullRequestID)
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.DeleteTeam(&req, ifMatch)
 	if err != nil {
-		h.logger.Error("MergePullRequest failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		h.logger.Error("DeleteTeam failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
 		code := service.ParseCodeFromError(err)
 		status := getStatusByCode(code)
 		if er, ok := err.(*models.ErrorResponse); ok {
@@ -210,24 +322,29 @@ func (h *Handler) MergeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("pull request merged", slog.String("pr_id", req.PullRequestID))
-	writeJSON(w, http.StatusOK, prResp)
+	h.logger.Info("team deleted", slog.String("team_name", req.TeamName))
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// ReassignHandler переназначает рецензента для pull request (POST /pullRequest/reassign)
-func (h *Handler) ReassignHandler(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("ReassignHandler called", slog.String("remote", r.RemoteAddr))
+// SetParentHandler изменяет родительскую команду (POST /team/setParent)
+func (h *Handler) SetParentHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetParentHandler called", slog.String("remote", r.RemoteAddr))
 
-	var req models.ReassignPullRequestRequest
+	var req models.SetTeamParentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("invalid request body in ReassignHandler", slog.Any("err", err))
+		h.logger.Error("invalid request body in SetParentHandler", slog.Any("err", err))
 		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
 		return
 	}
 
-	prResp, replacedBy, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID)
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.SetTeamParent(&req, ifMatch)
 	if err != nil {
-		h.logger.Error("ReassignReviewer failed", slog.Any("err", err))
+		h.logger.Error("SetTeamParent failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
 		code := service.ParseCodeFromError(err)
 		status := getStatusByCode(code)
 		if er, ok := err.(*models.ErrorResponse); ok {
@@ -238,29 +355,1236 @@ func (h *Handler) ReassignHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := models.ReassignPullRequestResponse{
-		PR:         *prResp,
-		ReplacedBy: replacedBy,
+	h.logger.Info("team parent changed", slog.String("team_name", req.TeamName))
+	setETag(w, resp.Team.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetPolicyHandler заменяет собственные настройки команды (POST /team/setPolicy)
+func (h *Handler) SetPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetPolicyHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetTeamPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetPolicyHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.SetTeamPolicy(&req, ifMatch)
+	if err != nil {
+		h.logger.Error("SetTeamPolicy failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
 	}
-	h.logger.Info("pull request reassigned", slog.String("pr_id", req.PullRequestID), slog.String("replaced_by", replacedBy))
+
+	h.logger.Info("team policy changed", slog.String("team_name", req.TeamName))
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// StatsUsersHandler handles GET /stats/users
-func (h *Handler) StatsUsersHandler(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("StatsUsersHandler called", slog.String("remote", r.RemoteAddr))
+// PolicyHandler возвращает итоговые настройки команды с учётом наследования (GET /team/policy?team_name=...)
+func (h *Handler) PolicyHandler(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.logger.Warn("PolicyHandler missing team_name", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "team_name is required")
+		return
+	}
 
-	resp, err := h.service.GetUserStats()
+	h.logger.Info("PolicyHandler called", slog.String("team_name", teamName))
+	resp, err := h.service.GetTeamPolicy(teamName)
 	if err != nil {
-		h.logger.Error("AddTeam failed", slog.Any("err", err))
+		h.logger.Error("GetTeamPolicy failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetOrganizationHandler относит команду к организации (POST /team/setOrganization)
+func (h *Handler) SetOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetOrganizationHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetTeamOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetOrganizationHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.SetTeamOrganization(&req, ifMatch)
+	if err != nil {
+		h.logger.Error("SetTeamOrganization failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("team organization changed", slog.String("team_name", req.TeamName), slog.String("org_name", req.OrgName))
+	setETag(w, resp.Team.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AddOrganizationHandler создаёт организацию (POST /org/add)
+func (h *Handler) AddOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("AddOrganizationHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.AddOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in AddOrganizationHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.AddOrganization(&req)
+	if err != nil {
+		h.logger.Error("AddOrganization failed", slog.Any("err", err), slog.String("org_name", req.OrgName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("organization created", slog.String("org_name", req.OrgName))
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetOrganizationHandler возвращает организацию (GET /org/get?org_name=...)
+func (h *Handler) GetOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	orgName := r.URL.Query().Get("org_name")
+	if orgName == "" {
+		h.logger.Warn("GetOrganizationHandler missing org_name", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "org_name is required")
+		return
+	}
+
+	h.logger.Info("GetOrganizationHandler called", slog.String("org_name", orgName))
+	resp, err := h.service.GetOrganization(orgName)
+	if err != nil {
+		h.logger.Error("GetOrganization failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetOrganizationPolicyHandler заменяет собственные настройки организации (POST /org/setPolicy)
+func (h *Handler) SetOrganizationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetOrganizationPolicyHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetOrganizationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetOrganizationPolicyHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.SetOrganizationPolicy(&req)
+	if err != nil {
+		h.logger.Error("SetOrganizationPolicy failed", slog.Any("err", err), slog.String("org_name", req.OrgName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("organization policy changed", slog.String("org_name", req.OrgName))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RebalanceHandler перераспределяет открытые ревью внутри команды (POST /team/rebalance)
+func (h *Handler) RebalanceHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("RebalanceHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.RebalanceTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in RebalanceHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.RebalanceTeam(&req, ifMatch)
+	if err != nil {
+		h.logger.Error("RebalanceTeam failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("team rebalanced", slog.String("team_name", req.TeamName), slog.Int("moves", len(resp.Moves)), slog.Bool("dry_run", resp.DryRun))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ExportTeamsHandler выгружает команды, пользователей и членства (GET /team/export?format=json|csv)
+func (h *Handler) ExportTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ExportTeamsHandler called", slog.String("remote", r.RemoteAddr))
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.TransferFormatJSON
+	}
+	if format != models.TransferFormatJSON && format != models.TransferFormatCSV {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "format must be json or csv")
+		return
+	}
+
+	data, err := h.service.ExportTeams()
+	if err != nil {
+		h.logger.Error("ExportTeams failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	if format == models.TransferFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="teams.csv"`)
+	} else {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(models.ErrorResponse{
-			ErrDetail: models.ErrorDetail{
-				Code:    "INTERNAL_ERROR",
-				Message: err.Error(),
-			},
-		})
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := service.EncodeTeamsExport(format, w, data); err != nil {
+		h.logger.Error("failed to write teams export", slog.Any("err", err))
+		return
+	}
+	h.logger.Info("teams exported", slog.String("format", format), slog.Int("teams", len(data.Teams)), slog.Int("users", len(data.Users)))
+}
+
+// ImportTeamsHandler загружает команды, пользователей и членства
+// (POST /team/import?format=json|csv&dry_run=true)
+func (h *Handler) ImportTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ImportTeamsHandler called", slog.String("remote", r.RemoteAddr))
+
+	dryRun, err := parseBoolQuery(r, "dry_run")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "dry_run must be a boolean")
+		return
+	}
+
+	data, err := service.DecodeTeamsExport(r.URL.Query().Get("format"), r.Body)
+	if err != nil {
+		h.logger.Error("invalid request body in ImportTeamsHandler", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	resp, err := h.service.ImportTeams(data, dryRun)
+	if err != nil {
+		h.logger.Error("ImportTeams failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("teams import processed", slog.Bool("applied", resp.Applied), slog.Int("errors", len(resp.Errors)))
+	// при ошибках в данных импорт не применяется
+	if len(resp.Errors) > 0 {
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetUserHandler получает пользователя (GET /users/get?user_id=...)
+func (h *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.logger.Warn("GetUserHandler missing user_id", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "user_id is required")
+		return
+	}
+
+	h.logger.Info("GetUserHandler called", slog.String("user_id", userID))
+	resp, err := h.service.GetUser(userID)
+	if err != nil {
+		h.logger.Error("GetUser failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateUserHandler частично обновляет пользователя (POST /users/update)
+func (h *Handler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UpdateUserHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in UpdateUserHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.UpdateUser(&req)
+	if err != nil {
+		h.logger.Error("UpdateUser failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("user updated", slog.String("user_id", req.UserID))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteUserHandler удаляет пользователя (POST /users/delete)
+func (h *Handler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("DeleteUserHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.DeleteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in DeleteUserHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.DeleteUser(&req)
+	if err != nil {
+		h.logger.Error("DeleteUser failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("user deleted", slog.String("user_id", req.UserID))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetIsActiveHandler изменяет статус активности пользователя (POST /users/setIsActive)
+func (h *Handler) SetIsActiveHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetIsActiveHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetUserActiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetIsActiveHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	userResp, err := h.service.SetUserActive(req.UserID, req.IsActive)
+	if err != nil {
+		h.logger.Error("SetUserActive failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("user active state changed", slog.String("user_id", req.UserID), slog.Bool("is_active", req.IsActive))
+	writeJSON(w, http.StatusOK, userResp)
+}
+
+// MoveTeamHandler переводит пользователя в другую команду (POST /users/moveTeam)
+func (h *Handler) MoveTeamHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("MoveTeamHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.MoveUserTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in MoveTeamHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.MoveUserTeam(&req)
+	if err != nil {
+		h.logger.Error("MoveUserTeam failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("user moved", slog.String("user_id", req.UserID), slog.String("team_name", req.TeamName))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetReviewWeightHandler изменяет вес пользователя при назначении ревьюверов (POST /users/setReviewWeight)
+func (h *Handler) SetReviewWeightHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetReviewWeightHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetUserReviewWeightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetReviewWeightHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	userResp, err := h.service.SetUserReviewWeight(req.UserID, req.ReviewWeight)
+	if err != nil {
+		h.logger.Error("SetUserReviewWeight failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("user review weight changed", slog.String("user_id", req.UserID), slog.Float64("review_weight", req.ReviewWeight))
+	writeJSON(w, http.StatusOK, userResp)
+}
+
+// LinkAccountHandler привязывает логин на code host к пользователю (POST /users/linkAccount)
+func (h *Handler) LinkAccountHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("LinkAccountHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.LinkAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in LinkAccountHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	account, err := h.service.LinkAccount(&req)
+	if err != nil {
+		h.logger.Error("LinkAccount failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("account linked", slog.String("user_id", account.UserID), slog.String("login", account.Login))
+	writeJSON(w, http.StatusOK, &models.ExternalAccountResponse{Account: *account})
+}
+
+// UnlinkAccountHandler удаляет привязку логина на code host (POST /users/unlinkAccount)
+func (h *Handler) UnlinkAccountHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UnlinkAccountHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.LinkAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in UnlinkAccountHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	account, err := h.service.UnlinkAccount(&req)
+	if err != nil {
+		h.logger.Error("UnlinkAccount failed", slog.Any("err", err), slog.String("login", req.Login))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("account unlinked", slog.String("provider", account.Provider), slog.String("login", account.Login))
+	writeJSON(w, http.StatusOK, &models.ExternalAccountResponse{Account: *account})
+}
+
+// GetReviewHandler получает очередь ревью пользователя (GET /users/getReview?user_id=...[&status=&min_age=&sort=&order=])
+func (h *Handler) GetReviewHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.logger.Warn("GetReviewHandler missing user_id", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "user_id is required")
+		return
+	}
+
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filter := models.ReviewQueueFilter{
+		Status: models.PRStatus(q.Get("status")),
+		Sort:   q.Get("sort"),
+		Order:  q.Get("order"),
+		Limit:  page.Limit,
+		Cursor: page.Cursor,
+	}
+	if v := q.Get("min_age"); v != "" {
+		minAge, err := time.ParseDuration(v)
+		if err != nil {
+			h.logger.Warn("invalid min_age in GetReviewHandler", slog.Any("err", err))
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "min_age must be a duration, e.g. 24h")
+			return
+		}
+		filter.MinAge = minAge
+	}
+
+	h.logger.Info("GetReviewHandler called", slog.String("user_id", userID))
+	resp, err := h.service.GetReviewPRs(userID, filter)
+	if err != nil {
+		h.logger.Error("GetReviewPRs failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetPRHandler получает pull request (GET /pullRequest/get?pull_request_id=...)
+func (h *Handler) GetPRHandler(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		h.logger.Warn("GetPRHandler missing pull_request_id", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "pull_request_id is required")
+		return
+	}
+
+	h.logger.Info("GetPRHandler called", slog.String("pr_id", prID))
+	resp, err := h.service.GetPullRequest(prID)
+	if err != nil {
+		h.logger.Error("GetPullRequest failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	setETag(w, resp.PR.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parsePRFilter читает условия выборки списка pull request'ов из query-параметров;
+// при ошибке отвечает 400 и возвращает false
+func (h *Handler) parsePRFilter(w http.ResponseWriter, r *http.Request) (models.PullRequestFilter, bool) {
+	q := r.URL.Query()
+	filter := models.PullRequestFilter{
+		Status:     models.PRStatus(q.Get("status")),
+		AuthorID:   q.Get("author_id"),
+		ReviewerID: q.Get("reviewer_id"),
+		TeamName:   q.Get("team_name"),
+		Sort:       q.Get("sort"),
+		Order:      q.Get("order"),
+	}
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return filter, false
+	}
+	filter.Limit, filter.Cursor = page.Limit, page.Cursor
+	for name, dst := range map[string]*time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"merged_from":  &filter.MergedFrom,
+		"merged_to":    &filter.MergedTo,
+	} {
+		t, err := parseTimeQuery(r, name)
+		if err != nil {
+			h.logger.Warn("invalid time in PR filter", slog.String("param", name), slog.Any("err", err))
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", name+" must be an RFC3339 time")
+			return filter, false
+		}
+		*dst = t
+	}
+	return filter, true
+}
+
+// ListPRHandler получает страницу pull request'ов по фильтру (GET /pullRequest/list)
+func (h *Handler) ListPRHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListPRHandler called", slog.String("remote", r.RemoteAddr))
+
+	filter, ok := h.parsePRFilter(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ListPullRequests(filter)
+	if err != nil {
+		h.logger.Error("ListPullRequests failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListArchivedPRHandler получает страницу архивных pull request'ов по тому же фильтру,
+// что и список рабочих PR (GET /pullRequest/archived)
+func (h *Handler) ListArchivedPRHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListArchivedPRHandler called", slog.String("remote", r.RemoteAddr))
+
+	filter, ok := h.parsePRFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.Archived = true
+
+	resp, err := h.service.ListPullRequests(filter)
+	if err != nil {
+		h.logger.Error("ListPullRequests failed", slog.Any("err", err), slog.Bool("archived", true))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateHandler создаёт новый pull request (POST /pullRequest/create[?explain=true])
+func (h *Handler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("CreateHandler called", slog.String("remote", r.RemoteAddr))
+
+	explain, err := parseBoolQuery(r, "explain")
+	if err != nil {
+		h.logger.Warn("invalid explain in CreateHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "explain must be a boolean")
+		return
+	}
+
+	var req models.CreatePullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in CreateHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	prResp, err := h.service.CreatePullRequest(&req, explain)
+	if err != nil {
+		h.logger.Error("CreatePullRequest failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request created", slog.Any("pr", prResp))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusCreated, prResp)
+}
+
+// BatchCreateHandler создаёт пакет pull request'ов (POST /pullRequest/batchCreate)
+func (h *Handler) BatchCreateHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("BatchCreateHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.BatchCreatePullRequestsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in BatchCreateHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.BatchCreatePullRequests(&req)
+	if err != nil {
+		h.logger.Error("BatchCreatePullRequests failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request batch processed", slog.Int("created", resp.Created), slog.Int("failed", resp.Failed))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdatePRHandler изменяет название, автора и метки pull request (POST /pullRequest/update)
+func (h *Handler) UpdatePRHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UpdatePRHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.UpdatePullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in UpdatePRHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	prResp, err := h.service.UpdatePullRequest(&req, ifMatch)
+	if err != nil {
+		h.logger.Error("UpdatePullRequest failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request updated", slog.String("pr_id", req.PullRequestID))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusOK, prResp)
+}
+
+// MergeHandler объединяет pull request (POST /pullRequest/merge)
+func (h *Handler) MergeHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("MergeHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.MergePullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in MergeHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	prResp, err := h.service.MergePullRequest(req.PullRequestID, ifMatch)
+	if err != nil {
+		h.logger.Error("MergePullRequest failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request merged", slog.String("pr_id", req.PullRequestID))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusOK, prResp)
+}
+
+// ClosePRHandler закрывает pull request без мержа (POST /pullRequest/close)
+func (h *Handler) ClosePRHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ClosePRHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.ClosePullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in ClosePRHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	prResp, err := h.service.ClosePullRequest(req.PullRequestID, ifMatch)
+	if err != nil {
+		h.logger.Error("ClosePullRequest failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request closed", slog.String("pr_id", req.PullRequestID))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusOK, prResp)
+}
+
+// ReopenPRHandler снова открывает закрытый pull request (POST /pullRequest/reopen)
+func (h *Handler) ReopenPRHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ReopenPRHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.ClosePullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in ReopenPRHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	prResp, err := h.service.ReopenPullRequest(req.PullRequestID, ifMatch)
+	if err != nil {
+		h.logger.Error("ReopenPullRequest failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request reopened", slog.String("pr_id", req.PullRequestID))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusOK, prResp)
+}
+
+// ReviewHandler выставляет состояние ревью рецензента (POST /pullRequest/review)
+func (h *Handler) ReviewHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ReviewHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in ReviewHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	prResp, err := h.service.SubmitReview(&req, ifMatch)
+	if err != nil {
+		h.logger.Error("SubmitReview failed", slog.Any("err", err), slog.String("pr_id", req.PullRequestID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("review submitted", slog.String("pr_id", req.PullRequestID), slog.String("user_id", req.UserID))
+	setETag(w, prResp.PR.Version)
+	writeJSON(w, http.StatusOK, prResp)
+}
+
+// ReassignHandler переназначает рецензента для pull request (POST /pullRequest/reassign[?explain=true])
+func (h *Handler) ReassignHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ReassignHandler called", slog.String("remote", r.RemoteAddr))
+
+	explain, err := parseBoolQuery(r, "explain")
+	if err != nil {
+		h.logger.Warn("invalid explain in ReassignHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "explain must be a boolean")
+		return
+	}
+
+	var req models.ReassignPullRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in ReassignHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	ifMatch, ok := h.parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID, explain, ifMatch)
+	if err != nil {
+		h.logger.Error("ReassignReviewer failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	h.logger.Info("pull request reassigned", slog.String("pr_id", req.PullRequestID), slog.String("replaced_by", resp.ReplacedBy))
+	setETag(w, resp.PR.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// StatsUsersHandler handles GET /stats/users
+func (h *Handler) StatsUsersHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("StatsUsersHandler called", slog.String("remote", r.RemoteAddr))
+
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetUserStats(page)
+	if err != nil {
+		h.logger.Error("GetUserStats failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SubscribeWebhookHandler создаёт подписку на события сервиса (POST /webhooks/subscribe)
+func (h *Handler) SubscribeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SubscribeWebhookHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.CreateWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SubscribeWebhookHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.CreateWebhookSubscription(&req)
+	if err != nil {
+		h.logger.Error("CreateWebhookSubscription failed", slog.Any("err", err), slog.String("url", req.URL))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// UnsubscribeWebhookHandler удаляет подписку (POST /webhooks/unsubscribe)
+func (h *Handler) UnsubscribeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UnsubscribeWebhookHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.DeleteWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in UnsubscribeWebhookHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.DeleteWebhookSubscription(req.ID)
+	if err != nil {
+		h.logger.Error("DeleteWebhookSubscription failed", slog.Any("err", err), slog.Int64("id", req.ID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListWebhooksHandler получает страницу подписок (GET /webhooks/list[?limit=&cursor=])
+func (h *Handler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListWebhooksHandler called", slog.String("remote", r.RemoteAddr))
+
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ListWebhookSubscriptions(page)
+	if err != nil {
+		h.logger.Error("ListWebhookSubscriptions failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// WebhookDeliveriesHandler получает журнал доставок (GET /webhooks/deliveries[?subscription_id=&status=&limit=&cursor=])
+func (h *Handler) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	h.listWebhookDeliveries(w, r, r.URL.Query().Get("status"))
+}
+
+// DeadLettersHandler получает доставки, исчерпавшие попытки (GET /webhooks/deadLetters[?subscription_id=&limit=&cursor=])
+func (h *Handler) DeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	h.listWebhookDeliveries(w, r, models.DeliveryStatusDead)
+}
+
+// listWebhookDeliveries отвечает журналом доставок со статусом status (пустой — любые)
+func (h *Handler) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, status string) {
+	h.logger.Info("webhook deliveries requested", slog.String("path", r.URL.Path), slog.String("status", status))
+
+	subscriptionID, err := parseIntQuery(r, "subscription_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "subscription_id must be an integer")
+		return
+	}
+	page, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	filter := models.WebhookDeliveryFilter{SubscriptionID: int64(subscriptionID), Status: status, Limit: page.Limit, Cursor: page.Cursor}
+	resp, err := h.service.ListWebhookDeliveries(filter)
+	if err != nil {
+		h.logger.Error("ListWebhookDeliveries failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RedeliverWebhookHandler возвращает доставку из dead-letter в очередь (POST /webhooks/redeliver)
+func (h *Handler) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("RedeliverWebhookHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.RedeliverWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in RedeliverWebhookHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.RedeliverWebhook(req.DeliveryID)
+	if err != nil {
+		h.logger.Error("RedeliverWebhook failed", slog.Any("err", err), slog.Int64("delivery_id", req.DeliveryID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetNotificationChannelHandler настраивает канал уведомлений команды (POST /team/setNotificationChannel)
+func (h *Handler) SetNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetNotificationChannelHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetNotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetNotificationChannelHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.SetNotificationChannel(&req)
+	if err != nil {
+		h.logger.Error("SetNotificationChannel failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteNotificationChannelHandler удаляет канал уведомлений команды (POST /team/deleteNotificationChannel)
+func (h *Handler) DeleteNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("DeleteNotificationChannelHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.DeleteNotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in DeleteNotificationChannelHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.DeleteNotificationChannel(req.TeamName)
+	if err != nil {
+		h.logger.Error("DeleteNotificationChannel failed", slog.Any("err", err), slog.String("team_name", req.TeamName))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// NotificationChannelHandler получает канал уведомлений команды с учётом наследования (GET /team/notificationChannel?team_name=...)
+func (h *Handler) NotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.logger.Warn("NotificationChannelHandler missing team_name", slog.String("remote", r.RemoteAddr))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "team_name is required")
+		return
+	}
+
+	h.logger.Info("NotificationChannelHandler called", slog.String("team_name", teamName))
+	resp, err := h.service.GetNotificationChannel(teamName)
+	if err != nil {
+		h.logger.Error("GetNotificationChannel failed", slog.Any("err", err))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetNotificationsHandler включает или отключает уведомления пользователя (POST /users/setNotifications)
+func (h *Handler) SetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetNotificationsHandler called", slog.String("remote", r.RemoteAddr))
+
+	var req models.SetUserNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body in SetNotificationsHandler", slog.Any("err", err))
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+		return
+	}
+
+	resp, err := h.service.SetUserNotifications(&req)
+	if err != nil {
+		h.logger.Error("SetUserNotifications failed", slog.Any("err", err), slog.String("user_id", req.UserID))
+		code := service.ParseCodeFromError(err)
+		status := getStatusByCode(code)
+		if er, ok := err.(*models.ErrorResponse); ok {
+			writeJSON(w, status, er)
+			return
+		}
+		writeError(w, status, "INTERNAL_ERROR", err.Error())
 		return
 	}
 
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/service"
+)
+
+// maxIdempotencyKeyLen ограничивает длину заголовка Idempotency-Key
+const maxIdempotencyKeyLen = 255
+
+// maxIdempotentBody максимальный размер тела запроса с Idempotency-Key: тело читается
+// в память целиком, чтобы посчитать его хеш
+const maxIdempotentBody = 32 << 20
+
+// responseRecorder пропускает ответ клиенту, сохраняя копию статуса и тела
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// Idempotency выполняет POST-запрос с заголовком Idempotency-Key не более одного раза:
+// первый ответ сохраняется и возвращается на повторы с тем же ключом и телом
+// (с заголовком Idempotent-Replayed: true)
+func (h *Handler) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyValue := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || keyValue == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(keyValue) > maxIdempotencyKeyLen {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, "VALIDATION_ERROR", "request body is too large")
+				return
+			}
+			h.logger.Warn("failed to read request body", slog.Any("err", err))
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// тело и query-параметры вместе определяют запрос
+		sum := sha256.Sum256(append([]byte(r.URL.RawQuery+"\n"), body...))
+
+		key := models.IdempotencyKey{Key: keyValue, Method: r.Method, Path: r.URL.Path}
+		stored, err := h.service.BeginIdempotentRequest(key, hex.EncodeToString(sum[:]))
+		if err != nil {
+			h.logger.Warn("idempotency check failed", slog.String("key", keyValue), slog.Any("err", err))
+			code := service.ParseCodeFromError(err)
+			status := getStatusByCode(code)
+			if er, ok := err.(*models.ErrorResponse); ok {
+				writeJSON(w, status, er)
+				return
+			}
+			writeError(w, status, "INTERNAL_ERROR", err.Error())
+			return
+		}
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			if stored.ETag != "" {
+				w.Header().Set("ETag", stored.ETag)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.StatusCode)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// при панике обработчика освобождаем ключ, иначе повторы получали бы 409 до истечения TTL
+			if !completed {
+				h.service.CompleteIdempotentRequest(key, models.IdempotentResponse{StatusCode: http.StatusInternalServerError})
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		completed = true
+		h.service.CompleteIdempotentRequest(key, models.IdempotentResponse{
+			StatusCode:  rec.status,
+			ContentType: w.Header().Get("Content-Type"),
+			ETag:        w.Header().Get("ETag"),
+			Body:        rec.body.Bytes(),
+		})
+	})
+}
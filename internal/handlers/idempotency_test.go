@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pr-review-manager/internal/service"
+)
+
+func TestIdempotencyRejectsLargeBody(t *testing.T) {
+	h := NewHandler(service.NewService(nil, nil), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	req := httptest.NewRequest(http.MethodPost, "/team/import", strings.NewReader(strings.Repeat("x", maxIdempotentBody+1)))
+	req.Header.Set("Idempotency-Key", "k1")
+	rec := httptest.NewRecorder()
+	h.Idempotency(next).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413", rec.Code)
+	}
+	if called {
+		t.Error("handler called for a rejected request")
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -21,63 +22,464 @@ type ErrorResponse struct {
 
 // Team представляет команду с участниками
 type Team struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName     string       `json:"team_name"`
+	ParentTeam   string       `json:"parent_team,omitempty"`
+	Organization string       `json:"organization,omitempty"`
+	Members      []TeamMember `json:"members"`
+	Version      int64        `json:"version"`
 }
 
+// TeamPolicy представляет собственные настройки команды; nil означает наследование от родителя
+type TeamPolicy struct {
+	ReviewerCount     *int `json:"reviewer_count,omitempty"`
+	RequiredApprovals *int `json:"required_approvals,omitempty"`
+	MaxOpenReviews    *int `json:"max_open_reviews,omitempty"`
+}
+
+// Organization представляет организацию: уровень выше иерархии команд со своими настройками.
+// Teams — команды, напрямую отнесённые к организации.
+type Organization struct {
+	OrgName string     `json:"org_name"`
+	Policy  TeamPolicy `json:"policy"`
+	Teams   []string   `json:"teams"`
+}
+
+// EffectivePolicy представляет итоговые настройки команды с учётом наследования.
+// Sources указывает для каждой настройки команду, где она задана, "org:<org_name>"
+// для настройки организации или "default".
+type EffectivePolicy struct {
+	TeamName          string            `json:"team_name"`
+	Chain             []string          `json:"chain"`
+	Organization      string            `json:"organization,omitempty"`
+	ReviewerCount     int               `json:"reviewer_count"`
+	RequiredApprovals int               `json:"required_approvals"`
+	MaxOpenReviews    int               `json:"max_open_reviews"`
+	Sources           map[string]string `json:"sources"`
+}
+
+// Значения настроек команды по умолчанию (для корня иерархии)
+const (
+	DefaultReviewerCount     = 2
+	DefaultRequiredApprovals = 0
+	DefaultMaxOpenReviews    = 0 // без ограничения
+	PolicySourceDefault      = "default"
+	PolicySourceOrgPrefix    = "org:"
+)
+
 // TeamMember представляет участника команды
 type TeamMember struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	IsActive bool   `json:"is_active"`
+	UserID       string  `json:"user_id"`
+	Username     string  `json:"username"`
+	IsActive     bool    `json:"is_active"`
+	ReviewWeight float64 `json:"review_weight"`
 }
 
-// User представляет пользователя
+// User представляет пользователя.
+// TeamName — основная команда, Teams — все команды, в которых он состоит.
 type User struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	UserID       string   `json:"user_id"`
+	Username     string   `json:"username"`
+	TeamName     string   `json:"team_name"`
+	Teams        []string `json:"teams"`
+	IsActive     bool     `json:"is_active"`
+	ReviewWeight float64  `json:"review_weight"`
 }
 
+// DefaultReviewWeight вес ревьювера по умолчанию (равная вероятность назначения)
+const DefaultReviewWeight = 1.0
+
 // PullRequest представляет полную информацию о pull request
 type PullRequest struct {
-	PullRequestID     string    `json:"pull_request_id"`
-	PullRequestName   string    `json:"pull_request_name"`
-	AuthorID          string    `json:"author_id"`
-	Status            PRStatus  `json:"status"`
-	AssignedReviewers []string  `json:"assigned_reviewers"`
-	CreatedAt         time.Time `json:"createdAt,omitempty"`
-	MergedAt          time.Time `json:"mergedAt,omitempty"`
+	PullRequestID     string     `json:"pull_request_id"`
+	PullRequestName   string     `json:"pull_request_name"`
+	AuthorID          string     `json:"author_id"`
+	TeamName          string     `json:"team_name,omitempty"`
+	Status            PRStatus   `json:"status"`
+	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Labels            []string   `json:"labels"`
+	Version           int64      `json:"version"`
+	CreatedAt         time.Time  `json:"createdAt,omitempty"`
+	MergedAt          time.Time  `json:"mergedAt,omitempty"`
+	ClosedAt          time.Time  `json:"closedAt,omitempty"`
+	ArchivedAt        *time.Time `json:"archivedAt,omitempty"` // только у архивных PR
+}
+
+// AssignmentExplanation описывает, как были выбраны ревьюверы
+type AssignmentExplanation struct {
+	Strategy   string              `json:"strategy"`
+	Candidates []string            `json:"candidates"`
+	Excluded   []ExcludedCandidate `json:"excluded"`
+	Chosen     []string            `json:"chosen"`
 }
 
+// ExcludedCandidate представляет участника команды, не попавшего в кандидаты, и причину исключения
+type ExcludedCandidate struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// Стратегии назначения ревьюверов
+const (
+	AssignmentStrategyWeightedRandom = "weighted_random"
+	AssignmentStrategyLoadAware      = "load_aware_weighted_random"
+)
+
+// Причины исключения участника команды из кандидатов
+const (
+	ExclusionReasonInactive        = "inactive"
+	ExclusionReasonAuthor          = "author"
+	ExclusionReasonAlreadyAssigned = "already_assigned"
+	ExclusionReasonAtCapacity      = "at_capacity"
+)
+
 // PullRequestShort представляет сокращенную информацию о pull request
 type PullRequestShort struct {
-	PullRequestID   string   `json:"pull_request_id"`
-	PullRequestName string   `json:"pull_request_name"`
-	AuthorID        string   `json:"author_id"`
-	Status          PRStatus `json:"status"`
+	PullRequestID   string      `json:"pull_request_id"`
+	PullRequestName string      `json:"pull_request_name"`
+	AuthorID        string      `json:"author_id"`
+	Status          PRStatus    `json:"status"`
+	CreatedAt       time.Time   `json:"createdAt,omitempty"`
+	ReviewState     ReviewState `json:"review_state,omitempty"`
+}
+
+// ReviewState представляет состояние ревью, выставленное рецензентом
+type ReviewState string
+
+const (
+	ReviewStatePending          ReviewState = "PENDING"
+	ReviewStateApproved         ReviewState = "APPROVED"
+	ReviewStateChangesRequested ReviewState = "CHANGES_REQUESTED"
+)
+
+// ReviewQueueFilter представляет условия выборки очереди ревью пользователя.
+// MinAge оставляет только PR, созданные не позже чем MinAge назад.
+type ReviewQueueFilter struct {
+	Status PRStatus
+	MinAge time.Duration
+	Sort   string
+	Order  string
+	Limit  int
+	Cursor string
+}
+
+// PullRequestFilter представляет условия выборки списка pull request'ов.
+// Нулевые значения полей не ограничивают выборку; интервалы дат полуоткрытые [from, to).
+type PullRequestFilter struct {
+	Status      PRStatus
+	AuthorID    string
+	ReviewerID  string
+	TeamName    string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	MergedFrom  time.Time
+	MergedTo    time.Time
+	Sort        string
+	Order       string
+	Limit       int
+	Cursor      string
+	Archived    bool // выборка из архива вместо рабочих таблиц
+}
+
+// PageRequest представляет параметры страницы списка: размер и курсор из предыдущего ответа
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// PullRequestListResponse представляет страницу списка pull request'ов
+type PullRequestListResponse struct {
+	PullRequests []PullRequest `json:"pull_requests"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// Сортировка и размер страницы списков
+const (
+	PRSortCreatedAt = "created_at"
+	PRSortMergedAt  = "merged_at"
+	PRSortID        = "pull_request_id"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// TeamsExport представляет снимок команд, пользователей и их членств для импорта и экспорта
+type TeamsExport struct {
+	Teams       []TeamRecord       `json:"teams"`
+	Users       []UserRecord       `json:"users"`
+	Memberships []MembershipRecord `json:"memberships"`
+}
+
+// TeamRecord представляет команду в снимке
+type TeamRecord struct {
+	TeamName   string `json:"team_name"`
+	ParentTeam string `json:"parent_team,omitempty"`
+}
+
+// UserRecord представляет пользователя в снимке; team_name — основная команда
+type UserRecord struct {
+	UserID       string  `json:"user_id"`
+	Username     string  `json:"username"`
+	TeamName     string  `json:"team_name,omitempty"`
+	IsActive     bool    `json:"is_active"`
+	ReviewWeight float64 `json:"review_weight"`
+}
+
+// MembershipRecord представляет членство пользователя в команде в снимке
+type MembershipRecord struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+}
+
+// ImportCounts представляет число созданных, изменённых и неизменных записей при импорте
+type ImportCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// TeamsImportResponse представляет результат импорта (или его проверки при dry_run)
+type TeamsImportResponse struct {
+	DryRun      bool         `json:"dry_run"`
+	Applied     bool         `json:"applied"`
+	Teams       ImportCounts `json:"teams"`
+	Users       ImportCounts `json:"users"`
+	Memberships ImportCounts `json:"memberships"`
+	Errors      []string     `json:"errors"`
+}
+
+// Форматы импорта и экспорта
+const (
+	TransferFormatJSON = "json"
+	TransferFormatCSV  = "csv"
+)
+
+// BackupSummary описывает резервную копию БД: версию формата, время создания
+// и число строк по таблицам
+type BackupSummary struct {
+	FormatVersion int              `json:"format_version"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Tables        map[string]int64 `json:"tables"`
+}
+
+// Формат резервной копии: gzip-поток JSON-записей (заголовок, строки таблиц, итог)
+const (
+	BackupFormat        = "pr-review-manager-backup"
+	BackupFormatVersion = 1
+)
+
+// IdempotencyKey идентифицирует запрос с заголовком Idempotency-Key
+type IdempotencyKey struct {
+	Key    string
+	Method string
+	Path   string
+}
+
+// IdempotentResponse представляет сохранённый первый ответ на запрос с Idempotency-Key
+type IdempotentResponse struct {
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Body        []byte
 }
 
+// DefaultIdempotencyTTL время хранения ответов на запросы с Idempotency-Key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
 // PRStatus представляет статус pull request
 type PRStatus string
 
 const (
 	PRStatusOpen   PRStatus = "OPEN"
 	PRStatusMerged PRStatus = "MERGED"
+	PRStatusClosed PRStatus = "CLOSED" // закрыт без мержа
 )
 
+// UpdateTeamRequest представляет запрос на переименование команды
+type UpdateTeamRequest struct {
+	TeamName    string `json:"team_name"`
+	NewTeamName string `json:"new_team_name"`
+}
+
+// AddTeamMemberRequest представляет запрос на добавление участника в команду
+type AddTeamMemberRequest struct {
+	TeamName string     `json:"team_name"`
+	Member   TeamMember `json:"member"`
+}
+
+// RemoveTeamMemberRequest представляет запрос на исключение участника из команды
+type RemoveTeamMemberRequest struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+}
+
+// DeleteTeamRequest представляет запрос на удаление команды
+type DeleteTeamRequest struct {
+	TeamName string `json:"team_name"`
+}
+
+// TeamMembershipResponse представляет команду после изменения состава.
+// ReleasedReviews содержит снятые назначения на OPEN PR; отсутствие to_user_id означает, что замены не нашлось.
+type TeamMembershipResponse struct {
+	Team            Team         `json:"team"`
+	ReleasedReviews []ReviewMove `json:"released_reviews"`
+}
+
+// DeleteTeamResponse представляет результат удаления команды
+type DeleteTeamResponse struct {
+	TeamName        string       `json:"team_name"`
+	DetachedUsers   []string     `json:"detached_users"`
+	ReleasedReviews []ReviewMove `json:"released_reviews"`
+}
+
+// SetTeamParentRequest представляет запрос на изменение родительской команды
+type SetTeamParentRequest struct {
+	TeamName   string `json:"team_name"`
+	ParentTeam string `json:"parent_team"`
+}
+
+// SetTeamPolicyRequest представляет запрос на замену собственных настроек команды
+type SetTeamPolicyRequest struct {
+	TeamName string     `json:"team_name"`
+	Policy   TeamPolicy `json:"policy"`
+}
+
+// TeamPolicyResponse представляет ответ с итоговыми настройками команды
+type TeamPolicyResponse struct {
+	Own       TeamPolicy      `json:"own"`
+	Effective EffectivePolicy `json:"effective"`
+}
+
+// AddOrganizationRequest представляет запрос на создание организации
+type AddOrganizationRequest struct {
+	OrgName string     `json:"org_name"`
+	Policy  TeamPolicy `json:"policy"`
+}
+
+// SetOrganizationPolicyRequest представляет запрос на замену собственных настроек организации
+type SetOrganizationPolicyRequest struct {
+	OrgName string     `json:"org_name"`
+	Policy  TeamPolicy `json:"policy"`
+}
+
+// SetTeamOrganizationRequest представляет запрос на отнесение команды к организации
+type SetTeamOrganizationRequest struct {
+	TeamName string `json:"team_name"`
+	OrgName  string `json:"org_name"`
+}
+
+// OrganizationResponse представляет ответ с организацией
+type OrganizationResponse struct {
+	Organization Organization `json:"organization"`
+}
+
+// UpdateUserRequest представляет запрос на частичное обновление пользователя; nil-поля не меняются
+type UpdateUserRequest struct {
+	UserID       string   `json:"user_id"`
+	Username     *string  `json:"username,omitempty"`
+	IsActive     *bool    `json:"is_active,omitempty"`
+	ReviewWeight *float64 `json:"review_weight,omitempty"`
+}
+
+// DeleteUserRequest представляет запрос на удаление пользователя.
+// Если у пользователя есть PR, их авторство передаётся TransferPRsTo.
+type DeleteUserRequest struct {
+	UserID        string `json:"user_id"`
+	TransferPRsTo string `json:"transfer_prs_to,omitempty"`
+}
+
+// DeleteUserResponse представляет результат удаления пользователя.
+// ReleasedReviews содержит переданные назначения удаляемого пользователя и нового автора его PR.
+type DeleteUserResponse struct {
+	UserID          string       `json:"user_id"`
+	TransferredPRs  int          `json:"transferred_prs"`
+	ReleasedReviews []ReviewMove `json:"released_reviews"`
+}
+
+// MoveUserTeamRequest представляет запрос на перевод пользователя в другую команду
+type MoveUserTeamRequest struct {
+	UserID          string `json:"user_id"`
+	TeamName        string `json:"team_name"`
+	ReassignReviews bool   `json:"reassign_reviews"`
+}
+
+// MoveUserTeamResponse представляет пользователя после перевода и переданные ревью
+type MoveUserTeamResponse struct {
+	User            User         `json:"user"`
+	ReleasedReviews []ReviewMove `json:"released_reviews"`
+}
+
 // SetUserActiveRequest представляет запрос на установку флага активности пользователя
 type SetUserActiveRequest struct {
 	UserID   string `json:"user_id"`
 	IsActive bool   `json:"is_active"`
 }
 
+// SetUserReviewWeightRequest представляет запрос на установку веса ревьювера
+type SetUserReviewWeightRequest struct {
+	UserID       string  `json:"user_id"`
+	ReviewWeight float64 `json:"review_weight"`
+}
+
 // CreatePullRequestRequest представляет запрос на создание PR
 type CreatePullRequestRequest struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	TeamName        string   `json:"team_name,omitempty"`
+	Labels          []string `json:"labels,omitempty"`
+}
+
+// BatchCreatePullRequestsRequest представляет запрос на создание множества PR.
+// В режиме atomic все PR создаются в одной транзакции (ошибка любого отменяет все),
+// в режиме per_item каждый PR создаётся отдельно.
+type BatchCreatePullRequestsRequest struct {
+	Mode  string                     `json:"mode,omitempty"`
+	Items []CreatePullRequestRequest `json:"items"`
+}
+
+// BatchCreateItemResult представляет результат создания одного PR из пакета
+type BatchCreateItemResult struct {
+	Index         int          `json:"index"`
+	PullRequestID string       `json:"pull_request_id"`
+	Status        string       `json:"status"`
+	PR            *PullRequest `json:"pr,omitempty"`
+	Error         *ErrorDetail `json:"error,omitempty"`
+}
+
+// BatchCreatePullRequestsResponse представляет результаты пакетного создания PR
+type BatchCreatePullRequestsResponse struct {
+	Mode      string                  `json:"mode"`
+	Committed bool                    `json:"committed"`
+	Created   int                     `json:"created"`
+	Failed    int                     `json:"failed"`
+	Results   []BatchCreateItemResult `json:"results"`
+}
+
+// Режимы и статусы пакетного создания PR
+const (
+	BatchModeAtomic  = "atomic"
+	BatchModePerItem = "per_item"
+
+	BatchItemCreated    = "created"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back"
+	BatchItemSkipped    = "skipped"
+
+	MaxBatchSize = 5000
+)
+
+// UpdatePullRequestRequest представляет запрос на изменение метаданных PR.
+// Незаданные поля не изменяются; version должна совпадать с текущей версией PR.
+type UpdatePullRequestRequest struct {
+	PullRequestID   string    `json:"pull_request_id"`
+	PullRequestName *string   `json:"pull_request_name,omitempty"`
+	AuthorID        *string   `json:"author_id,omitempty"`
+	Labels          *[]string `json:"labels,omitempty"`
+	Version         int64     `json:"version"`
 }
 
 // MergePullRequestRequest представляет запрос на мерж PR
@@ -85,6 +487,18 @@ type MergePullRequestRequest struct {
 	PullRequestID string `json:"pull_request_id"`
 }
 
+// ClosePullRequestRequest представляет запрос на закрытие или повторное открытие PR
+type ClosePullRequestRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+}
+
+// SubmitReviewRequest представляет запрос на изменение состояния ревью рецензента
+type SubmitReviewRequest struct {
+	PullRequestID string      `json:"pull_request_id"`
+	UserID        string      `json:"user_id"`
+	State         ReviewState `json:"state"`
+}
+
 // ReassignPullRequestRequest представляет запрос на переназначение ревьювера
 type ReassignPullRequestRequest struct {
 	PullRequestID string `json:"pull_request_id"`
@@ -93,19 +507,49 @@ type ReassignPullRequestRequest struct {
 
 // ReassignPullRequestResponse представляет ответ на переназначение ревьювера
 type ReassignPullRequestResponse struct {
-	PR         PullRequest `json:"pr"`
-	ReplacedBy string      `json:"replaced_by"`
+	PR         PullRequest            `json:"pr"`
+	ReplacedBy string                 `json:"replaced_by"`
+	Explain    *AssignmentExplanation `json:"explain,omitempty"`
+}
+
+// RebalanceTeamRequest представляет запрос на перераспределение открытых ревью в команде
+type RebalanceTeamRequest struct {
+	TeamName  string   `json:"team_name"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// ReviewMove представляет перенос назначения ревьювера на другого участника
+type ReviewMove struct {
+	PullRequestID string `json:"pull_request_id"`
+	FromUserID    string `json:"from_user_id"`
+	ToUserID      string `json:"to_user_id,omitempty"`
 }
 
+// RebalanceTeamResponse представляет план (или результат) перераспределения ревью
+type RebalanceTeamResponse struct {
+	TeamName  string         `json:"team_name"`
+	Average   float64        `json:"average"`
+	Threshold float64        `json:"threshold"`
+	DryRun    bool           `json:"dry_run"`
+	Moves     []ReviewMove   `json:"moves"`
+	Loads     map[string]int `json:"loads"`
+}
+
+// DefaultRebalanceThreshold допустимое превышение средней нагрузки по умолчанию
+const DefaultRebalanceThreshold = 1.0
+
 // UserReviewResponse представляет ответ с PR пользователя для ревью
 type UserReviewResponse struct {
 	UserID       string             `json:"user_id"`
 	PullRequests []PullRequestShort `json:"pull_requests"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
 }
 
 // TeamResponse представляет ответ с информацией о команде
 type TeamResponse struct {
-	Team Team `json:"team"`
+	Team       Team   `json:"team"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // UserResponse представляет ответ с информацией о пользователе
@@ -115,20 +559,275 @@ type UserResponse struct {
 
 // PullRequestResponse представляет ответ с информацией о PR
 type PullRequestResponse struct {
-	PR PullRequest `json:"pr"`
+	PR      PullRequest            `json:"pr"`
+	Explain *AssignmentExplanation `json:"explain,omitempty"`
 }
 
 // StatsUserResponse представляет статистику по назначениям для пользователей
 type StatsUserResponse struct {
-	Stats map[string]int `json:"stats"`
+	Stats      map[string]int `json:"stats"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
 // Error codes
 const (
-	ErrorCodeTeamExists  = "TEAM_EXISTS"
-	ErrorCodePRExists    = "PR_EXISTS"
-	ErrorCodePRMerged    = "PR_MERGED"
-	ErrorCodeNotAssigned = "NOT_ASSIGNED"
-	ErrorCodeNoCandidate = "NO_CANDIDATE"
-	ErrorCodeNotFound    = "NOT_FOUND"
+	ErrorCodeTeamExists      = "TEAM_EXISTS"
+	ErrorCodePRExists        = "PR_EXISTS"
+	ErrorCodePRMerged        = "PR_MERGED"
+	ErrorCodeNotAssigned     = "NOT_ASSIGNED"
+	ErrorCodeNoCandidate     = "NO_CANDIDATE"
+	ErrorCodeNotFound        = "NOT_FOUND"
+	ErrorCodeValidation      = "VALIDATION_ERROR"
+	ErrorCodeUserInOtherTeam = "USER_IN_OTHER_TEAM"
+	ErrorCodeUserHasPRs      = "USER_HAS_PULL_REQUESTS"
+	ErrorCodeVersionConflict = "VERSION_CONFLICT"
+	ErrorCodePrecondition    = "PRECONDITION_FAILED"
+	ErrorCodeKeyReused       = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeKeyInProgress   = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	ErrorCodePRClosed        = "PR_CLOSED"
+	ErrorCodeAccountLinked   = "ACCOUNT_LINKED"
+	ErrorCodeNotApproved     = "NOT_ENOUGH_APPROVALS"
+	ErrorCodeOrgExists       = "ORG_EXISTS"
+)
+
+// Провайдеры внешних систем хранения кода
+const (
+	CodeHostGitHub = "github"
+	CodeHostGitLab = "gitlab"
+)
+
+// LinkAccountRequest представляет запрос на привязку учётной записи на code host или в чате к пользователю
+type LinkAccountRequest struct {
+	UserID   string `json:"user_id"`
+	Provider string `json:"provider"`
+	Login    string `json:"login"`
+}
+
+// ExternalAccount представляет привязку логина на code host или ID в чате к пользователю
+type ExternalAccount struct {
+	Provider string `json:"provider"`
+	Login    string `json:"login"`
+	UserID   string `json:"user_id"`
+}
+
+// ExternalAccountResponse представляет ответ с привязкой учётной записи
+type ExternalAccountResponse struct {
+	Account ExternalAccount `json:"account"`
+}
+
+// ExternalPullRequestRef идентифицирует pull request на code host
+type ExternalPullRequestRef struct {
+	Provider   string `json:"provider"`
+	Repository string `json:"repository"`
+	Number     int64  `json:"number"`
+}
+
+// CodeHostEventType тип события code host, приведённого к операциям сервиса
+type CodeHostEventType string
+
+const (
+	CodeHostEventOpened   CodeHostEventType = "opened"
+	CodeHostEventClosed   CodeHostEventType = "closed"
+	CodeHostEventReopened CodeHostEventType = "reopened"
+	CodeHostEventMerged   CodeHostEventType = "merged"
+	CodeHostEventReview   CodeHostEventType = "review"
+)
+
+// CodeHostEvent событие pull request с code host, не зависящее от провайдера.
+// Логины автора и рецензента переводятся в user_id через привязанные учётные записи.
+type CodeHostEvent struct {
+	Type          CodeHostEventType
+	Ref           ExternalPullRequestRef
+	Title         string
+	AuthorLogin   string
+	Labels        []string
+	ReviewerLogin string
+	ReviewState   ReviewState
+}
+
+// Результаты обработки события code host
+const (
+	CodeHostActionCreated  = "created"
+	CodeHostActionMerged   = "merged"
+	CodeHostActionClosed   = "closed"
+	CodeHostActionReopened = "reopened"
+	CodeHostActionReviewed = "review_recorded"
+	CodeHostActionIgnored  = "ignored"
+)
+
+// CodeHostEventResponse представляет результат обработки события code host
+type CodeHostEventResponse struct {
+	Action        string `json:"action"`
+	PullRequestID string `json:"pull_request_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// DomainEventType тип события сервиса, рассылаемого подписчикам
+type DomainEventType string
+
+const (
+	EventPRCreated          DomainEventType = "pr.created"
+	EventPRMerged           DomainEventType = "pr.merged"
+	EventPRClosed           DomainEventType = "pr.closed"
+	EventPRReopened         DomainEventType = "pr.reopened"
+	EventReviewerAssigned   DomainEventType = "reviewer.assigned"
+	EventReviewerReassigned DomainEventType = "reviewer.reassigned"
+	EventReviewerUnassigned DomainEventType = "reviewer.unassigned"
+	EventUserDeactivated    DomainEventType = "user.deactivated"
+)
+
+// DomainEventTypes все типы событий сервиса
+var DomainEventTypes = []DomainEventType{
+	EventPRCreated, EventPRMerged, EventPRClosed, EventPRReopened,
+	EventReviewerAssigned, EventReviewerReassigned, EventReviewerUnassigned, EventUserDeactivated,
+}
+
+// DomainEvent событие сервиса; тело доставки webhook
+type DomainEvent struct {
+	ID         string          `json:"id"`
+	Type       DomainEventType `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// PullRequestEventData данные событий pr.created и pr.merged
+type PullRequestEventData struct {
+	PR PullRequest `json:"pr"`
+}
+
+// ReviewerEventData данные событий reviewer.*; для reviewer.unassigned UserID — снятый ревьювер
+type ReviewerEventData struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	OldUserID     string `json:"old_user_id,omitempty"`
+}
+
+// UserEventData данные события user.deactivated
+type UserEventData struct {
+	User User `json:"user"`
+}
+
+// WebhookSubscription подписка на события сервиса. Секрет используется для подписи
+// доставок и не возвращается в ответах.
+type WebhookSubscription struct {
+	ID         int64             `json:"id"`
+	URL        string            `json:"url"`
+	Secret     string            `json:"-"`
+	EventTypes []DomainEventType `json:"event_types"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// CreateWebhookSubscriptionRequest представляет запрос на создание подписки
+type CreateWebhookSubscriptionRequest struct {
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret"`
+	EventTypes []DomainEventType `json:"event_types"`
+}
+
+// DeleteWebhookSubscriptionRequest представляет запрос на удаление подписки
+type DeleteWebhookSubscriptionRequest struct {
+	ID int64 `json:"id"`
+}
+
+// WebhookSubscriptionResponse представляет ответ с подпиской
+type WebhookSubscriptionResponse struct {
+	Subscription WebhookSubscription `json:"subscription"`
+}
+
+// WebhookSubscriptionListResponse представляет страницу подписок в порядке создания
+type WebhookSubscriptionListResponse struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+	NextCursor    string                `json:"next_cursor,omitempty"`
+}
+
+// Статусы доставки webhook
+const (
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusDelivered = "DELIVERED"
+	DeliveryStatusDead      = "DEAD"
+)
+
+// WebhookDelivery доставка события подписчику
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      DomainEventType `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryFilter параметры журнала доставок; нулевые значения не ограничивают выборку
+type WebhookDeliveryFilter struct {
+	SubscriptionID int64
+	Status         string
+	Limit          int
+	Cursor         string
+}
+
+// WebhookDeliveryListResponse представляет страницу журнала доставок, новые первыми
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// RedeliverWebhookRequest представляет запрос на повторную доставку из dead-letter
+type RedeliverWebhookRequest struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// WebhookDeliveryResponse представляет ответ с доставкой
+type WebhookDeliveryResponse struct {
+	Delivery WebhookDelivery `json:"delivery"`
+}
+
+// Ограничения журнала доставок
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 500
 )
+
+// NotificationChannel канал чата команды для уведомлений о назначениях. Адрес incoming
+// webhook содержит секрет и не возвращается в ответах.
+type NotificationChannel struct {
+	TeamName   string `json:"team_name"`
+	Provider   string `json:"provider"`
+	WebhookURL string `json:"-"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// SetNotificationChannelRequest представляет запрос на настройку канала уведомлений команды
+type SetNotificationChannelRequest struct {
+	TeamName   string `json:"team_name"`
+	Provider   string `json:"provider"`
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// DeleteNotificationChannelRequest представляет запрос на удаление канала уведомлений команды
+type DeleteNotificationChannelRequest struct {
+	TeamName string `json:"team_name"`
+}
+
+// NotificationChannelResponse представляет ответ с каналом уведомлений команды
+type NotificationChannelResponse struct {
+	NotificationChannel NotificationChannel `json:"notification_channel"`
+}
+
+// SetUserNotificationsRequest представляет запрос на включение или отключение уведомлений пользователя
+type SetUserNotificationsRequest struct {
+	UserID  string `json:"user_id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// UserNotificationsResponse представляет настройку уведомлений пользователя
+type UserNotificationsResponse struct {
+	UserID  string `json:"user_id"`
+	Enabled bool   `json:"enabled"`
+}
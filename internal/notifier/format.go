@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Slack форматирует сообщение для incoming webhook Slack (разметка mrkdwn).
+// Канал задаётся при создании webhook в Slack, поэтому channel не передаётся.
+// Пользователи с привязанным Slack member ID упоминаются как <@ID>, остальные
+// выводятся экранированным именем без упоминания.
+type Slack struct{}
+
+func (Slack) Format(a Assignment, _ string) ([]byte, error) {
+	return json.Marshal(map[string]string{"text": assignmentText(a, "*", slackEscape, slackMention)})
+}
+
+// Mattermost форматирует сообщение для incoming webhook Mattermost (Markdown);
+// channel переопределяет канал по умолчанию. Пользователи с привязанным именем
+// Mattermost упоминаются как @username, остальные выводятся экранированным именем
+// без упоминания.
+type Mattermost struct{}
+
+func (Mattermost) Format(a Assignment, channel string) ([]byte, error) {
+	payload := map[string]string{"text": assignmentText(a, "**", markdownEscape, mattermostMention)}
+	if channel != "" {
+		payload["channel"] = channel
+	}
+	return json.Marshal(payload)
+}
+
+// assignmentText текст сообщения; bold — маркер полужирного начертания, escape экранирует
+// название и ID PR, mention выводит участника назначения
+func assignmentText(a Assignment, bold string, escape func(string) string, mention func(User) string) string {
+	name := a.PullRequestName
+	if name == "" {
+		name = a.PullRequestID
+	}
+	pr := fmt.Sprintf("%s%s%s (%s)", bold, escape(name), bold, escape(a.PullRequestID))
+	if a.PreviousReviewer.Name != "" {
+		return fmt.Sprintf("%s was assigned to review %s instead of %s (author: %s)",
+			mention(a.Reviewer), pr, mention(a.PreviousReviewer), mention(a.Author))
+	}
+	return fmt.Sprintf("%s was assigned to review %s (author: %s)", mention(a.Reviewer), pr, mention(a.Author))
+}
+
+// slackMention упоминание <@ID> по Slack member ID или экранированное имя
+func slackMention(u User) string {
+	if ValidUserID(ProviderSlack, u.ChatID) {
+		return "<@" + u.ChatID + ">"
+	}
+	return slackEscape(u.Name)
+}
+
+// mattermostMention упоминание @username по привязанному имени Mattermost или экранированное имя
+func mattermostMention(u User) string {
+	if ValidUserID(ProviderMattermost, u.ChatID) {
+		return "@" + u.ChatID
+	}
+	return markdownEscape(u.Name)
+}
+
+var (
+	// slackUserID member ID пользователя Slack
+	slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]{2,}$`)
+	// mattermostUsername имя пользователя Mattermost
+	mattermostUsername = regexp.MustCompile(`^[a-z][a-z0-9._-]{2,21}$`)
+	// mattermostReserved служебные упоминания Mattermost, оповещающие весь канал
+	mattermostReserved = map[string]bool{"all": true, "channel": true, "here": true}
+)
+
+// ValidUserID проверяет ID пользователя в чате provider: member ID для Slack
+// (U…/W…), имя пользователя для Mattermost, кроме служебных упоминаний
+func ValidUserID(provider, id string) bool {
+	switch provider {
+	case ProviderSlack:
+		return slackUserID.MatchString(id)
+	case ProviderMattermost:
+		return mattermostUsername.MatchString(id) && !mattermostReserved[id]
+	default:
+		return false
+	}
+}
+
+// mentionBreak разрывает «@» невидимым пробелом, чтобы текст вида @channel не стал упоминанием
+const mentionBreak = "@\u200b"
+
+// slackEscape экранирует управляющие символы разметки Slack (в том числе <!channel>) и упоминания
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "@", mentionBreak).Replace
+
+// markdownEscape экранирует символы выделения Markdown и упоминания
+var markdownEscape = strings.NewReplacer("*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "@", mentionBreak).Replace
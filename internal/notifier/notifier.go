@@ -0,0 +1,103 @@
+// Package notifier отправляет в чат (Slack, Mattermost) сообщения о назначении ревьюверов
+// через incoming webhook.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Провайдеры чатов
+const (
+	ProviderSlack      = "slack"
+	ProviderMattermost = "mattermost"
+)
+
+// ErrRejected возвращается, если чат отклонил сообщение (ответ 4xx, кроме 429):
+// повтор не поможет, нужно исправить настройку канала
+var ErrRejected = errors.New("message rejected by chat")
+
+// User участник назначения: имя в сервисе и ID в чате, если он привязан
+// (member ID для Slack, имя пользователя для Mattermost)
+type User struct {
+	Name   string
+	ChatID string
+}
+
+// Assignment назначение ревьювера, о котором сообщается в канал команды
+type Assignment struct {
+	PullRequestID    string
+	PullRequestName  string
+	Author           User
+	Reviewer         User
+	PreviousReviewer User // Name непусто при переназначении
+}
+
+// Formatter формирует тело запроса к incoming webhook чата
+type Formatter interface {
+	Format(a Assignment, channel string) ([]byte, error)
+}
+
+// formatters форматтеры поддерживаемых провайдеров
+var formatters = map[string]Formatter{
+	ProviderSlack:      Slack{},
+	ProviderMattermost: Mattermost{},
+}
+
+// Supported проверяет, что провайдер чата поддерживается
+func Supported(provider string) bool {
+	_, ok := formatters[provider]
+	return ok
+}
+
+// Notifier отправляет сообщения в incoming webhook чатов
+type Notifier struct {
+	client *http.Client
+}
+
+// New создаёт Notifier; client nil — клиент с таймаутом 10 секунд
+func New(client *http.Client) *Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Notifier{client: client}
+}
+
+// NotifyAssignment отправляет сообщение о назначении в webhookURL провайдера provider.
+// channel переопределяет канал webhook, если провайдер это поддерживает.
+func (n *Notifier) NotifyAssignment(ctx context.Context, provider, webhookURL, channel string, a Assignment) error {
+	f, ok := formatters[provider]
+	if !ok {
+		return fmt.Errorf("%w: unknown provider %q", ErrRejected, provider)
+	}
+	body, err := f.Format(a, channel)
+	if err != nil {
+		return fmt.Errorf("format message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s status %d: %s", ErrRejected, provider, resp.StatusCode, bytes.TrimSpace(msg))
+	default:
+		return fmt.Errorf("%s status %d: %s", provider, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+}
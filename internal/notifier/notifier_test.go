@@ -0,0 +1,157 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// capture поднимает incoming webhook, который отвечает status и сохраняет тело запроса
+func capture(t *testing.T, status int) (*httptest.Server, *map[string]string) {
+	t.Helper()
+	var payload map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			t.Errorf("decode payload %s: %v", raw, err)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &payload
+}
+
+func TestSlackPayload(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a    Assignment
+		want string
+	}{
+		{
+			name: "mentions by member id",
+			a: Assignment{PullRequestID: "pr-1", PullRequestName: "Add cache",
+				Author: User{Name: "alice", ChatID: "U01AUTHOR"}, Reviewer: User{Name: "bob", ChatID: "U02REVIEW"}},
+			want: "<@U02REVIEW> was assigned to review *Add cache* (pr-1) (author: <@U01AUTHOR>)",
+		},
+		{
+			name: "reassignment without linked accounts",
+			a: Assignment{PullRequestID: "pr-1", PullRequestName: "Add cache",
+				Author: User{Name: "alice"}, Reviewer: User{Name: "bob"}, PreviousReviewer: User{Name: "carol"}},
+			want: "bob was assigned to review *Add cache* (pr-1) instead of carol (author: alice)",
+		},
+		{
+			name: "escapes names and title",
+			a: Assignment{PullRequestID: "pr-1", PullRequestName: "<!channel> & @here",
+				Author: User{Name: "<!channel>"}, Reviewer: User{Name: "@everyone", ChatID: "<!here>"}},
+			want: "@\u200beveryone was assigned to review *&lt;!channel&gt; &amp; @\u200bhere* (pr-1) (author: &lt;!channel&gt;)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, payload := capture(t, http.StatusOK)
+			if err := New(srv.Client()).NotifyAssignment(context.Background(), ProviderSlack, srv.URL, "ignored", tc.a); err != nil {
+				t.Fatalf("NotifyAssignment: %v", err)
+			}
+			if got := (*payload)["text"]; got != tc.want {
+				t.Errorf("text = %q, want %q", got, tc.want)
+			}
+			if _, ok := (*payload)["channel"]; ok {
+				t.Errorf("slack payload must not set channel: %v", *payload)
+			}
+		})
+	}
+}
+
+func TestMattermostPayload(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		a       Assignment
+		channel string
+		want    map[string]string
+	}{
+		{
+			name: "mentions only linked usernames",
+			a: Assignment{PullRequestID: "pr-1", PullRequestName: "Add cache",
+				Author: User{Name: "alice"}, Reviewer: User{Name: "Bob", ChatID: "bob.smith"}, PreviousReviewer: User{Name: "carol"}},
+			channel: "reviews",
+			want: map[string]string{
+				"text":    "@bob.smith was assigned to review **Add cache** (pr-1) instead of carol (author: alice)",
+				"channel": "reviews",
+			},
+		},
+		{
+			name: "does not mention the whole channel",
+			a: Assignment{PullRequestID: "pr_1", PullRequestName: "fix *all* @channel",
+				Author: User{Name: "channel"}, Reviewer: User{Name: "all", ChatID: "here"}},
+			want: map[string]string{
+				"text": "all was assigned to review **fix \\*all\\* @\u200bchannel** (pr\\_1) (author: channel)",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, payload := capture(t, http.StatusOK)
+			if err := New(srv.Client()).NotifyAssignment(context.Background(), ProviderMattermost, srv.URL, tc.channel, tc.a); err != nil {
+				t.Fatalf("NotifyAssignment: %v", err)
+			}
+			if len(*payload) != len(tc.want) {
+				t.Errorf("payload = %v, want %v", *payload, tc.want)
+			}
+			for k, want := range tc.want {
+				if got := (*payload)[k]; got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyAssignmentStatus(t *testing.T) {
+	a := Assignment{PullRequestID: "pr-1", Author: User{Name: "alice"}, Reviewer: User{Name: "bob"}}
+	for _, tc := range []struct {
+		status   int
+		wantErr  bool
+		rejected bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNotFound, true, true},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusBadGateway, true, false},
+	} {
+		srv, _ := capture(t, tc.status)
+		err := New(srv.Client()).NotifyAssignment(context.Background(), ProviderMattermost, srv.URL, "", a)
+		if (err != nil) != tc.wantErr || errors.Is(err, ErrRejected) != tc.rejected {
+			t.Errorf("status %d: err = %v, want error %v, rejected %v", tc.status, err, tc.wantErr, tc.rejected)
+		}
+	}
+
+	if err := New(nil).NotifyAssignment(context.Background(), "teams", "http://127.0.0.1", "", a); !errors.Is(err, ErrRejected) {
+		t.Errorf("unknown provider: err = %v, want ErrRejected", err)
+	}
+}
+
+func TestValidUserID(t *testing.T) {
+	for _, tc := range []struct {
+		provider, id string
+		want         bool
+	}{
+		{ProviderSlack, "U012AB3CD", true},
+		{ProviderSlack, "W012AB3CD", true},
+		{ProviderSlack, "u012ab3cd", false},
+		{ProviderSlack, "<!channel>", false},
+		{ProviderMattermost, "alice.smith", true},
+		{ProviderMattermost, "channel", false},
+		{ProviderMattermost, "Alice", false},
+		{ProviderMattermost, "al", false},
+		{"teams", "alice", false},
+	} {
+		if got := ValidUserID(tc.provider, tc.id); got != tc.want {
+			t.Errorf("ValidUserID(%s, %q) = %v, want %v", tc.provider, tc.id, got, tc.want)
+		}
+	}
+}
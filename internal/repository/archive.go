@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ArchivePullRequests переносит до limit закрытых pull request'ов, закрытых раньше cutoff,
+// вместе с рецензентами в архивные таблицы и возвращает число перенесённых PR.
+// Время закрытия — merged_at или closed_at, а при их отсутствии created_at. Строки, заблокированные
+// другими транзакциями, пропускаются до следующего запуска. Вызывается внутри WithTx.
+func (s *Storage) ArchivePullRequests(cutoff time.Time, limit int) (int, error) {
+	rows, err := s.db.Query(`
+        SELECT pull_request_id FROM pull_requests
+        WHERE status <> 'OPEN' AND COALESCE(merged_at, closed_at, created_at) < $1
+        ORDER BY pull_request_id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("select prs to archive: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("scan pr id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("select prs to archive: %w", err)
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = s.db.Exec(`
+        INSERT INTO pull_requests_archive
+            (pull_request_id, pull_request_name, author_id, status, created_at, merged_at, closed_at, labels, version, team_name)
+        SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at, closed_at, labels, version, team_name
+        FROM pull_requests WHERE pull_request_id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("archive prs: %w", err)
+	}
+	_, err = s.db.Exec(`
+        INSERT INTO reviewers_archive (pull_request_id, user_id, review_state)
+        SELECT pull_request_id, user_id, review_state
+        FROM reviewers WHERE pull_request_id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("archive reviewers: %w", err)
+	}
+	// рецензенты удаляются каскадно
+	if _, err := s.db.Exec(`DELETE FROM pull_requests WHERE pull_request_id = ANY($1)`, pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("delete archived prs: %w", err)
+	}
+	return len(ids), nil
+}
+
+// PullRequestArchived проверяет, есть ли pull request в архиве
+func (s *Storage) PullRequestArchived(prID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pull_requests_archive WHERE pull_request_id=$1)`, prID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check archived pr: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// backupTable таблица, входящая в резервную копию, и запрос, выгружающий её строки
+// в формате JSON в порядке, допустимом для вставки (родители раньше потомков)
+type backupTable struct {
+	name  string
+	query string
+}
+
+// backupTables таблицы резервной копии в порядке зависимостей по внешним ключам.
+// idempotency_keys не сохраняется: это временные данные с ограниченным сроком жизни;
+// подписки на webhook, каналы уведомлений, журнал доставок, outbox событий и задания
+// по событиям тоже не сохраняются — это настройки и очереди интеграций конкретной установки.
+var backupTables = []backupTable{
+	{name: "organizations", query: `SELECT row_to_json(t) FROM organizations t ORDER BY org_name`},
+	{name: "organization_policies", query: `SELECT row_to_json(t) FROM organization_policies t ORDER BY org_name`},
+	{name: "teams", query: `
+        WITH RECURSIVE tree AS (
+            SELECT team_name, 0 AS depth FROM teams WHERE parent_team IS NULL
+            UNION ALL
+            SELECT c.team_name, tree.depth + 1 FROM teams c JOIN tree ON c.parent_team = tree.team_name
+        )
+        SELECT row_to_json(t) FROM teams t JOIN tree USING (team_name)
+        ORDER BY tree.depth, t.team_name
+    `},
+	{name: "team_policies", query: `SELECT row_to_json(t) FROM team_policies t ORDER BY team_name`},
+	{name: "users", query: `SELECT row_to_json(t) FROM users t ORDER BY user_id`},
+	{name: "notification_opt_outs", query: `SELECT row_to_json(t) FROM notification_opt_outs t ORDER BY user_id`},
+	{name: "external_accounts", query: `SELECT row_to_json(t) FROM external_accounts t ORDER BY provider, login`},
+	{name: "team_memberships", query: `SELECT row_to_json(t) FROM team_memberships t ORDER BY team_name, user_id`},
+	{name: "pull_requests", query: `SELECT row_to_json(t) FROM pull_requests t ORDER BY pull_request_id`},
+	{name: "pull_request_refs", query: `SELECT row_to_json(t) FROM pull_request_refs t ORDER BY pull_request_id`},
+	{name: "reviewers", query: `SELECT row_to_json(t) FROM reviewers t ORDER BY pull_request_id, user_id`},
+	{name: "pull_requests_archive", query: `SELECT row_to_json(t) FROM pull_requests_archive t ORDER BY pull_request_id`},
+	{name: "reviewers_archive", query: `SELECT row_to_json(t) FROM reviewers_archive t ORDER BY pull_request_id, user_id`},
+}
+
+// BackupTables возвращает имена таблиц резервной копии в порядке восстановления
+func BackupTables() []string {
+	names := make([]string, 0, len(backupTables))
+	for _, t := range backupTables {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// findBackupTable ищет таблицу резервной копии по имени
+func findBackupTable(name string) (backupTable, bool) {
+	for _, t := range backupTables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return backupTable{}, false
+}
+
+// BeginSnapshot переводит текущую транзакцию в режим согласованного чтения,
+// чтобы все таблицы выгружались на один момент времени. Вызывается первым запросом в WithTx.
+func (s *Storage) BeginSnapshot() error {
+	if _, err := s.db.Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY`); err != nil {
+		return fmt.Errorf("begin snapshot: %w", err)
+	}
+	return nil
+}
+
+// DumpTable выгружает строки таблицы резервной копии в виде JSON-объектов
+func (s *Storage) DumpTable(table string, fn func(row json.RawMessage) error) error {
+	t, ok := findBackupTable(table)
+	if !ok {
+		return fmt.Errorf("dump table: unknown table %q", table)
+	}
+	rows, err := s.db.Query(t.query)
+	if err != nil {
+		return fmt.Errorf("dump %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return fmt.Errorf("scan %s: %w", table, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// BackupTablesEmpty проверяет, что все таблицы резервной копии пусты
+func (s *Storage) BackupTablesEmpty() (bool, error) {
+	for _, t := range backupTables {
+		var exists bool
+		if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + pq.QuoteIdentifier(t.name) + `)`).Scan(&exists); err != nil {
+			return false, fmt.Errorf("check %s: %w", t.name, err)
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RestoreRows вставляет строки резервной копии в таблицу. Заполняются только колонки,
+// присутствующие в строках, поэтому колонки, добавленные после создания копии,
+// получают значения по умолчанию; неизвестные колонки считаются ошибкой.
+func (s *Storage) RestoreRows(table string, rows []json.RawMessage) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if _, ok := findBackupTable(table); !ok {
+		return fmt.Errorf("restore: unknown table %q", table)
+	}
+
+	var first map[string]json.RawMessage
+	if err := json.Unmarshal(rows[0], &first); err != nil {
+		return fmt.Errorf("restore %s: invalid row: %w", table, err)
+	}
+	known, err := s.tableColumns(table)
+	if err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(first))
+	for c := range first {
+		if !known[c] {
+			return fmt.Errorf("restore %s: unknown column %q", table, c)
+		}
+		columns = append(columns, pq.QuoteIdentifier(c))
+	}
+	sort.Strings(columns)
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", table, err)
+	}
+	list := strings.Join(columns, ", ")
+	quoted := pq.QuoteIdentifier(table)
+	_, err = s.db.Exec(`INSERT INTO `+quoted+` (`+list+`) SELECT `+list+` FROM json_populate_recordset(NULL::`+quoted+`, $1)`, string(data))
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", table, err)
+	}
+	return nil
+}
+
+// tableColumns получает набор колонок таблицы
+func (s *Storage) tableColumns(table string) (map[string]bool, error) {
+	rows, err := s.db.Query(`
+        SELECT column_name FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = $1
+    `, table)
+	if err != nil {
+		return nil, fmt.Errorf("list columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pr-review-manager/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// LinkAccount привязывает логин на code host к пользователю. Возвращает false,
+// если логин уже привязан к другому пользователю.
+func (s *Storage) LinkAccount(a models.ExternalAccount) (bool, error) {
+	var userID string
+	err := s.db.QueryRow(`
+        INSERT INTO external_accounts (provider, login, user_id) VALUES ($1,$2,$3)
+        ON CONFLICT (provider, login) DO UPDATE SET user_id = external_accounts.user_id
+        RETURNING user_id
+    `, a.Provider, a.Login, a.UserID).Scan(&userID)
+	if err != nil {
+		return false, fmt.Errorf("link account: %w", err)
+	}
+	return userID == a.UserID, nil
+}
+
+// UnlinkAccount удаляет привязку логина на code host и возвращает пользователя, к которому он был привязан
+func (s *Storage) UnlinkAccount(provider, login string) (string, error) {
+	var userID string
+	err := s.db.QueryRow(`DELETE FROM external_accounts WHERE provider=$1 AND login=$2 RETURNING user_id`, provider, login).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("unlink account: not found")
+		}
+		return "", fmt.Errorf("unlink account: %w", err)
+	}
+	return userID, nil
+}
+
+// ResolveAccount получает user_id по логину на code host; пустая строка, если логин не привязан
+func (s *Storage) ResolveAccount(provider, login string) (string, error) {
+	var userID string
+	err := s.db.QueryRow(`SELECT user_id FROM external_accounts WHERE provider=$1 AND login=$2`, provider, login).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("resolve account: %w", err)
+	}
+	return userID, nil
+}
+
+// CreatePullRequestRef связывает pull request с PR на code host
+func (s *Storage) CreatePullRequestRef(prID string, ref models.ExternalPullRequestRef) error {
+	_, err := s.db.Exec(`
+        INSERT INTO pull_request_refs (pull_request_id, provider, repository, number) VALUES ($1,$2,$3,$4)
+    `, prID, ref.Provider, ref.Repository, ref.Number)
+	if err != nil {
+		return fmt.Errorf("create pr ref: %w", err)
+	}
+	return nil
+}
+
+// FindPullRequestByRef получает ID pull request'а по PR на code host; пустая строка, если связи нет
+func (s *Storage) FindPullRequestByRef(ref models.ExternalPullRequestRef) (string, error) {
+	var prID string
+	err := s.db.QueryRow(`
+        SELECT pull_request_id FROM pull_request_refs WHERE provider=$1 AND repository=$2 AND number=$3
+    `, ref.Provider, ref.Repository, ref.Number).Scan(&prID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("find pr by ref: %w", err)
+	}
+	return prID, nil
+}
+
+// SetReviewState выставляет состояние ревью рецензента PR и увеличивает версию PR.
+// Возвращает false, если пользователь не назначен рецензентом.
+func (s *Storage) SetReviewState(prID, userID string, state models.ReviewState) (bool, error) {
+	res, err := s.db.Exec(`
+        UPDATE reviewers SET review_state=$1 WHERE pull_request_id=$2 AND user_id=$3
+    `, string(state), prID, userID)
+	if err != nil {
+		return false, fmt.Errorf("set review state: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+	return true, s.bumpPullRequestVersion(prID)
+}
+
+// CountApprovals возвращает число рецензентов PR, одобривших его
+func (s *Storage) CountApprovals(prID string) (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+        SELECT COUNT(*) FROM reviewers WHERE pull_request_id=$1 AND review_state='APPROVED'
+    `, prID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count approvals: %w", err)
+	}
+	return n, nil
+}
+
+// GetPullRequestRef получает PR на code host, с которым связан pull request; nil, если связи нет
+func (s *Storage) GetPullRequestRef(prID string) (*models.ExternalPullRequestRef, error) {
+	var ref models.ExternalPullRequestRef
+	err := s.db.QueryRow(`
+        SELECT provider, repository, number FROM pull_request_refs WHERE pull_request_id=$1
+    `, prID).Scan(&ref.Provider, &ref.Repository, &ref.Number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get pr ref: %w", err)
+	}
+	return &ref, nil
+}
+
+// ListAccountLogins получает логины пользователей на code host; пользователи без привязки пропускаются
+func (s *Storage) ListAccountLogins(provider string, userIDs []string) (map[string]string, error) {
+	rows, err := s.db.Query(`
+        SELECT user_id, login FROM external_accounts WHERE provider=$1 AND user_id = ANY($2)
+        ORDER BY login
+    `, provider, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list account logins: %w", err)
+	}
+	defer rows.Close()
+
+	logins := map[string]string{}
+	for rows.Next() {
+		var userID, login string
+		if err := rows.Scan(&userID, &login); err != nil {
+			return nil, fmt.Errorf("scan account login: %w", err)
+		}
+		logins[userID] = login
+	}
+	return logins, rows.Err()
+}
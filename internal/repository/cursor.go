@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации не удалось разобрать
+// или он выдан для другого порядка сортировки
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Префиксы порядков сортировки, для которых выдаются курсоры списков
+const (
+	cursorSortMembers = "team_members:user_id"
+	cursorSortReviews = "user_reviews"
+	cursorSortArchive = "archive:"
+	cursorSortStats   = "stats_users:user_id"
+	cursorSortHooks   = "webhook_subscriptions:created_at"
+	cursorSortDeliver = "webhook_deliveries:created_at"
+)
+
+// pageCursor позиция последней выданной строки: значение ключа сортировки и ID
+type pageCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+// encodeCursor кодирует позицию в непрозрачную для клиента строку
+func encodeCursor(sort, key, id string) string {
+	data, _ := json.Marshal(pageCursor{Sort: sort, Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор, выданный для того же порядка сортировки
+func decodeCursor(cursor, sort string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := encodeCursor("user_reviews:created_at:asc", "2026-01-01T00:00:00Z", "pr-7")
+
+	c, err := decodeCursor(cursor, "user_reviews:created_at:asc")
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if c.Key != "2026-01-01T00:00:00Z" || c.ID != "pr-7" {
+		t.Errorf("cursor %+v, want key and id of the last row", c)
+	}
+}
+
+func TestCursorRejectsOtherSort(t *testing.T) {
+	cursor := encodeCursor("user_reviews:created_at:asc", "2026-01-01T00:00:00Z", "pr-7")
+
+	if _, err := decodeCursor(cursor, "user_reviews:created_at:desc"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another order: %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestCursorRejectsGarbage(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := decodeCursor(cursor, cursorSortMembers); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%q: %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+// ReserveIdempotencyKey занимает ключ для нового запроса. Если ключ уже занят и не истёк,
+// возвращает сохранённую запись и false; запись с нулевым StatusCode ещё выполняется.
+// Истёкший ключ занимается заново; удаляет истёкшие ключи PurgeIdempotencyKeys.
+func (s *Storage) ReserveIdempotencyKey(key models.IdempotencyKey, requestHash string, ttl time.Duration) (models.IdempotentResponse, bool, error) {
+	var stored models.IdempotentResponse
+	res, err := s.db.Exec(`
+        INSERT INTO idempotency_keys (idempotency_key, method, path, request_hash, expires_at)
+        VALUES ($1,$2,$3,$4, now() + $5 * interval '1 microsecond')
+        ON CONFLICT (idempotency_key, method, path) DO UPDATE
+        SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = '', etag = '',
+            body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= now()
+    `, key.Key, key.Method, key.Path, requestHash, ttl.Microseconds())
+	if err != nil {
+		return stored, false, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 1 {
+		return stored, true, nil
+	}
+
+	var statusCode sql.NullInt64
+	err = s.db.QueryRow(`
+        SELECT request_hash, status_code, content_type, etag, body
+        FROM idempotency_keys
+        WHERE idempotency_key=$1 AND method=$2 AND path=$3
+    `, key.Key, key.Method, key.Path).Scan(&stored.RequestHash, &statusCode, &stored.ContentType, &stored.ETag, &stored.Body)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// ключ освободили между вставкой и чтением: клиент может повторить запрос
+			return stored, false, nil
+		}
+		return stored, false, fmt.Errorf("get idempotency key: %w", err)
+	}
+	stored.StatusCode = int(statusCode.Int64)
+	return stored, false, nil
+}
+
+// SaveIdempotentResponse сохраняет ответ на запрос с занятым ключом
+func (s *Storage) SaveIdempotentResponse(key models.IdempotencyKey, resp models.IdempotentResponse) error {
+	_, err := s.db.Exec(`
+        UPDATE idempotency_keys SET status_code=$1, content_type=$2, etag=$3, body=$4
+        WHERE idempotency_key=$5 AND method=$6 AND path=$7
+    `, resp.StatusCode, resp.ContentType, resp.ETag, resp.Body, key.Key, key.Method, key.Path)
+	if err != nil {
+		return fmt.Errorf("save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы повтор запроса выполнился заново
+func (s *Storage) ReleaseIdempotencyKey(key models.IdempotencyKey) error {
+	_, err := s.db.Exec(`
+        DELETE FROM idempotency_keys WHERE idempotency_key=$1 AND method=$2 AND path=$3
+    `, key.Key, key.Method, key.Path)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет истёкшие ключи и возвращает их число
+func (s *Storage) PurgeIdempotencyKeys() (int, error) {
+	res, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return int(affected), nil
+}
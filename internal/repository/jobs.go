@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+// Виды заданий по событиям outbox
+const (
+	// JobCodeHostSync передать ревьюверов PR на code host
+	JobCodeHostSync = "codehost_sync"
+	// JobChatNotify отправить уведомление о назначении в чат команды
+	JobChatNotify = "chat_notify"
+)
+
+// EventJob задание по событию outbox
+type EventJob struct {
+	ID       int64
+	Kind     string
+	Event    models.DomainEvent
+	Attempts int
+}
+
+// EnqueueEventJob ставит в очередь задание kind по событию ev. Повторная публикация
+// события не создаёт второго задания того же вида.
+func (s *Storage) EnqueueEventJob(kind string, ev models.DomainEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event %s: %w", ev.Type, err)
+	}
+	_, err = s.db.Exec(`
+        INSERT INTO event_jobs (kind, event_id, payload, next_attempt_at) VALUES ($1,$2,$3,$4)
+        ON CONFLICT (kind, event_id) DO NOTHING
+    `, kind, ev.ID, string(payload), ev.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("enqueue event job: %w", err)
+	}
+	return nil
+}
+
+// ClaimEventJobs берёт до limit заданий, срок которых наступил к now, в порядке
+// постановки и откладывает их следующую попытку до leaseUntil, чтобы параллельные
+// обработчики не выполнили их повторно.
+func (s *Storage) ClaimEventJobs(now, leaseUntil time.Time, limit int) ([]EventJob, error) {
+	rows, err := s.db.Query(`
+        UPDATE event_jobs SET next_attempt_at = $2
+        WHERE id IN (
+            SELECT id FROM event_jobs
+            WHERE status = 'PENDING' AND next_attempt_at <= $1
+            ORDER BY id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, kind, payload, attempts
+    `, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim event jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []EventJob
+	for rows.Next() {
+		var j EventJob
+		var payload []byte
+		if err := rows.Scan(&j.ID, &j.Kind, &payload, &j.Attempts); err != nil {
+			return nil, fmt.Errorf("scan event job: %w", err)
+		}
+		if err := json.Unmarshal(payload, &j.Event); err != nil {
+			return nil, fmt.Errorf("decode event job %d: %w", j.ID, err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// CompleteEventJob отмечает задание выполненным
+func (s *Storage) CompleteEventJob(id int64, at time.Time) error {
+	_, err := s.db.Exec(`
+        UPDATE event_jobs SET status='DONE', attempts=attempts+1, last_error=NULL, done_at=$2 WHERE id=$1
+    `, id, at)
+	if err != nil {
+		return fmt.Errorf("complete event job: %w", err)
+	}
+	return nil
+}
+
+// FailEventJob записывает неудачную попытку задания. Если nextAttempt nil,
+// задание переносится в dead-letter (статус DEAD).
+func (s *Storage) FailEventJob(id int64, lastError string, nextAttempt *time.Time) error {
+	status := "PENDING"
+	if nextAttempt == nil {
+		status = "DEAD"
+	}
+	_, err := s.db.Exec(`
+        UPDATE event_jobs
+        SET status=$2, attempts=attempts+1, last_error=$3, next_attempt_at=COALESCE($4, next_attempt_at)
+        WHERE id=$1
+    `, id, status, sqlNullString(lastError), nextAttempt)
+	if err != nil {
+		return fmt.Errorf("fail event job: %w", err)
+	}
+	return nil
+}
+
+// PurgeEventJobs удаляет выполненные задания, поставленные раньше before
+func (s *Storage) PurgeEventJobs(before time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM event_jobs WHERE status = 'DONE' AND created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge event jobs: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pr-review-manager/internal/models"
+)
+
+// SetNotificationChannel создаёт или заменяет канал уведомлений команды
+func (s *Storage) SetNotificationChannel(ch models.NotificationChannel) error {
+	_, err := s.db.Exec(`
+        INSERT INTO team_notification_channels (team_name, provider, webhook_url, channel) VALUES ($1,$2,$3,$4)
+        ON CONFLICT (team_name) DO UPDATE
+        SET provider = EXCLUDED.provider, webhook_url = EXCLUDED.webhook_url, channel = EXCLUDED.channel
+    `, ch.TeamName, ch.Provider, ch.WebhookURL, sqlNullString(ch.Channel))
+	if err != nil {
+		return fmt.Errorf("set notification channel: %w", err)
+	}
+	return nil
+}
+
+// DeleteNotificationChannel удаляет канал уведомлений команды и возвращает удалённый канал
+func (s *Storage) DeleteNotificationChannel(teamName string) (models.NotificationChannel, error) {
+	ch := models.NotificationChannel{TeamName: teamName}
+	var channel sql.NullString
+	err := s.db.QueryRow(`
+        DELETE FROM team_notification_channels WHERE team_name=$1 RETURNING provider, webhook_url, channel
+    `, teamName).Scan(&ch.Provider, &ch.WebhookURL, &channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ch, fmt.Errorf("delete notification channel: not found")
+		}
+		return ch, fmt.Errorf("delete notification channel: %w", err)
+	}
+	ch.Channel = channel.String
+	return ch, nil
+}
+
+// GetNotificationChannel получает собственный канал уведомлений команды; nil, если он не задан
+func (s *Storage) GetNotificationChannel(teamName string) (*models.NotificationChannel, error) {
+	ch := models.NotificationChannel{TeamName: teamName}
+	var channel sql.NullString
+	err := s.db.QueryRow(`
+        SELECT provider, webhook_url, channel FROM team_notification_channels WHERE team_name=$1
+    `, teamName).Scan(&ch.Provider, &ch.WebhookURL, &channel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get notification channel: %w", err)
+	}
+	ch.Channel = channel.String
+	return &ch, nil
+}
+
+// SetNotificationsEnabled включает или отключает уведомления пользователя
+func (s *Storage) SetNotificationsEnabled(userID string, enabled bool) error {
+	var err error
+	if enabled {
+		_, err = s.db.Exec(`DELETE FROM notification_opt_outs WHERE user_id=$1`, userID)
+	} else {
+		_, err = s.db.Exec(`INSERT INTO notification_opt_outs (user_id) VALUES ($1) ON CONFLICT DO NOTHING`, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("set notifications enabled: %w", err)
+	}
+	return nil
+}
+
+// NotificationsEnabled проверяет, что пользователь не отказался от уведомлений
+func (s *Storage) NotificationsEnabled(userID string) (bool, error) {
+	var optedOut bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM notification_opt_outs WHERE user_id=$1)`, userID).Scan(&optedOut)
+	if err != nil {
+		return false, fmt.Errorf("check notifications enabled: %w", err)
+	}
+	return !optedOut, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pr-review-manager/internal/models"
+)
+
+// CreateOrganization создаёт организацию
+func (s *Storage) CreateOrganization(orgName string) error {
+	_, err := s.db.Exec(`INSERT INTO organizations (org_name) VALUES ($1)`, orgName)
+	if err != nil {
+		return fmt.Errorf("create organization: %w", err)
+	}
+	return nil
+}
+
+// GetOrganization получает организацию с её собственными настройками и командами
+func (s *Storage) GetOrganization(orgName string) (models.Organization, error) {
+	org := models.Organization{OrgName: orgName, Teams: []string{}}
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM organizations WHERE org_name=$1)`, orgName).Scan(&exists)
+	if err != nil {
+		return org, fmt.Errorf("get organization: %w", err)
+	}
+	if !exists {
+		return org, fmt.Errorf("organization not found: %w", sql.ErrNoRows)
+	}
+
+	if org.Policy, err = s.GetOrganizationPolicy(orgName); err != nil {
+		return org, err
+	}
+
+	rows, err := s.db.Query(`SELECT team_name FROM teams WHERE org_name=$1 ORDER BY team_name`, orgName)
+	if err != nil {
+		return org, fmt.Errorf("list organization teams: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			return org, fmt.Errorf("scan organization team: %w", err)
+		}
+		org.Teams = append(org.Teams, team)
+	}
+	return org, rows.Err()
+}
+
+// GetOrganizationPolicy получает собственные настройки организации
+func (s *Storage) GetOrganizationPolicy(orgName string) (models.TeamPolicy, error) {
+	var p models.TeamPolicy
+	var reviewerCount, requiredApprovals, maxOpenReviews sql.NullInt64
+	err := s.db.QueryRow(`
+        SELECT reviewer_count, required_approvals, max_open_reviews
+        FROM organization_policies WHERE org_name=$1
+    `, orgName).Scan(&reviewerCount, &requiredApprovals, &maxOpenReviews)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, nil
+		}
+		return p, fmt.Errorf("get organization policy: %w", err)
+	}
+	p.ReviewerCount = nullIntPtr(reviewerCount)
+	p.RequiredApprovals = nullIntPtr(requiredApprovals)
+	p.MaxOpenReviews = nullIntPtr(maxOpenReviews)
+	return p, nil
+}
+
+// SetOrganizationPolicy заменяет собственные настройки организации
+func (s *Storage) SetOrganizationPolicy(orgName string, p models.TeamPolicy) error {
+	_, err := s.db.Exec(`
+        INSERT INTO organization_policies (org_name, reviewer_count, required_approvals, max_open_reviews)
+        VALUES ($1,$2,$3,$4)
+        ON CONFLICT (org_name) DO UPDATE
+        SET reviewer_count = EXCLUDED.reviewer_count,
+            required_approvals = EXCLUDED.required_approvals,
+            max_open_reviews = EXCLUDED.max_open_reviews
+    `, orgName, intPtrValue(p.ReviewerCount), intPtrValue(p.RequiredApprovals), intPtrValue(p.MaxOpenReviews))
+	if err != nil {
+		return fmt.Errorf("set organization policy: %w", err)
+	}
+	return nil
+}
+
+// GetTeamOrganization получает организацию, к которой напрямую отнесена команда (пустая строка, если нет)
+func (s *Storage) GetTeamOrganization(teamName string) (string, error) {
+	var org sql.NullString
+	err := s.db.QueryRow(`SELECT org_name FROM teams WHERE team_name=$1`, teamName).Scan(&org)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("team not found: %w", err)
+		}
+		return "", fmt.Errorf("get team organization: %w", err)
+	}
+	return org.String, nil
+}
+
+// SetTeamOrganization относит команду к организации (пустая строка убирает связь)
+func (s *Storage) SetTeamOrganization(teamName, orgName string) error {
+	res, err := s.db.Exec(`UPDATE teams SET org_name=$1, version = version + 1 WHERE team_name=$2`, sqlNullString(orgName), teamName)
+	if err != nil {
+		return fmt.Errorf("set team organization: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("set team organization: not found")
+	}
+	return nil
+}
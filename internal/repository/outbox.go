@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pr-review-manager/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// OutboxEvent событие из outbox с номером записи
+type OutboxEvent struct {
+	ID    int64
+	Event models.DomainEvent
+}
+
+// AddOutboxEvents записывает события в outbox. Вызывается в транзакции, изменяющей данные,
+// чтобы события сохранялись тогда и только тогда, когда фиксируются изменения.
+func (s *Storage) AddOutboxEvents(events ...models.DomainEvent) error {
+	for _, ev := range events {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("marshal event %s: %w", ev.Type, err)
+		}
+		_, err = s.db.Exec(`
+            INSERT INTO outbox_events (event_id, event_type, payload, created_at) VALUES ($1,$2,$3,$4)
+        `, ev.ID, string(ev.Type), string(payload), ev.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("add outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClaimOutboxEvents блокирует до limit неопубликованных событий в порядке записи.
+// Вызывается в транзакции; события, заблокированные другим обработчиком, пропускаются.
+func (s *Storage) ClaimOutboxEvents(limit int) ([]OutboxEvent, error) {
+	rows, err := s.db.Query(`
+        SELECT id, payload FROM outbox_events
+        WHERE published_at IS NULL
+        ORDER BY id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&e.ID, &payload); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		if err := json.Unmarshal(payload, &e.Event); err != nil {
+			return nil, fmt.Errorf("decode outbox event %d: %w", e.ID, err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkOutboxPublished отмечает события опубликованными
+func (s *Storage) MarkOutboxPublished(ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.db.Exec(`UPDATE outbox_events SET published_at=$2 WHERE id = ANY($1)`, pq.Array(ids), at)
+	if err != nil {
+		return fmt.Errorf("mark outbox published: %w", err)
+	}
+	return nil
+}
+
+// PurgeOutboxEvents удаляет опубликованные события, записанные раньше before
+func (s *Storage) PurgeOutboxEvents(before time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM outbox_events WHERE published_at IS NOT NULL AND created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox events: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
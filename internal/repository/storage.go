@@ -5,15 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"pr-review-manager/internal/models"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ErrVersionConflict возвращается, если запись изменилась с момента чтения
+var ErrVersionConflict = errors.New("version conflict")
+
+// dbtx общий набор методов *sql.DB и *sql.Tx
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type Storage struct {
-	db *sql.DB
+	db   dbtx
+	conn *sql.DB // nil, если Storage работает внутри транзакции
 }
 
 func NewStorage(databaseURL string) (*Storage, error) {
@@ -24,18 +36,39 @@ func NewStorage(databaseURL string) (*Storage, error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
-	return &Storage{db: db}, nil
+	return &Storage{db: db, conn: db}, nil
 }
 
 func (s *Storage) Close() error {
-	return s.db.Close()
+	return s.conn.Close()
+}
+
+// WithTx выполняет fn в транзакции. Storage, переданный в fn, работает внутри неё;
+// при ошибке fn транзакция откатывается. Вложенные вызовы используют внешнюю транзакцию.
+func (s *Storage) WithTx(fn func(tx *Storage) error) error {
+	if s.conn == nil {
+		return fn(s)
+	}
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Storage{db: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
 }
 
 // CreateTables создаёт таблицы в БД для хранения команд, пользователей и pull request'ов
 func (s *Storage) CreateTables(logger *slog.Logger) error {
 	logger.Info("Creating database tables")
 
-	tx, err := s.db.Begin()
+	tx, err := s.conn.Begin()
 	if err != nil {
 		logger.Error("begin tx failed", "err", err)
 		return err
@@ -53,13 +86,72 @@ func (s *Storage) CreateTables(logger *slog.Logger) error {
 		return err
 	}
 
+	// teams.parent_team: команды образуют иерархию (отдел -> команды),
+	// настройки наследуются от родителя
+	_, err = tx.Exec(`
+        ALTER TABLE teams
+        ADD COLUMN IF NOT EXISTS parent_team TEXT REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL
+    `)
+	if err != nil {
+		logger.Error("add teams.parent_team column failed", "err", err)
+		return err
+	}
+
+	// organizations: организации (уровень выше иерархии команд) со своими настройками,
+	// которые команды наследуют после цепочки родительских команд
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS organizations (
+            org_name   TEXT PRIMARY KEY,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+	if err != nil {
+		logger.Error("create organizations table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS organization_policies (
+            org_name           TEXT PRIMARY KEY REFERENCES organizations(org_name) ON UPDATE CASCADE ON DELETE CASCADE,
+            reviewer_count     INTEGER CHECK (reviewer_count >= 0),
+            required_approvals INTEGER CHECK (required_approvals >= 0),
+            max_open_reviews   INTEGER CHECK (max_open_reviews >= 0)
+        )
+    `)
+	if err != nil {
+		logger.Error("create organization_policies table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        ALTER TABLE teams
+        ADD COLUMN IF NOT EXISTS org_name TEXT REFERENCES organizations(org_name) ON UPDATE CASCADE ON DELETE SET NULL
+    `)
+	if err != nil {
+		logger.Error("add teams.org_name column failed", "err", err)
+		return err
+	}
+
+	// team_policies: собственные настройки команды; NULL означает наследование от родителя
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS team_policies (
+            team_name          TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+            reviewer_count     INTEGER CHECK (reviewer_count >= 0),
+            required_approvals INTEGER CHECK (required_approvals >= 0),
+            max_open_reviews   INTEGER CHECK (max_open_reviews >= 0)
+        )
+    `)
+	if err != nil {
+		logger.Error("create team_policies table failed", "err", err)
+		return err
+	}
+
 	// users: таблица пользователей с ссылкой на команду
 	_, err = tx.Exec(`
         CREATE TABLE IF NOT EXISTS users (
-            user_id   TEXT PRIMARY KEY,
-            username  TEXT NOT NULL,
-            is_active BOOLEAN NOT NULL DEFAULT TRUE,
-            team_name TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE
+            user_id       TEXT PRIMARY KEY,
+            username      TEXT NOT NULL,
+            is_active     BOOLEAN NOT NULL DEFAULT TRUE,
+            team_name     TEXT REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL,
+            review_weight DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (review_weight > 0)
         )
     `)
 	if err != nil {
@@ -67,12 +159,40 @@ func (s *Storage) CreateTables(logger *slog.Logger) error {
 		return err
 	}
 
+	// review_weight: добавляем колонку веса в уже существующие БД
+	_, err = tx.Exec(`
+        ALTER TABLE users
+        ADD COLUMN IF NOT EXISTS review_weight DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (review_weight > 0)
+    `)
+	if err != nil {
+		logger.Error("add users.review_weight column failed", "err", err)
+		return err
+	}
+
+	// users.team_name: пользователь может остаться без команды после её удаления или выхода из неё,
+	// а переименование команды каскадно обновляет участников
+	_, err = tx.Exec(`ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL`)
+	if err != nil {
+		logger.Error("drop users.team_name not null failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        ALTER TABLE users
+        DROP CONSTRAINT IF EXISTS users_team_name_fkey,
+        ADD CONSTRAINT users_team_name_fkey FOREIGN KEY (team_name)
+            REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL
+    `)
+	if err != nil {
+		logger.Error("alter users.team_name foreign key failed", "err", err)
+		return err
+	}
+
 	// pull_requests: таблица pull request'ов со статусом и датами
 	_, err = tx.Exec(`
         CREATE TABLE IF NOT EXISTS pull_requests (
             pull_request_id   TEXT PRIMARY KEY,
             pull_request_name TEXT NOT NULL,
-            author_id         TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+            author_id         TEXT NOT NULL REFERENCES users(user_id) ON DELETE RESTRICT,
             status            TEXT NOT NULL CHECK (status IN ('OPEN','MERGED')),
             created_at        TIMESTAMPTZ,
             merged_at         TIMESTAMPTZ
@@ -83,6 +203,19 @@ func (s *Storage) CreateTables(logger *slog.Logger) error {
 		return err
 	}
 
+	// pull_requests.author_id: удаление автора не должно каскадно удалять его PR,
+	// авторство передаётся явно перед удалением пользователя
+	_, err = tx.Exec(`
+        ALTER TABLE pull_requests
+        DROP CONSTRAINT IF EXISTS pull_requests_author_id_fkey,
+        ADD CONSTRAINT pull_requests_author_id_fkey FOREIGN KEY (author_id)
+            REFERENCES users(user_id) ON DELETE RESTRICT
+    `)
+	if err != nil {
+		logger.Error("alter pull_requests.author_id foreign key failed", "err", err)
+		return err
+	}
+
 	// reviewers: таблица для связи PR и рецензентов (макс 2 на PR)
 	_, err = tx.Exec(`
         CREATE TABLE IF NOT EXISTS reviewers (
@@ -96,6 +229,315 @@ func (s *Storage) CreateTables(logger *slog.Logger) error {
 		return err
 	}
 
+	// teams.version: версия команды для оптимистичной блокировки
+	_, err = tx.Exec(`ALTER TABLE teams ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1`)
+	if err != nil {
+		logger.Error("add teams.version column failed", "err", err)
+		return err
+	}
+
+	// pull_requests.labels и version: метки PR и версия для оптимистичной блокировки
+	_, err = tx.Exec(`
+        ALTER TABLE pull_requests
+        ADD COLUMN IF NOT EXISTS labels  TEXT[] NOT NULL DEFAULT '{}',
+        ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1
+    `)
+	if err != nil {
+		logger.Error("add pull_requests.labels and version columns failed", "err", err)
+		return err
+	}
+
+	// reviewers.review_state: состояние ревью конкретного рецензента
+	_, err = tx.Exec(`
+        ALTER TABLE reviewers
+        ADD COLUMN IF NOT EXISTS review_state TEXT NOT NULL DEFAULT 'PENDING'
+            CHECK (review_state IN ('PENDING','APPROVED','CHANGES_REQUESTED'))
+    `)
+	if err != nil {
+		logger.Error("add reviewers.review_state column failed", "err", err)
+		return err
+	}
+
+	// team_memberships: участие пользователей в командах (многие-ко-многим);
+	// users.team_name остаётся основной командой пользователя
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS team_memberships (
+            team_name TEXT NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+            user_id   TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+            PRIMARY KEY (team_name, user_id)
+        )
+    `)
+	if err != nil {
+		logger.Error("create team_memberships table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        INSERT INTO team_memberships (team_name, user_id)
+        SELECT team_name, user_id FROM users WHERE team_name IS NOT NULL
+        ON CONFLICT DO NOTHING
+    `)
+	if err != nil {
+		logger.Error("backfill team_memberships failed", "err", err)
+		return err
+	}
+
+	// pull_requests.team_name: команда, из которой выбираются ревьюверы PR
+	_, err = tx.Exec(`
+        ALTER TABLE pull_requests
+        ADD COLUMN IF NOT EXISTS team_name TEXT REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL
+    `)
+	if err != nil {
+		logger.Error("add pull_requests.team_name column failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        UPDATE pull_requests p SET team_name = u.team_name
+        FROM users u
+        WHERE p.author_id = u.user_id AND p.team_name IS NULL AND u.team_name IS NOT NULL
+    `)
+	if err != nil {
+		logger.Error("backfill pull_requests.team_name failed", "err", err)
+		return err
+	}
+
+	// idempotency_keys: первый ответ на POST-запрос с заголовком Idempotency-Key;
+	// status_code NULL означает, что запрос ещё выполняется
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS idempotency_keys (
+            idempotency_key TEXT NOT NULL,
+            method          TEXT NOT NULL,
+            path            TEXT NOT NULL,
+            request_hash    TEXT NOT NULL,
+            status_code     INT,
+            content_type    TEXT NOT NULL DEFAULT '',
+            etag            TEXT NOT NULL DEFAULT '',
+            body            BYTEA,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            expires_at      TIMESTAMPTZ NOT NULL,
+            PRIMARY KEY (idempotency_key, method, path)
+        )
+    `)
+	if err != nil {
+		logger.Error("create idempotency_keys table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at)`)
+	if err != nil {
+		logger.Error("create idempotency_keys index failed", "err", err)
+		return err
+	}
+
+	// pull_requests_archive и reviewers_archive: закрытые PR старше срока хранения,
+	// перенесённые из рабочих таблиц; ссылки на пользователей и команды не проверяются,
+	// чтобы архив не мешал их удалению
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS pull_requests_archive (
+            pull_request_id   TEXT PRIMARY KEY,
+            pull_request_name TEXT NOT NULL,
+            author_id         TEXT NOT NULL,
+            status            TEXT NOT NULL,
+            created_at        TIMESTAMPTZ,
+            merged_at         TIMESTAMPTZ,
+            labels            TEXT[] NOT NULL DEFAULT '{}',
+            version           BIGINT NOT NULL DEFAULT 1,
+            team_name         TEXT,
+            archived_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+	if err != nil {
+		logger.Error("create pull_requests_archive table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS reviewers_archive (
+            pull_request_id TEXT NOT NULL REFERENCES pull_requests_archive(pull_request_id) ON DELETE CASCADE,
+            user_id         TEXT NOT NULL,
+            review_state    TEXT NOT NULL DEFAULT 'PENDING',
+            PRIMARY KEY (pull_request_id, user_id)
+        )
+    `)
+	if err != nil {
+		logger.Error("create reviewers_archive table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS reviewers_archive_user_id_idx ON reviewers_archive (user_id)`)
+	if err != nil {
+		logger.Error("create reviewers_archive index failed", "err", err)
+		return err
+	}
+
+	// pull_requests.status CLOSED и closed_at: PR, закрытые на code host без мержа
+	_, err = tx.Exec(`
+        ALTER TABLE pull_requests
+        DROP CONSTRAINT IF EXISTS pull_requests_status_check,
+        ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN','MERGED','CLOSED')),
+        ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ
+    `)
+	if err != nil {
+		logger.Error("alter pull_requests status for CLOSED failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE pull_requests_archive ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ`)
+	if err != nil {
+		logger.Error("add pull_requests_archive.closed_at column failed", "err", err)
+		return err
+	}
+
+	// external_accounts: логины пользователей на code host (GitHub, GitLab)
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS external_accounts (
+            provider TEXT NOT NULL,
+            login    TEXT NOT NULL,
+            user_id  TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+            PRIMARY KEY (provider, login)
+        )
+    `)
+	if err != nil {
+		logger.Error("create external_accounts table failed", "err", err)
+		return err
+	}
+
+	// pull_request_refs: PR на code host, из которого создан pull request
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS pull_request_refs (
+            pull_request_id TEXT PRIMARY KEY REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+            provider        TEXT NOT NULL,
+            repository      TEXT NOT NULL,
+            number          BIGINT NOT NULL,
+            UNIQUE (provider, repository, number)
+        )
+    `)
+	if err != nil {
+		logger.Error("create pull_request_refs table failed", "err", err)
+		return err
+	}
+
+	// webhook_subscriptions: подписки внешних систем на события сервиса
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+            id          BIGSERIAL PRIMARY KEY,
+            url         TEXT NOT NULL,
+            secret      TEXT NOT NULL,
+            event_types TEXT[] NOT NULL,
+            created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+	if err != nil {
+		logger.Error("create webhook_subscriptions table failed", "err", err)
+		return err
+	}
+
+	// webhook_deliveries: доставки событий подписчикам; DEAD — исчерпаны попытки
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS webhook_deliveries (
+            id              BIGSERIAL PRIMARY KEY,
+            subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+            event_id        TEXT NOT NULL,
+            event_type      TEXT NOT NULL,
+            payload         JSONB NOT NULL,
+            status          TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING','DELIVERED','DEAD')),
+            attempts        INT NOT NULL DEFAULT 0,
+            next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            response_status INT,
+            last_error      TEXT,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            delivered_at    TIMESTAMPTZ
+        )
+    `)
+	if err != nil {
+		logger.Error("create webhook_deliveries table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING'`)
+	if err != nil {
+		logger.Error("create webhook_deliveries index failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, id)`)
+	if err != nil {
+		logger.Error("create webhook_deliveries subscription index failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS webhook_deliveries_created_idx ON webhook_deliveries (created_at, id)`)
+	if err != nil {
+		logger.Error("create webhook_deliveries created index failed", "err", err)
+		return err
+	}
+
+	// outbox_events: события, записанные в одной транзакции с изменениями;
+	// published_at заполняет обработчик outbox после рассылки
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS outbox_events (
+            id           BIGSERIAL PRIMARY KEY,
+            event_id     TEXT NOT NULL,
+            event_type   TEXT NOT NULL,
+            payload      JSONB NOT NULL,
+            created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+            published_at TIMESTAMPTZ
+        )
+    `)
+	if err != nil {
+		logger.Error("create outbox_events table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL`)
+	if err != nil {
+		logger.Error("create outbox_events index failed", "err", err)
+		return err
+	}
+
+	// event_jobs: задания обработчиков событий outbox (передача ревьюверов на code host,
+	// уведомления в чат); одно задание вида kind на событие, DEAD — исчерпаны попытки
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS event_jobs (
+            id              BIGSERIAL PRIMARY KEY,
+            kind            TEXT NOT NULL,
+            event_id        TEXT NOT NULL,
+            payload         JSONB NOT NULL,
+            status          TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING','DONE','DEAD')),
+            attempts        INT NOT NULL DEFAULT 0,
+            next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            last_error      TEXT,
+            created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+            done_at         TIMESTAMPTZ,
+            UNIQUE (kind, event_id)
+        )
+    `)
+	if err != nil {
+		logger.Error("create event_jobs table failed", "err", err)
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS event_jobs_pending_idx ON event_jobs (next_attempt_at) WHERE status = 'PENDING'`)
+	if err != nil {
+		logger.Error("create event_jobs index failed", "err", err)
+		return err
+	}
+
+	// team_notification_channels: канал чата команды для уведомлений о назначениях
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS team_notification_channels (
+            team_name   TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+            provider    TEXT NOT NULL CHECK (provider IN ('slack','mattermost')),
+            webhook_url TEXT NOT NULL,
+            channel     TEXT
+        )
+    `)
+	if err != nil {
+		logger.Error("create team_notification_channels table failed", "err", err)
+		return err
+	}
+
+	// notification_opt_outs: пользователи, отказавшиеся от уведомлений в чат
+	_, err = tx.Exec(`
+        CREATE TABLE IF NOT EXISTS notification_opt_outs (
+            user_id TEXT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE
+        )
+    `)
+	if err != nil {
+		logger.Error("create notification_opt_outs table failed", "err", err)
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		logger.Error("commit create tables failed", "err", err)
 		return err
@@ -106,7 +548,8 @@ func (s *Storage) CreateTables(logger *slog.Logger) error {
 
 // CreateTeam создаёт новую команду в БД
 func (s *Storage) CreateTeam(team models.Team) error {
-	_, err := s.db.Exec(`INSERT INTO teams (team_name) VALUES ($1)`, team.TeamName)
+	_, err := s.db.Exec(`INSERT INTO teams (team_name, parent_team, org_name) VALUES ($1,$2,$3)`,
+		team.TeamName, sqlNullString(team.ParentTeam), sqlNullString(team.Organization))
 	if err != nil {
 		return fmt.Errorf("create team: %w", err)
 	}
@@ -119,27 +562,60 @@ func (s *Storage) DeleteTeam(teamName string) error {
 	return err
 }
 
+// RenameTeam переименовывает команду; участники переносятся каскадно
+func (s *Storage) RenameTeam(teamName, newTeamName string) error {
+	res, err := s.db.Exec(`UPDATE teams SET team_name=$1, version = version + 1 WHERE team_name=$2`, newTeamName, teamName)
+	if err != nil {
+		return fmt.Errorf("rename team: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("rename team: not found")
+	}
+	return nil
+}
+
+// GetTeamInfo получает команду без участников: родительскую команду и версию
+func (s *Storage) GetTeamInfo(teamName string) (models.Team, error) {
+	t := models.Team{TeamName: teamName, Members: []models.TeamMember{}}
+	var parent, org sql.NullString
+	err := s.db.QueryRow(`SELECT parent_team, org_name, version FROM teams WHERE team_name=$1`, teamName).Scan(&parent, &org, &t.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Team{}, fmt.Errorf("team not found: %w", err)
+		}
+		return models.Team{}, fmt.Errorf("get team: %w", err)
+	}
+	t.ParentTeam = parent.String
+	t.Organization = org.String
+	return t, nil
+}
+
 // GetTeam получает команду со всеми её участниками
 func (s *Storage) GetTeam(teamName string) (models.Team, error) {
-	var t models.Team
-	t.TeamName = teamName
+	// проверяем существование команды до чтения участников: внутри транзакции
+	// нельзя выполнять новый запрос, пока не дочитаны строки предыдущего
+	t, err := s.GetTeamInfo(teamName)
+	if err != nil {
+		return models.Team{}, err
+	}
 
-	rows, err := s.db.Query(`SELECT user_id, username, is_active FROM users WHERE team_name=$1`, teamName)
+	rows, err := s.db.Query(`
+        SELECT u.user_id, u.username, u.is_active, u.review_weight
+        FROM users u
+        JOIN team_memberships tm ON tm.user_id = u.user_id
+        WHERE tm.team_name=$1
+        ORDER BY u.user_id
+    `, teamName)
 	if err != nil {
 		return t, fmt.Errorf("get team users: %w", err)
 	}
 	defer rows.Close()
 
-	var count int
-	err = s.db.QueryRow(`SELECT COUNT(*) FROM teams WHERE team_name=$1`, teamName).Scan(&count)
-	if err != nil || count == 0 {
-		return models.Team{}, fmt.Errorf("team not found")
-	}
-
 	members := []models.TeamMember{}
 	for rows.Next() {
 		var m models.TeamMember
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.ReviewWeight); err != nil {
 			return t, fmt.Errorf("scan team member: %w", err)
 		}
 		members = append(members, m)
@@ -148,39 +624,231 @@ func (s *Storage) GetTeam(teamName string) (models.Team, error) {
 	return t, nil
 }
 
-// UpsertUser вставляет или обновляет пользователя в БД
-func (s *Storage) UpsertUser(u models.User) error {
+// ListTeamMembers получает страницу участников команды, упорядоченных по user_id.
+// Возвращает курсор следующей страницы или пустую строку.
+func (s *Storage) ListTeamMembers(teamName string, limit int, cursor string) ([]models.TeamMember, string, error) {
+	after := ""
+	if cursor != "" {
+		c, err := decodeCursor(cursor, cursorSortMembers)
+		if err != nil {
+			return nil, "", err
+		}
+		after = c.ID
+	}
+	rows, err := s.db.Query(`
+        SELECT u.user_id, u.username, u.is_active, u.review_weight
+        FROM users u
+        JOIN team_memberships tm ON tm.user_id = u.user_id
+        WHERE tm.team_name=$1 AND u.user_id > $2
+        ORDER BY u.user_id
+        LIMIT $3
+    `, teamName, after, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("list team members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var m models.TeamMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.ReviewWeight); err != nil {
+			return nil, "", fmt.Errorf("scan team member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list team members: %w", err)
+	}
+	next := ""
+	if len(members) > limit {
+		members = members[:limit]
+		next = encodeCursor(cursorSortMembers, "", members[limit-1].UserID)
+	}
+	return members, next, nil
+}
+
+// GetTeamParent получает родительскую команду (пустая строка для корневой)
+func (s *Storage) GetTeamParent(teamName string) (string, error) {
+	var parent sql.NullString
+	err := s.db.QueryRow(`SELECT parent_team FROM teams WHERE team_name=$1`, teamName).Scan(&parent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("team not found: %w", err)
+		}
+		return "", fmt.Errorf("get team parent: %w", err)
+	}
+	return parent.String, nil
+}
+
+// LockTeamVersion получает версию команды, блокируя её строку до конца транзакции
+func (s *Storage) LockTeamVersion(teamName string) (int64, error) {
+	var version int64
+	err := s.db.QueryRow(`SELECT version FROM teams WHERE team_name=$1 FOR UPDATE`, teamName).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("team not found: %w", err)
+		}
+		return 0, fmt.Errorf("lock team version: %w", err)
+	}
+	return version, nil
+}
+
+// bumpTeamVersion увеличивает версию команды
+func (s *Storage) bumpTeamVersion(teamName string) error {
+	_, err := s.db.Exec(`UPDATE teams SET version = version + 1 WHERE team_name=$1`, teamName)
+	if err != nil {
+		return fmt.Errorf("bump team version: %w", err)
+	}
+	return nil
+}
+
+// SetTeamParent задаёт родительскую команду (пустая строка делает команду корневой)
+func (s *Storage) SetTeamParent(teamName, parentTeam string) error {
+	res, err := s.db.Exec(`UPDATE teams SET parent_team=$1, version = version + 1 WHERE team_name=$2`, sqlNullString(parentTeam), teamName)
+	if err != nil {
+		return fmt.Errorf("set team parent: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("set team parent: not found")
+	}
+	return nil
+}
+
+// GetTeamPolicy получает собственные настройки команды (без наследования)
+func (s *Storage) GetTeamPolicy(teamName string) (models.TeamPolicy, error) {
+	var p models.TeamPolicy
+	var reviewerCount, requiredApprovals, maxOpenReviews sql.NullInt64
+	err := s.db.QueryRow(`
+        SELECT reviewer_count, required_approvals, max_open_reviews
+        FROM team_policies WHERE team_name=$1
+    `, teamName).Scan(&reviewerCount, &requiredApprovals, &maxOpenReviews)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, nil
+		}
+		return p, fmt.Errorf("get team policy: %w", err)
+	}
+	p.ReviewerCount = nullIntPtr(reviewerCount)
+	p.RequiredApprovals = nullIntPtr(requiredApprovals)
+	p.MaxOpenReviews = nullIntPtr(maxOpenReviews)
+	return p, nil
+}
+
+// SetTeamPolicy заменяет собственные настройки команды
+func (s *Storage) SetTeamPolicy(teamName string, p models.TeamPolicy) error {
 	_, err := s.db.Exec(`
-        INSERT INTO users (user_id, username, is_active, team_name)
+        INSERT INTO team_policies (team_name, reviewer_count, required_approvals, max_open_reviews)
         VALUES ($1,$2,$3,$4)
+        ON CONFLICT (team_name) DO UPDATE
+        SET reviewer_count = EXCLUDED.reviewer_count,
+            required_approvals = EXCLUDED.required_approvals,
+            max_open_reviews = EXCLUDED.max_open_reviews
+    `, teamName, intPtrValue(p.ReviewerCount), intPtrValue(p.RequiredApprovals), intPtrValue(p.MaxOpenReviews))
+	if err != nil {
+		return fmt.Errorf("set team policy: %w", err)
+	}
+	return s.bumpTeamVersion(teamName)
+}
+
+// AddMembership добавляет пользователя в команду (без изменения основной команды)
+func (s *Storage) AddMembership(teamName, userID string) error {
+	_, err := s.db.Exec(`
+        WITH added AS (
+            INSERT INTO team_memberships (team_name, user_id) VALUES ($1,$2)
+            ON CONFLICT DO NOTHING
+            RETURNING team_name
+        )
+        UPDATE teams SET version = version + 1 WHERE team_name IN (SELECT team_name FROM added)
+    `, teamName, userID)
+	if err != nil {
+		return fmt.Errorf("add membership: %w", err)
+	}
+	return nil
+}
+
+// RemoveMembership исключает пользователя из команды
+func (s *Storage) RemoveMembership(teamName, userID string) error {
+	_, err := s.db.Exec(`
+        WITH removed AS (
+            DELETE FROM team_memberships WHERE team_name=$1 AND user_id=$2
+            RETURNING team_name
+        )
+        UPDATE teams SET version = version + 1 WHERE team_name IN (SELECT team_name FROM removed)
+    `, teamName, userID)
+	if err != nil {
+		return fmt.Errorf("remove membership: %w", err)
+	}
+	return nil
+}
+
+// ListUserTeams получает названия всех команд пользователя
+func (s *Storage) ListUserTeams(userID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT team_name FROM team_memberships WHERE user_id=$1 ORDER BY team_name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user teams: %w", err)
+	}
+	defer rows.Close()
+	teams := []string{}
+	for rows.Next() {
+		var teamName string
+		if err := rows.Scan(&teamName); err != nil {
+			return nil, fmt.Errorf("scan user team: %w", err)
+		}
+		teams = append(teams, teamName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list user teams: %w", err)
+	}
+	return teams, nil
+}
+
+// UpsertUser вставляет или обновляет пользователя в БД.
+// Основная команда пользователя также добавляется в его членства.
+func (s *Storage) UpsertUser(u models.User) error {
+	_, err := s.db.Exec(`
+        INSERT INTO users (user_id, username, is_active, team_name, review_weight)
+        VALUES ($1,$2,$3,$4,$5)
         ON CONFLICT (user_id) DO UPDATE
         SET username = EXCLUDED.username,
             is_active = EXCLUDED.is_active,
-            team_name = EXCLUDED.team_name
-    `, u.UserID, u.Username, u.IsActive, u.TeamName)
+            team_name = EXCLUDED.team_name,
+            review_weight = EXCLUDED.review_weight
+    `, u.UserID, u.Username, u.IsActive, sqlNullString(u.TeamName), u.ReviewWeight)
 	if err != nil {
 		return fmt.Errorf("upsert user: %w", err)
 	}
+	if u.TeamName != "" {
+		return s.AddMembership(u.TeamName, u.UserID)
+	}
 	return nil
 }
 
 // GetUser получает информацию о пользователе по ID
 func (s *Storage) GetUser(userID string) (models.User, error) {
 	var u models.User
-	row := s.db.QueryRow(`SELECT user_id, username, team_name, is_active FROM users WHERE user_id=$1`, userID)
-	if err := row.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
+	var teamName sql.NullString
+	row := s.db.QueryRow(`SELECT user_id, username, team_name, is_active, review_weight FROM users WHERE user_id=$1`, userID)
+	if err := row.Scan(&u.UserID, &u.Username, &teamName, &u.IsActive, &u.ReviewWeight); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return u, fmt.Errorf("user not found: %w", err)
 		}
 		return u, fmt.Errorf("scan user: %w", err)
 	}
+	u.TeamName = teamName.String
+	teams, err := s.ListUserTeams(userID)
+	if err != nil {
+		return u, err
+	}
+	u.Teams = teams
 	return u, nil
 }
 
-// UpdateUser обновляет информацию о пользователе
+// UpdateUser обновляет информацию о пользователе.
+// Основная команда пользователя также добавляется в его членства.
 func (s *Storage) UpdateUser(u models.User) error {
-	res, err := s.db.Exec(`UPDATE users SET username=$1, is_active=$2, team_name=$3 WHERE user_id=$4`,
-		u.Username, u.IsActive, u.TeamName, u.UserID)
+	res, err := s.db.Exec(`UPDATE users SET username=$1, is_active=$2, team_name=$3, review_weight=$4 WHERE user_id=$5`,
+		u.Username, u.IsActive, sqlNullString(u.TeamName), u.ReviewWeight, u.UserID)
 	if err != nil {
 		return fmt.Errorf("update user: %w", err)
 	}
@@ -188,16 +856,53 @@ func (s *Storage) UpdateUser(u models.User) error {
 	if affected == 0 {
 		return fmt.Errorf("update user: not found")
 	}
+	if u.TeamName != "" {
+		return s.AddMembership(u.TeamName, u.UserID)
+	}
+	return nil
+}
+
+// DeleteUser удаляет пользователя; его членства в командах и назначения рецензентом удаляются каскадно
+func (s *Storage) DeleteUser(userID string) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE user_id=$1`, userID)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("delete user: not found")
+	}
 	return nil
 }
 
+// CountPRsByAuthor возвращает число pull request'ов пользователя
+func (s *Storage) CountPRsByAuthor(userID string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pull_requests WHERE author_id=$1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count prs by author: %w", err)
+	}
+	return count, nil
+}
+
+// TransferAuthorship передаёт авторство всех pull request'ов другому пользователю.
+func (s *Storage) TransferAuthorship(fromUserID, toUserID string) (int, error) {
+	res, err := s.db.Exec(`UPDATE pull_requests SET author_id=$1, version = version + 1 WHERE author_id=$2`, toUserID, fromUserID)
+	if err != nil {
+		return 0, fmt.Errorf("transfer authorship: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return int(affected), nil
+}
+
 // CreatePullRequest создаёт новый pull request в БД
 func (s *Storage) CreatePullRequest(pr models.PullRequest) error {
 	_, err := s.db.Exec(`
         INSERT INTO pull_requests
-          (pull_request_id, pull_request_name, author_id, status, created_at)
-        VALUES ($1,$2,$3,$4,$5)
-    `, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, string(pr.Status), pr.CreatedAt)
+          (pull_request_id, pull_request_name, author_id, status, created_at, team_name, labels)
+        VALUES ($1,$2,$3,$4,$5,$6,$7)
+    `, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, string(pr.Status), pr.CreatedAt, sqlNullString(pr.TeamName),
+		pq.Array(append([]string{}, pr.Labels...)))
 	if err != nil {
 		return fmt.Errorf("create pr: %w", err)
 	}
@@ -209,24 +914,29 @@ func (s *Storage) GetPullRequest(prID string) (models.PullRequest, error) {
 	var pr models.PullRequest
 	var createdAt sql.NullTime
 	var mergedAt sql.NullTime
+	var closedAt sql.NullTime
+	var teamName sql.NullString
 	row := s.db.QueryRow(`
-        SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+        SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at, closed_at, team_name, labels, version
         FROM pull_requests WHERE pull_request_id=$1
     `, prID)
 	var status string
-	if err := row.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &status, &createdAt, &mergedAt); err != nil {
+	if err := row.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &status, &createdAt, &mergedAt, &closedAt, &teamName,
+		pq.Array(&pr.Labels), &pr.Version); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return pr, fmt.Errorf("pr not found: %w", err)
 		}
 		return pr, fmt.Errorf("scan pr: %w", err)
 	}
 	pr.Status = models.PRStatus(status)
+	pr.TeamName = teamName.String
 	if createdAt.Valid {
 		pr.CreatedAt = createdAt.Time
 	}
 	if mergedAt.Valid {
 		pr.MergedAt = mergedAt.Time
 	}
+	pr.ClosedAt = closedAt.Time
 	// загружаем назначенных рецензентов
 	reviewers, err := s.ListReviewersByPR(prID)
 	if err != nil {
@@ -236,22 +946,191 @@ func (s *Storage) GetPullRequest(prID string) (models.PullRequest, error) {
 	return pr, nil
 }
 
-// UpdatePullRequest обновляет pull request и его список рецензентов
-func (s *Storage) UpdatePullRequest(pr models.PullRequest) error {
-	_, err := s.db.Exec(`
-        UPDATE pull_requests SET pull_request_name=$1, author_id=$2, status=$3, created_at=$4, merged_at=$5
-        WHERE pull_request_id=$6
-    `, pr.PullRequestName, pr.AuthorID, string(pr.Status), pr.CreatedAt, sqlNullTime(pr.MergedAt), pr.PullRequestID)
+// prSortColumns выражения сортировки списка pull request'ов и приведение ключа курсора к их типу
+var prSortColumns = map[string]struct{ expr, cast string }{
+	models.PRSortCreatedAt: {`COALESCE(p.created_at, 'epoch'::timestamptz)`, "::timestamptz"},
+	models.PRSortMergedAt:  {`COALESCE(p.merged_at, 'epoch'::timestamptz)`, "::timestamptz"},
+	models.PRSortID:        {`p.pull_request_id`, ""},
+}
+
+// ListPullRequests получает страницу pull request'ов по фильтру вместе с рецензентами,
+// из архива, если задан f.Archived. Порядок стабилен: по полю сортировки, затем
+// по pull_request_id. Возвращает курсор следующей страницы или пустую строку,
+// если страница последняя.
+func (s *Storage) ListPullRequests(f models.PullRequestFilter) ([]models.PullRequest, string, error) {
+	col, ok := prSortColumns[f.Sort]
+	if !ok {
+		return nil, "", fmt.Errorf("list prs: unknown sort %q", f.Sort)
+	}
+	dir, cmp := "ASC", ">"
+	if f.Order == models.SortOrderDesc {
+		dir, cmp = "DESC", "<"
+	}
+	cursorSort := f.Sort + ":" + f.Order
+	prTable, reviewersTable, archivedAt := "pull_requests", "reviewers", "NULL::timestamptz"
+	if f.Archived {
+		prTable, reviewersTable, archivedAt = "pull_requests_archive", "reviewers_archive", "p.archived_at"
+		cursorSort = cursorSortArchive + cursorSort
+	}
+
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.Status != "" {
+		conds = append(conds, "p.status = "+arg(string(f.Status)))
+	}
+	if f.AuthorID != "" {
+		conds = append(conds, "p.author_id = "+arg(f.AuthorID))
+	}
+	if f.ReviewerID != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM "+reviewersTable+" r WHERE r.pull_request_id = p.pull_request_id AND r.user_id = "+arg(f.ReviewerID)+")")
+	}
+	if f.TeamName != "" {
+		conds = append(conds, "p.team_name = "+arg(f.TeamName))
+	}
+	if !f.CreatedFrom.IsZero() {
+		conds = append(conds, "p.created_at >= "+arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		conds = append(conds, "p.created_at < "+arg(f.CreatedTo))
+	}
+	if !f.MergedFrom.IsZero() {
+		conds = append(conds, "p.merged_at >= "+arg(f.MergedFrom))
+	}
+	if !f.MergedTo.IsZero() {
+		conds = append(conds, "p.merged_at < "+arg(f.MergedTo))
+	}
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor, cursorSort)
+		if err != nil {
+			return nil, "", err
+		}
+		conds = append(conds, fmt.Sprintf("(%s, p.pull_request_id) %s (%s%s, %s)",
+			col.expr, cmp, arg(c.Key), col.cast, arg(c.ID)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+        SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status,
+               p.created_at, p.merged_at, p.closed_at, p.team_name, p.labels, p.version, %[5]s, %[1]s::text
+        FROM %[6]s p
+        %[2]s
+        ORDER BY %[1]s %[3]s, p.pull_request_id %[3]s
+        LIMIT %[4]s
+    `, col.expr, where, dir, arg(f.Limit+1), archivedAt, prTable)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list prs: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.PullRequest{}
+	var keys []string
+	for rows.Next() {
+		var pr models.PullRequest
+		var status, key string
+		var createdAt, mergedAt, closedAt, archived sql.NullTime
+		var teamName sql.NullString
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &status,
+			&createdAt, &mergedAt, &closedAt, &teamName, pq.Array(&pr.Labels), &pr.Version, &archived, &key); err != nil {
+			return nil, "", fmt.Errorf("scan pr: %w", err)
+		}
+		if archived.Valid {
+			pr.ArchivedAt = &archived.Time
+		}
+		pr.Status = models.PRStatus(status)
+		pr.TeamName = teamName.String
+		pr.CreatedAt = createdAt.Time
+		pr.MergedAt = mergedAt.Time
+		pr.ClosedAt = closedAt.Time
+		pr.AssignedReviewers = []string{}
+		result = append(result, pr)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list prs: %w", err)
+	}
+	rows.Close()
+
+	next := ""
+	if len(result) > f.Limit {
+		result = result[:f.Limit]
+		last := result[len(result)-1]
+		next = encodeCursor(cursorSort, keys[f.Limit-1], last.PullRequestID)
+	}
+	if err := s.fillReviewers(reviewersTable, result); err != nil {
+		return nil, "", err
+	}
+	return result, next, nil
+}
+
+// fillReviewers загружает рецензентов для списка pull request'ов одним запросом
+// из таблицы reviewers или reviewers_archive
+func (s *Storage) fillReviewers(table string, prs []models.PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(prs))
+	ids := make([]string, 0, len(prs))
+	for i, pr := range prs {
+		index[pr.PullRequestID] = i
+		ids = append(ids, pr.PullRequestID)
+	}
+	rows, err := s.db.Query(`
+        SELECT pull_request_id, user_id FROM `+table+`
+        WHERE pull_request_id = ANY($1)
+        ORDER BY pull_request_id, user_id
+    `, pq.Array(ids))
 	if err != nil {
+		return fmt.Errorf("list reviewers by prs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var prID, userID string
+		if err := rows.Scan(&prID, &userID); err != nil {
+			return fmt.Errorf("scan reviewer: %w", err)
+		}
+		i := index[prID]
+		prs[i].AssignedReviewers = append(prs[i].AssignedReviewers, userID)
+	}
+	return rows.Err()
+}
+
+// UpdatePullRequest обновляет pull request и его список рецензентов, если его версия
+// совпадает с pr.Version, и записывает в pr новую версию. При несовпадении версии
+// (PR изменён с момента чтения) возвращает ErrVersionConflict.
+func (s *Storage) UpdatePullRequest(pr *models.PullRequest) error {
+	err := s.db.QueryRow(`
+        UPDATE pull_requests SET pull_request_name=$1, author_id=$2, status=$3, created_at=$4, merged_at=$5,
+               closed_at=$6, version = version + 1
+        WHERE pull_request_id=$7 AND version=$8
+        RETURNING version
+    `, pr.PullRequestName, pr.AuthorID, string(pr.Status), pr.CreatedAt, sqlNullTime(pr.MergedAt), sqlNullTime(pr.ClosedAt),
+		pr.PullRequestID, pr.Version).Scan(&pr.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVersionConflict
+		}
 		return fmt.Errorf("update pr: %w", err)
 	}
-	// удаляем всех старых рецензентов и вставляем новых
-	_, err = s.db.Exec(`DELETE FROM reviewers WHERE pull_request_id=$1`, pr.PullRequestID)
+	// удаляем снятых рецензентов и добавляем новых; оставшиеся сохраняют состояние ревью
+	_, err = s.db.Exec(`
+        DELETE FROM reviewers WHERE pull_request_id=$1 AND NOT (user_id = ANY($2))
+    `, pr.PullRequestID, pq.Array(append([]string{}, pr.AssignedReviewers...)))
 	if err != nil {
 		return fmt.Errorf("delete reviewers on update: %w", err)
 	}
 	for _, uid := range pr.AssignedReviewers {
-		_, err := s.db.Exec(`INSERT INTO reviewers (pull_request_id, user_id) VALUES ($1,$2)`, pr.PullRequestID, uid)
+		_, err := s.db.Exec(`
+            INSERT INTO reviewers (pull_request_id, user_id) VALUES ($1,$2)
+            ON CONFLICT DO NOTHING
+        `, pr.PullRequestID, uid)
 		if err != nil {
 			return fmt.Errorf("insert reviewer on update: %w", err)
 		}
@@ -259,6 +1138,46 @@ func (s *Storage) UpdatePullRequest(pr models.PullRequest) error {
 	return nil
 }
 
+// UpdatePullRequestMeta обновляет название, автора и метки pull request'а, если его версия
+// совпадает с pr.Version, и записывает в pr новую версию. При несовпадении версии
+// возвращает ErrVersionConflict.
+func (s *Storage) UpdatePullRequestMeta(pr *models.PullRequest) error {
+	err := s.db.QueryRow(`
+        UPDATE pull_requests SET pull_request_name=$1, author_id=$2, labels=$3, version = version + 1
+        WHERE pull_request_id=$4 AND version=$5
+        RETURNING version
+    `, pr.PullRequestName, pr.AuthorID, pq.Array(append([]string{}, pr.Labels...)), pr.PullRequestID, pr.Version).Scan(&pr.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("update pr meta: %w", err)
+	}
+	return nil
+}
+
+// bumpPullRequestVersion увеличивает версию pull request'а
+func (s *Storage) bumpPullRequestVersion(prID string) error {
+	_, err := s.db.Exec(`UPDATE pull_requests SET version = version + 1 WHERE pull_request_id=$1`, prID)
+	if err != nil {
+		return fmt.Errorf("bump pr version: %w", err)
+	}
+	return nil
+}
+
+// RemoveReviewer снимает рецензента с pull request'а
+func (s *Storage) RemoveReviewer(prID, userID string) error {
+	res, err := s.db.Exec(`DELETE FROM reviewers WHERE pull_request_id=$1 AND user_id=$2`, prID, userID)
+	if err != nil {
+		return fmt.Errorf("remove reviewer: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("remove reviewer: not assigned")
+	}
+	return s.bumpPullRequestVersion(prID)
+}
+
 // AssignReviewer назначает рецензента для pull request'а
 func (s *Storage) AssignReviewer(prID, userID string) error {
 	_, err := s.db.Exec(`
@@ -290,7 +1209,13 @@ func (s *Storage) ListReviewersByPR(prID string) ([]string, error) {
 
 // ListActiveMembers получает всех активных участников команды
 func (s *Storage) ListActiveMembers(teamName string) ([]models.TeamMember, error) {
-	rows, err := s.db.Query(`SELECT user_id, username, is_active FROM users WHERE team_name=$1 AND is_active = TRUE`, teamName)
+	rows, err := s.db.Query(`
+        SELECT u.user_id, u.username, u.is_active, u.review_weight
+        FROM users u
+        JOIN team_memberships tm ON tm.user_id = u.user_id
+        WHERE tm.team_name=$1 AND u.is_active = TRUE
+        ORDER BY u.user_id
+    `, teamName)
 	if err != nil {
 		return nil, fmt.Errorf("list active members: %w", err)
 	}
@@ -298,7 +1223,7 @@ func (s *Storage) ListActiveMembers(teamName string) ([]models.TeamMember, error
 	var members []models.TeamMember
 	for rows.Next() {
 		var m models.TeamMember
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.ReviewWeight); err != nil {
 			return nil, fmt.Errorf("scan team member: %w", err)
 		}
 		members = append(members, m)
@@ -306,54 +1231,269 @@ func (s *Storage) ListActiveMembers(teamName string) ([]models.TeamMember, error
 	return members, nil
 }
 
-// ListPRsByReviewer получает список pull request'ов, для которых пользователь назначен рецензентом
-func (s *Storage) ListPRsByReviewer(userID string) ([]models.PullRequestShort, error) {
-	rows, err := s.db.Query(`
-        SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status
+// ListPRsByReviewer получает страницу pull request'ов, для которых пользователь назначен рецензентом,
+// вместе с его состоянием ревью. Учитываются фильтры статуса и интервала created_at;
+// порядок стабилен: по полю сортировки, затем по pull_request_id.
+// Возвращает курсор следующей страницы или пустую строку.
+func (s *Storage) ListPRsByReviewer(userID string, f models.PullRequestFilter) ([]models.PullRequestShort, string, error) {
+	col, ok := prSortColumns[f.Sort]
+	if !ok {
+		return nil, "", fmt.Errorf("list prs by reviewer: unknown sort %q", f.Sort)
+	}
+	dir, cmp := "ASC", ">"
+	if f.Order == models.SortOrderDesc {
+		dir, cmp = "DESC", "<"
+	}
+	cursorSort := cursorSortReviews + ":" + f.Sort + ":" + f.Order
+
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"r.user_id = $1"}
+	if f.Status != "" {
+		conds = append(conds, "p.status = "+arg(string(f.Status)))
+	}
+	if !f.CreatedFrom.IsZero() {
+		conds = append(conds, "p.created_at >= "+arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		conds = append(conds, "p.created_at < "+arg(f.CreatedTo))
+	}
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor, cursorSort)
+		if err != nil {
+			return nil, "", err
+		}
+		conds = append(conds, fmt.Sprintf("(%s, p.pull_request_id) %s (%s%s, %s)",
+			col.expr, cmp, arg(c.Key), col.cast, arg(c.ID)))
+	}
+
+	query := fmt.Sprintf(`
+        SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status,
+               p.created_at, r.review_state, %[1]s::text
         FROM pull_requests p
         JOIN reviewers r ON r.pull_request_id = p.pull_request_id
-        WHERE r.user_id = $1
-    `, userID)
+        WHERE %[2]s
+        ORDER BY %[1]s %[3]s, p.pull_request_id %[3]s
+        LIMIT %[4]s
+    `, col.expr, strings.Join(conds, " AND "), dir, arg(f.Limit+1))
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list prs by reviewer: %w", err)
+		return nil, "", fmt.Errorf("list prs by reviewer: %w", err)
 	}
 	defer rows.Close()
 
-	var result []models.PullRequestShort
+	result := []models.PullRequestShort{}
+	var keys []string
 	for rows.Next() {
 		var pr models.PullRequestShort
-		var status string
-		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &status); err != nil {
-			return nil, fmt.Errorf("scan pr short: %w", err)
+		var status, state, key string
+		var createdAt sql.NullTime
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &status,
+			&createdAt, &state, &key); err != nil {
+			return nil, "", fmt.Errorf("scan pr short: %w", err)
 		}
 		pr.Status = models.PRStatus(status)
+		pr.CreatedAt = createdAt.Time
+		pr.ReviewState = models.ReviewState(state)
 		result = append(result, pr)
+		keys = append(keys, key)
 	}
-	return result, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list prs by reviewer: %w", err)
+	}
+	next := ""
+	if len(result) > f.Limit {
+		result = result[:f.Limit]
+		next = encodeCursor(cursorSort, keys[f.Limit-1], result[f.Limit-1].PullRequestID)
+	}
+	return result, next, nil
+}
+
+// ListOpenReviewCounts возвращает число назначений на OPEN PR для указанных пользователей
+func (s *Storage) ListOpenReviewCounts(userIDs []string) (map[string]int, error) {
+	rows, err := s.db.Query(`
+        SELECT r.user_id, COUNT(*)
+        FROM reviewers r
+        JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+        WHERE p.status = 'OPEN' AND r.user_id = ANY($1)
+        GROUP BY r.user_id
+    `, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list open review counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("scan open review count: %w", err)
+		}
+		counts[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list open review counts: %w", err)
+	}
+	return counts, nil
 }
 
-// ListUserReviewCounts возвращает словарь user_id -> количество PR, где он назначен ревьюером
-func (s *Storage) ListUserReviewCounts() (map[string]int, error) {
+// ListUserReviewCounts возвращает страницу словаря user_id -> количество PR, где он назначен
+// ревьюером; пользователи упорядочены по user_id. Возвращает курсор следующей страницы или пустую строку.
+func (s *Storage) ListUserReviewCounts(limit int, cursor string) (map[string]int, string, error) {
+	after := ""
+	if cursor != "" {
+		c, err := decodeCursor(cursor, cursorSortStats)
+		if err != nil {
+			return nil, "", err
+		}
+		after = c.ID
+	}
 	rows, err := s.db.Query(`
         SELECT user_id, COUNT(DISTINCT pull_request_id) AS count
-        FROM reviewers
+        FROM (
+            SELECT user_id, pull_request_id FROM reviewers
+            UNION ALL
+            SELECT user_id, pull_request_id FROM reviewers_archive
+        ) r
+        WHERE user_id > $1
         GROUP BY user_id
-    `)
+        ORDER BY user_id
+        LIMIT $2
+    `, after, limit+1)
 	if err != nil {
-		return nil, fmt.Errorf("list user review counts: %w", err)
+		return nil, "", fmt.Errorf("list user review counts: %w", err)
 	}
 	defer rows.Close()
 
 	stats := make(map[string]int)
+	var last string
 	for rows.Next() {
 		var userID string
 		var count int
 		if err := rows.Scan(&userID, &count); err != nil {
-			return nil, fmt.Errorf("scan user count: %w", err)
+			return nil, "", fmt.Errorf("scan user count: %w", err)
+		}
+		if len(stats) == limit {
+			return stats, encodeCursor(cursorSortStats, "", last), nil
 		}
 		stats[userID] = count
+		last = userID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list user review counts: %w", err)
+	}
+	return stats, "", nil
+}
+
+// ListOpenPRsByTeam получает OPEN pull request'ы команды вместе с рецензентами
+func (s *Storage) ListOpenPRsByTeam(teamName string) ([]models.PullRequest, error) {
+	rows, err := s.db.Query(`
+        SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.team_name, r.user_id
+        FROM pull_requests p
+        JOIN reviewers r ON r.pull_request_id = p.pull_request_id
+        WHERE p.status = 'OPEN' AND p.team_name = $1
+        ORDER BY p.created_at, p.pull_request_id, r.user_id
+    `, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("list open prs by team: %w", err)
+	}
+	defer rows.Close()
+	return scanOpenPRReviewers(rows)
+}
+
+// ListOpenPRsByReviewer получает OPEN pull request'ы, на которые назначен пользователь, со всеми рецензентами.
+// Если teamName не пуст, возвращаются только PR этой команды.
+func (s *Storage) ListOpenPRsByReviewer(userID, teamName string) ([]models.PullRequest, error) {
+	rows, err := s.db.Query(`
+        SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.team_name, r.user_id
+        FROM pull_requests p
+        JOIN reviewers r ON r.pull_request_id = p.pull_request_id
+        WHERE p.status = 'OPEN'
+          AND p.pull_request_id IN (SELECT pull_request_id FROM reviewers WHERE user_id = $1)
+          AND ($2 = '' OR p.team_name = $2)
+        ORDER BY p.created_at, p.pull_request_id, r.user_id
+    `, userID, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("list open prs by reviewer: %w", err)
+	}
+	defer rows.Close()
+	return scanOpenPRReviewers(rows)
+}
+
+// scanOpenPRReviewers собирает строки (pr, рецензент), упорядоченные по PR, в список OPEN pull request'ов
+func scanOpenPRReviewers(rows *sql.Rows) ([]models.PullRequest, error) {
+	var result []models.PullRequest
+	for rows.Next() {
+		var pr models.PullRequest
+		var teamName sql.NullString
+		var reviewerID string
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &teamName, &reviewerID); err != nil {
+			return nil, fmt.Errorf("scan open pr reviewer: %w", err)
+		}
+		pr.TeamName = teamName.String
+		if n := len(result); n > 0 && result[n-1].PullRequestID == pr.PullRequestID {
+			result[n-1].AssignedReviewers = append(result[n-1].AssignedReviewers, reviewerID)
+			continue
+		}
+		pr.Status = models.PRStatusOpen
+		pr.AssignedReviewers = []string{reviewerID}
+		result = append(result, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan open pr reviewers: %w", err)
 	}
-	return stats, nil
+	return result, nil
+}
+
+// MoveReviewer переносит назначение рецензента на pull request с одного пользователя на другого.
+// Строка назначения обновляется на месте; состояние ревью сбрасывается в PENDING,
+// так как новый рецензент ещё не смотрел PR.
+func (s *Storage) MoveReviewer(prID, fromUserID, toUserID string) error {
+	res, err := s.db.Exec(`
+        UPDATE reviewers SET user_id=$3, review_state='PENDING'
+        WHERE pull_request_id=$1 AND user_id=$2
+    `, prID, fromUserID, toUserID)
+	if err != nil {
+		return fmt.Errorf("move reviewer: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("move reviewer: not assigned")
+	}
+	return s.bumpPullRequestVersion(prID)
+}
+
+// ListSubmittedReviews возвращает для pull request'ов рецензентов, уже выставивших
+// состояние ревью (не PENDING): pull_request_id -> user_id -> true
+func (s *Storage) ListSubmittedReviews(prIDs []string) (map[string]map[string]bool, error) {
+	rows, err := s.db.Query(`
+        SELECT pull_request_id, user_id FROM reviewers
+        WHERE pull_request_id = ANY($1) AND review_state <> 'PENDING'
+    `, pq.Array(prIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list submitted reviews: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[string]map[string]bool{}
+	for rows.Next() {
+		var prID, userID string
+		if err := rows.Scan(&prID, &userID); err != nil {
+			return nil, fmt.Errorf("scan submitted review: %w", err)
+		}
+		if result[prID] == nil {
+			result[prID] = map[string]bool{}
+		}
+		result[prID][userID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list submitted reviews: %w", err)
+	}
+	return result, nil
 }
 
 // sqlNullTime преобразует время в sql.NullTime (NULL если время нулевое)
@@ -363,3 +1503,36 @@ func sqlNullTime(t time.Time) interface{} {
 	}
 	return t
 }
+
+// sqlNullInt преобразует число в значение для БД (NULL если число нулевое)
+func sqlNullInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// sqlNullString преобразует строку в значение для БД (NULL если строка пустая)
+func sqlNullString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+// nullIntPtr преобразует sql.NullInt64 в указатель (nil для NULL)
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+// intPtrValue преобразует указатель в значение для БД (NULL для nil)
+func intPtrValue(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
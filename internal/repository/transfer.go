@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"pr-review-manager/internal/models"
+)
+
+// ListTeams получает все команды с родительскими командами, упорядоченные по имени
+func (s *Storage) ListTeams() ([]models.TeamRecord, error) {
+	rows, err := s.db.Query(`SELECT team_name, parent_team FROM teams ORDER BY team_name`)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []models.TeamRecord{}
+	for rows.Next() {
+		var t models.TeamRecord
+		var parent sql.NullString
+		if err := rows.Scan(&t.TeamName, &parent); err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+		t.ParentTeam = parent.String
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// ListUsers получает всех пользователей с основной командой, упорядоченных по user_id
+func (s *Storage) ListUsers() ([]models.UserRecord, error) {
+	rows, err := s.db.Query(`
+        SELECT user_id, username, team_name, is_active, review_weight
+        FROM users ORDER BY user_id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.UserRecord{}
+	for rows.Next() {
+		var u models.UserRecord
+		var teamName sql.NullString
+		if err := rows.Scan(&u.UserID, &u.Username, &teamName, &u.IsActive, &u.ReviewWeight); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		u.TeamName = teamName.String
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// ListMemberships получает все членства в командах, упорядоченные по команде и пользователю
+func (s *Storage) ListMemberships() ([]models.MembershipRecord, error) {
+	rows, err := s.db.Query(`SELECT team_name, user_id FROM team_memberships ORDER BY team_name, user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list memberships: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []models.MembershipRecord{}
+	for rows.Next() {
+		var m models.MembershipRecord
+		if err := rows.Scan(&m.TeamName, &m.UserID); err != nil {
+			return nil, fmt.Errorf("scan membership: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// EnsureTeam создаёт команду без родителя, если её ещё нет
+func (s *Storage) EnsureTeam(teamName string) error {
+	_, err := s.db.Exec(`INSERT INTO teams (team_name) VALUES ($1) ON CONFLICT DO NOTHING`, teamName)
+	if err != nil {
+		return fmt.Errorf("ensure team: %w", err)
+	}
+	return nil
+}
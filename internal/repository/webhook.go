@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pr-review-manager/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ClaimedDelivery доставка, взятая на отправку, с адресом и секретом подписки
+type ClaimedDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// CreateWebhookSubscription создаёт подписку и заполняет её ID и время создания
+func (s *Storage) CreateWebhookSubscription(sub *models.WebhookSubscription) error {
+	err := s.db.QueryRow(`
+        INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES ($1,$2,$3)
+        RETURNING id, created_at
+    `, sub.URL, sub.Secret, pq.Array(eventTypeStrings(sub.EventTypes))).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookSubscriptions получает страницу подписок в порядке создания (created_at, id).
+// Возвращает курсор следующей страницы или пустую строку.
+func (s *Storage) ListWebhookSubscriptions(limit int, cursor string) ([]models.WebhookSubscription, string, error) {
+	args := []any{limit + 1}
+	where := ""
+	if cursor != "" {
+		c, err := decodeCursor(cursor, cursorSortHooks)
+		if err != nil {
+			return nil, "", err
+		}
+		id, err := strconv.ParseInt(c.ID, 10, 64)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		args = append(args, c.Key, id)
+		where = "WHERE (created_at, id) > ($2::timestamptz, $3)"
+	}
+	rows, err := s.db.Query(`
+        SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions
+        `+where+`
+        ORDER BY created_at, id
+        LIMIT $1
+    `, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		var types []string
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&types), &sub.CreatedAt); err != nil {
+			return nil, "", fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		for _, t := range types {
+			sub.EventTypes = append(sub.EventTypes, models.DomainEventType(t))
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	next := ""
+	if len(subs) > limit {
+		subs = subs[:limit]
+		last := subs[limit-1]
+		next = encodeCursor(cursorSortHooks, last.CreatedAt.Format(time.RFC3339Nano), strconv.FormatInt(last.ID, 10))
+	}
+	return subs, next, nil
+}
+
+// DeleteWebhookSubscription удаляет подписку вместе с журналом её доставок и возвращает удалённую подписку
+func (s *Storage) DeleteWebhookSubscription(id int64) (models.WebhookSubscription, error) {
+	sub := models.WebhookSubscription{ID: id}
+	var types []string
+	err := s.db.QueryRow(`
+        DELETE FROM webhook_subscriptions WHERE id=$1 RETURNING url, event_types, created_at
+    `, id).Scan(&sub.URL, pq.Array(&types), &sub.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sub, fmt.Errorf("delete webhook subscription: not found")
+		}
+		return sub, fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	for _, t := range types {
+		sub.EventTypes = append(sub.EventTypes, models.DomainEventType(t))
+	}
+	return sub, nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь доставки всем подпискам на его тип.
+// Возвращает число созданных доставок.
+func (s *Storage) EnqueueWebhookDeliveries(ev models.DomainEvent, payload []byte) (int, error) {
+	res, err := s.db.Exec(`
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, next_attempt_at)
+        SELECT id, $1, $2, $3, $4 FROM webhook_subscriptions WHERE $2 = ANY(event_types)
+    `, ev.ID, string(ev.Type), string(payload), ev.OccurredAt)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ClaimWebhookDeliveries берёт до limit доставок, срок попытки которых наступил к now,
+// и откладывает их следующую попытку до leaseUntil, чтобы параллельные обработчики
+// не отправили их повторно, пока идёт отправка.
+func (s *Storage) ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]ClaimedDelivery, error) {
+	rows, err := s.db.Query(`
+        UPDATE webhook_deliveries d SET next_attempt_at = $2
+        FROM webhook_subscriptions ws
+        WHERE ws.id = d.subscription_id AND d.id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'PENDING' AND next_attempt_at <= $1
+            ORDER BY next_attempt_at
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, d.created_at, ws.url, ws.secret
+    `, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []ClaimedDelivery
+	for rows.Next() {
+		var d ClaimedDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Attempts, &d.CreatedAt,
+			&d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		d.Status = models.DeliveryStatusPending
+		claimed = append(claimed, d)
+	}
+	return claimed, rows.Err()
+}
+
+// CompleteWebhookDelivery отмечает доставку успешной
+func (s *Storage) CompleteWebhookDelivery(id int64, responseStatus int, at time.Time) error {
+	_, err := s.db.Exec(`
+        UPDATE webhook_deliveries
+        SET status='DELIVERED', attempts=attempts+1, response_status=$2, last_error=NULL, delivered_at=$3
+        WHERE id=$1
+    `, id, responseStatus, at)
+	if err != nil {
+		return fmt.Errorf("complete webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// FailWebhookDelivery записывает неудачную попытку доставки. Если nextAttempt nil,
+// доставка переносится в dead-letter (статус DEAD).
+func (s *Storage) FailWebhookDelivery(id int64, responseStatus int, lastError string, nextAttempt *time.Time) error {
+	status := models.DeliveryStatusPending
+	if nextAttempt == nil {
+		status = models.DeliveryStatusDead
+	}
+	_, err := s.db.Exec(`
+        UPDATE webhook_deliveries
+        SET status=$2, attempts=attempts+1, response_status=$3, last_error=$4,
+            next_attempt_at=COALESCE($5, next_attempt_at)
+        WHERE id=$1
+    `, id, status, sqlNullInt(responseStatus), sqlNullString(lastError), nextAttempt)
+	if err != nil {
+		return fmt.Errorf("fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// RequeueWebhookDelivery возвращает доставку из dead-letter в очередь с обнулённым числом попыток
+func (s *Storage) RequeueWebhookDelivery(id int64, at time.Time) (models.WebhookDelivery, error) {
+	res, err := s.db.Exec(`
+        UPDATE webhook_deliveries SET status='PENDING', attempts=0, next_attempt_at=$2
+        WHERE id=$1 AND status='DEAD'
+    `, id, at)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("requeue webhook delivery: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return models.WebhookDelivery{}, fmt.Errorf("requeue webhook delivery: not found")
+	}
+	return s.GetWebhookDelivery(id)
+}
+
+// GetWebhookDelivery получает доставку по ID
+func (s *Storage) GetWebhookDelivery(id int64) (models.WebhookDelivery, error) {
+	deliveries, err := s.queryWebhookDeliveries(`WHERE id=$1`, id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return models.WebhookDelivery{}, fmt.Errorf("get webhook delivery: %w", sql.ErrNoRows)
+	}
+	return deliveries[0], nil
+}
+
+// ListWebhookDeliveries получает страницу журнала доставок, новые первыми: по убыванию
+// (created_at, id). Возвращает курсор следующей страницы или пустую строку.
+func (s *Storage) ListWebhookDeliveries(f models.WebhookDeliveryFilter) ([]models.WebhookDelivery, string, error) {
+	var conds []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.SubscriptionID > 0 {
+		conds = append(conds, "subscription_id = "+arg(f.SubscriptionID))
+	}
+	if f.Status != "" {
+		conds = append(conds, "status = "+arg(f.Status))
+	}
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor, cursorSortDeliver)
+		if err != nil {
+			return nil, "", err
+		}
+		id, err := strconv.ParseInt(c.ID, 10, 64)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		conds = append(conds, fmt.Sprintf("(created_at, id) < (%s::timestamptz, %s)", arg(c.Key), arg(id)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	deliveries, err := s.queryWebhookDeliveries(fmt.Sprintf("%s ORDER BY created_at DESC, id DESC LIMIT %s", where, arg(f.Limit+1)), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(deliveries) > f.Limit {
+		deliveries = deliveries[:f.Limit]
+		last := deliveries[f.Limit-1]
+		next = encodeCursor(cursorSortDeliver, last.CreatedAt.Format(time.RFC3339Nano), strconv.FormatInt(last.ID, 10))
+	}
+	return deliveries, next, nil
+}
+
+// queryWebhookDeliveries выбирает доставки с условием и порядком tail
+func (s *Storage) queryWebhookDeliveries(tail string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+        SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+               response_status, last_error, created_at, delivered_at
+        FROM webhook_deliveries `+tail, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		var nextAttempt, deliveredAt sql.NullTime
+		var responseStatus sql.NullInt64
+		var lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&nextAttempt, &responseStatus, &lastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		if nextAttempt.Valid && d.Status == models.DeliveryStatusPending {
+			d.NextAttemptAt = &nextAttempt.Time
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		d.ResponseStatus = int(responseStatus.Int64)
+		d.LastError = lastError.String
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// eventTypeStrings приводит типы событий к строкам для pq.Array
+func eventTypeStrings(types []models.DomainEventType) []string {
+	res := make([]string, len(types))
+	for i, t := range types {
+		res[i] = string(t)
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"pr-review-manager/internal/repository"
+)
+
+// archiveBatchSize число PR, переносимых в архив одной транзакцией
+const archiveBatchSize = 500
+
+// ArchivePullRequests переносит в архив закрытые PR, закрытые больше retention назад,
+// и возвращает их число. Перенос идёт пачками в отдельных транзакциях, чтобы не держать
+// блокировки на рабочих таблицах.
+func (s *Service) ArchivePullRequests(retention time.Duration) (int, error) {
+	cutoff := s.now().UTC().Add(-retention)
+	if s.logger != nil {
+		s.logger.Info("ArchivePullRequests вызван", slog.Time("cutoff", cutoff))
+	}
+
+	total := 0
+	for {
+		var moved int
+		err := s.storage.WithTx(func(tx *repository.Storage) error {
+			var err error
+			moved, err = tx.ArchivePullRequests(cutoff, archiveBatchSize)
+			return err
+		})
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("не удалось перенести PR в архив", slog.Int("archived", total), slog.Any("err", err))
+			}
+			return total, fmt.Errorf("failed archive pull requests: %w", err)
+		}
+		total += moved
+		if moved < archiveBatchSize {
+			break
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Info("PR перенесены в архив", slog.Int("archived", total))
+	}
+	return total, nil
+}
+
+// RunArchiver периодически переносит в архив PR старше retention, пока не отменён ctx.
+// Ошибки запуска логируются, следующий запуск выполняется по расписанию.
+func (s *Service) RunArchiver(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, _ = s.ArchivePullRequests(retention)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestArchivedPullRequestsStayVisible(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, _ := newDBService(t, WithClock(func() time.Time { return now }))
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if _, err := s.MergePullRequest("pr-1", 0); err != nil {
+		t.Fatalf("MergePullRequest: %v", err)
+	}
+
+	now = now.Add(48 * time.Hour)
+	if n, err := s.ArchivePullRequests(24 * time.Hour); err != nil || n != 1 {
+		t.Fatalf("archived %d prs, %v; want 1", n, err)
+	}
+
+	stats, err := s.GetUserStats(models.PageRequest{})
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if stats.Stats["r1"] != 1 {
+		t.Errorf("stats %v: archived review of r1 must still count", stats.Stats)
+	}
+
+	archived, err := s.ListPullRequests(models.PullRequestFilter{Archived: true})
+	if err != nil {
+		t.Fatalf("ListPullRequests archived: %v", err)
+	}
+	if len(archived.PullRequests) != 1 {
+		t.Fatalf("archived prs %+v, want pr-1", archived.PullRequests)
+	}
+	if pr := archived.PullRequests[0]; pr.PullRequestID != "pr-1" || pr.ArchivedAt == nil || !equalStrings(pr.AssignedReviewers, []string{"r1"}) {
+		t.Errorf("archived pr %+v, want pr-1 with its reviewer", pr)
+	}
+
+	active, err := s.ListPullRequests(models.PullRequestFilter{})
+	if err != nil {
+		t.Fatalf("ListPullRequests: %v", err)
+	}
+	if len(active.PullRequests) != 0 {
+		t.Errorf("working list %+v, want no archived prs", active.PullRequests)
+	}
+}
+
+func TestGetUserStatsPages(t *testing.T) {
+	s, _ := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true), member("r3", true), member("r4", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: id, PullRequestName: id, AuthorID: "author"}, false); err != nil {
+			t.Fatalf("CreatePullRequest %s: %v", id, err)
+		}
+	}
+	all, err := s.GetUserStats(models.PageRequest{})
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if all.NextCursor != "" {
+		t.Fatalf("next cursor %q on a single page", all.NextCursor)
+	}
+
+	got := map[string]int{}
+	last := ""
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		resp, err := s.GetUserStats(models.PageRequest{Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for id, n := range resp.Stats {
+			if id <= last {
+				t.Errorf("page %d: user %s after %s, want user_id order", page, id, last)
+			}
+			got[id] = n
+			last = id
+		}
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if len(got) != len(all.Stats) {
+		t.Fatalf("pages %v, want %v", got, all.Stats)
+	}
+	for id, n := range all.Stats {
+		if got[id] != n {
+			t.Errorf("user %s: %d reviews in pages, want %d", id, got[id], n)
+		}
+	}
+
+	if _, err := s.GetUserStats(models.PageRequest{Cursor: "bad"}); ParseCodeFromError(err) != models.ErrorCodeValidation {
+		t.Errorf("invalid cursor: err %v, want %s", err, models.ErrorCodeValidation)
+	}
+}
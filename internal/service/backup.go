@@ -0,0 +1,192 @@
+package service
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// restoreBatchSize число строк, вставляемых одним запросом при восстановлении
+const restoreBatchSize = 500
+
+// backupRecord запись потока резервной копии. Первая запись — заголовок
+// (format, version, created_at), затем строки таблиц (table, row) в порядке
+// восстановления, последняя — итог (end, rows) для проверки целостности.
+type backupRecord struct {
+	Format    string           `json:"format,omitempty"`
+	Version   int              `json:"version,omitempty"`
+	CreatedAt *time.Time       `json:"created_at,omitempty"`
+	Table     string           `json:"table,omitempty"`
+	Row       json.RawMessage  `json:"row,omitempty"`
+	End       bool             `json:"end,omitempty"`
+	Rows      map[string]int64 `json:"rows,omitempty"`
+}
+
+// Backup записывает в w резервную копию всех команд, пользователей, pull request'ов
+// и ревьюверов на один момент времени
+func (s *Service) Backup(w io.Writer) (*models.BackupSummary, error) {
+	if s.logger != nil {
+		s.logger.Info("Backup вызван")
+	}
+
+	summary := &models.BackupSummary{
+		FormatVersion: models.BackupFormatVersion,
+		CreatedAt:     s.now().UTC(),
+		Tables:        map[string]int64{},
+	}
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+
+	err := enc.Encode(backupRecord{Format: models.BackupFormat, Version: summary.FormatVersion, CreatedAt: &summary.CreatedAt})
+	if err == nil {
+		err = s.storage.WithTx(func(tx *repository.Storage) error {
+			if err := tx.BeginSnapshot(); err != nil {
+				return err
+			}
+			for _, table := range repository.BackupTables() {
+				summary.Tables[table] = 0
+				err := tx.DumpTable(table, func(row json.RawMessage) error {
+					summary.Tables[table]++
+					return enc.Encode(backupRecord{Table: table, Row: row})
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = enc.Encode(backupRecord{End: true, Rows: summary.Tables})
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось создать резервную копию", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed backup: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("резервная копия создана", slog.Any("tables", summary.Tables))
+	}
+	return summary, nil
+}
+
+// Restore загружает резервную копию из r в пустую БД. Копия применяется целиком
+// в одной транзакции; копия более новой версии формата, неполная копия или
+// непустая БД — ошибка валидации.
+func (s *Service) Restore(r io.Reader) (*models.BackupSummary, error) {
+	if s.logger != nil {
+		s.logger.Info("Restore вызван")
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errWithCode(models.ErrorCodeValidation, "backup is not a gzip stream: "+err.Error())
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(gz)
+
+	var header backupRecord
+	if err := dec.Decode(&header); err != nil || header.Format != models.BackupFormat || header.CreatedAt == nil {
+		return nil, errWithCode(models.ErrorCodeValidation, "backup header is missing or invalid")
+	}
+	if header.Version < 1 || header.Version > models.BackupFormatVersion {
+		return nil, errWithCode(models.ErrorCodeValidation,
+			fmt.Sprintf("backup format version %d is not supported (max %d)", header.Version, models.BackupFormatVersion))
+	}
+
+	summary := &models.BackupSummary{
+		FormatVersion: header.Version,
+		CreatedAt:     *header.CreatedAt,
+		Tables:        map[string]int64{},
+	}
+	order := map[string]int{}
+	for i, table := range repository.BackupTables() {
+		order[table] = i
+	}
+
+	err = s.storage.WithTx(func(tx *repository.Storage) error {
+		empty, err := tx.BackupTablesEmpty()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return errWithCode(models.ErrorCodeValidation, "restore requires an empty database")
+		}
+
+		current := ""
+		var batch []json.RawMessage
+		flush := func() error {
+			err := tx.RestoreRows(current, batch)
+			batch = batch[:0]
+			return err
+		}
+		for {
+			var rec backupRecord
+			if err := dec.Decode(&rec); err != nil {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					return errWithCode(models.ErrorCodeValidation, "backup is truncated")
+				}
+				return errWithCode(models.ErrorCodeValidation, "backup is corrupted: "+err.Error())
+			}
+			if rec.End {
+				if err := flush(); err != nil {
+					return err
+				}
+				for table, n := range rec.Rows {
+					if summary.Tables[table] != n {
+						return errWithCode(models.ErrorCodeValidation,
+							fmt.Sprintf("backup is inconsistent: table %s has %d rows, expected %d", table, summary.Tables[table], n))
+					}
+				}
+				return nil
+			}
+
+			idx, ok := order[rec.Table]
+			if !ok || rec.Row == nil {
+				return errWithCode(models.ErrorCodeValidation, fmt.Sprintf("backup contains unknown table %q", rec.Table))
+			}
+			if rec.Table != current {
+				if current != "" && idx < order[current] {
+					return errWithCode(models.ErrorCodeValidation, fmt.Sprintf("backup table %s is out of order", rec.Table))
+				}
+				if err := flush(); err != nil {
+					return err
+				}
+				current = rec.Table
+			}
+			batch = append(batch, rec.Row)
+			summary.Tables[rec.Table]++
+			if len(batch) >= restoreBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось восстановить резервную копию", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed restore: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("резервная копия восстановлена", slog.Any("tables", summary.Tables))
+	}
+	return summary, nil
+}
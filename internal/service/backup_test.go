@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// gzipRecords собирает поток резервной копии из записей
+func gzipRecords(t *testing.T, recs ...backupRecord) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatalf("encode record: %v", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return &buf
+}
+
+func backupHeader() backupRecord {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return backupRecord{Format: models.BackupFormat, Version: models.BackupFormatVersion, CreatedAt: &created}
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	s, _ := newDBService(t)
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	created, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	teams, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+
+	var buf bytes.Buffer
+	dumped, err := s.Backup(&buf)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	truncateAll(t, os.Getenv("TEST_DATABASE_URL"))
+
+	restored, err := s.Restore(&buf)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Tables, dumped.Tables) {
+		t.Errorf("restored rows %v, want %v", restored.Tables, dumped.Tables)
+	}
+	gotTeams, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+	if !reflect.DeepEqual(gotTeams, teams) {
+		t.Errorf("teams after restore %+v, want %+v", gotTeams, teams)
+	}
+	pr, err := s.GetPullRequest("pr-1")
+	if err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+	if !equalStrings(pr.PR.AssignedReviewers, created.PR.AssignedReviewers) || pr.PR.Version != created.PR.Version {
+		t.Errorf("pr after restore %+v, want %+v", pr.PR, created.PR)
+	}
+}
+
+func TestRestoreRejectsTruncatedBackup(t *testing.T) {
+	s, _ := newDBService(t)
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{member("u1", true)}}, false); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	var full bytes.Buffer
+	if _, err := s.Backup(&full); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	truncateAll(t, os.Getenv("TEST_DATABASE_URL"))
+
+	table := repository.BackupTables()[0]
+	cases := map[string]*bytes.Buffer{
+		// поток оборван посреди gzip
+		"cut stream": bytes.NewBuffer(full.Bytes()[:full.Len()/2]),
+		// записи целы, но нет итоговой
+		"no end record": gzipRecords(t, backupHeader(), backupRecord{Table: table, Row: json.RawMessage(`{}`)}),
+	}
+	for name, in := range cases {
+		_, err := s.Restore(in)
+		if ParseCodeFromError(err) != models.ErrorCodeValidation {
+			t.Errorf("%s: %v, want %s", name, err, models.ErrorCodeValidation)
+		}
+	}
+	left, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+	if len(left.Teams) != 0 || len(left.Users) != 0 {
+		t.Errorf("failed restores left data: %+v", left)
+	}
+}
+
+func TestRestoreRejectsOutOfOrderTables(t *testing.T) {
+	s, _ := newDBService(t)
+	tables := repository.BackupTables()
+
+	in := gzipRecords(t, backupHeader(),
+		backupRecord{Table: tables[1], Row: json.RawMessage(`{}`)},
+		backupRecord{Table: tables[0], Row: json.RawMessage(`{}`)},
+		backupRecord{End: true, Rows: map[string]int64{tables[0]: 1, tables[1]: 1}},
+	)
+	_, err := s.Restore(in)
+	if ParseCodeFromError(err) != models.ErrorCodeValidation || !strings.Contains(err.Error(), "out of order") {
+		t.Errorf("out of order tables: %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// errBatchItemFailed прерывает транзакцию пакета в режиме atomic
+var errBatchItemFailed = errors.New("batch item failed")
+
+// BatchCreatePullRequests создаёт пакет PR. Ревьюверы выбираются с учётом нагрузки:
+// открытые ревью, в том числе назначенные ранее в этом же пакете, уменьшают вес кандидата,
+// так что пакет распределяется по команде равномернее, чем серия одиночных запросов.
+// Режим atomic создаёт все PR в одной транзакции и при первой ошибке отменяет пакет,
+// режим per_item (по умолчанию) создаёт каждый PR отдельно и продолжает после ошибок.
+func (s *Service) BatchCreatePullRequests(req *models.BatchCreatePullRequestsRequest) (*models.BatchCreatePullRequestsResponse, error) {
+	if req.Mode == "" {
+		req.Mode = models.BatchModePerItem
+	}
+	if s.logger != nil {
+		s.logger.Info("BatchCreatePullRequests вызван", slog.String("mode", req.Mode), slog.Int("items", len(req.Items)))
+	}
+	if req.Mode != models.BatchModeAtomic && req.Mode != models.BatchModePerItem {
+		return nil, errWithCode(models.ErrorCodeValidation, "mode must be atomic or per_item")
+	}
+	if len(req.Items) == 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "items must not be empty")
+	}
+	if len(req.Items) > models.MaxBatchSize {
+		return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("at most %d items per batch", models.MaxBatchSize))
+	}
+
+	resp := &models.BatchCreatePullRequestsResponse{
+		Mode:    req.Mode,
+		Results: make([]models.BatchCreateItemResult, len(req.Items)),
+	}
+	for i, item := range req.Items {
+		resp.Results[i] = models.BatchCreateItemResult{Index: i, PullRequestID: item.PullRequestID, Status: models.BatchItemSkipped}
+	}
+
+	if req.Mode == models.BatchModeAtomic {
+		err := s.storage.WithTx(func(tx *repository.Storage) error {
+			for i := range req.Items {
+				pr, _, err := s.createPullRequest(tx, &req.Items[i], true)
+				if err != nil {
+					resp.Results[i].Status = models.BatchItemFailed
+					resp.Results[i].Error = batchItemError(err)
+					return errBatchItemFailed
+				}
+				if err := tx.AddOutboxEvents(s.pullRequestCreatedEvents(pr)...); err != nil {
+					return err
+				}
+				resp.Results[i].Status = models.BatchItemCreated
+				resp.Results[i].PR = &pr
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errBatchItemFailed) {
+			if s.logger != nil {
+				s.logger.Error("не удалось создать пакет PR", slog.Any("err", err))
+			}
+			return nil, fmt.Errorf("failed batch create prs: %w", err)
+		}
+		resp.Committed = err == nil
+		for i := range resp.Results {
+			switch {
+			case resp.Results[i].Status == models.BatchItemFailed:
+				resp.Failed++
+			case resp.Results[i].Status == models.BatchItemCreated && !resp.Committed:
+				resp.Results[i].Status = models.BatchItemRolledBack
+				resp.Results[i].PR = nil
+			case resp.Results[i].Status == models.BatchItemCreated:
+				resp.Created++
+			}
+		}
+	} else {
+		for i := range req.Items {
+			var pr models.PullRequest
+			err := s.storage.WithTx(func(tx *repository.Storage) error {
+				var err error
+				if pr, _, err = s.createPullRequest(tx, &req.Items[i], true); err != nil {
+					return err
+				}
+				return tx.AddOutboxEvents(s.pullRequestCreatedEvents(pr)...)
+			})
+			if err != nil {
+				resp.Results[i].Status = models.BatchItemFailed
+				resp.Results[i].Error = batchItemError(err)
+				resp.Failed++
+				continue
+			}
+			resp.Results[i].Status = models.BatchItemCreated
+			resp.Results[i].PR = &pr
+			resp.Created++
+		}
+		resp.Committed = resp.Created > 0
+	}
+
+	if s.logger != nil {
+		s.logger.Info("пакет PR обработан", slog.String("mode", req.Mode), slog.Int("created", resp.Created), slog.Int("failed", resp.Failed))
+	}
+	return resp, nil
+}
+
+// batchItemError преобразует ошибку создания PR в описание для результата элемента пакета
+func batchItemError(err error) *models.ErrorDetail {
+	var er *models.ErrorResponse
+	if errors.As(err, &er) {
+		detail := er.ErrDetail
+		return &detail
+	}
+	return &models.ErrorDetail{Code: "INTERNAL_ERROR", Message: err.Error()}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+func batchTeam(t *testing.T, s *Service) {
+	t.Helper()
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true), member("r3", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+}
+
+func TestBatchCreateAtomicRollsBack(t *testing.T) {
+	s, _ := newDBService(t)
+	batchTeam(t, s)
+
+	resp, err := s.BatchCreatePullRequests(&models.BatchCreatePullRequestsRequest{
+		Mode: models.BatchModeAtomic,
+		Items: []models.CreatePullRequestRequest{
+			{PullRequestID: "pr-1", PullRequestName: "pr-1", AuthorID: "author"},
+			{PullRequestID: "pr-2", PullRequestName: "pr-2", AuthorID: "nobody"},
+			{PullRequestID: "pr-3", PullRequestName: "pr-3", AuthorID: "author"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchCreatePullRequests: %v", err)
+	}
+	if resp.Committed || resp.Created != 0 || resp.Failed != 1 {
+		t.Errorf("committed %v, created %d, failed %d; want a rolled back batch", resp.Committed, resp.Created, resp.Failed)
+	}
+	wantStatus := []string{models.BatchItemRolledBack, models.BatchItemFailed, models.BatchItemSkipped}
+	for i, want := range wantStatus {
+		if resp.Results[i].Status != want {
+			t.Errorf("item %d: status %s, want %s", i, resp.Results[i].Status, want)
+		}
+	}
+	if _, err := s.GetPullRequest("pr-1"); ParseCodeFromError(err) != models.ErrorCodeNotFound {
+		t.Errorf("pr-1 after rollback: %v, want NOT_FOUND", err)
+	}
+}
+
+func TestBatchCreatePerItemContinues(t *testing.T) {
+	s, _ := newDBService(t)
+	batchTeam(t, s)
+
+	resp, err := s.BatchCreatePullRequests(&models.BatchCreatePullRequestsRequest{
+		Items: []models.CreatePullRequestRequest{
+			{PullRequestID: "pr-1", PullRequestName: "pr-1", AuthorID: "author"},
+			{PullRequestID: "pr-1", PullRequestName: "duplicate", AuthorID: "author"},
+			{PullRequestID: "pr-3", PullRequestName: "pr-3", AuthorID: "author"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchCreatePullRequests: %v", err)
+	}
+	if resp.Mode != models.BatchModePerItem || !resp.Committed || resp.Created != 2 || resp.Failed != 1 {
+		t.Errorf("response %+v, want 2 created and 1 failed", resp)
+	}
+	if e := resp.Results[1].Error; e == nil || e.Code != models.ErrorCodePRExists {
+		t.Errorf("item 1 error %+v, want %s", e, models.ErrorCodePRExists)
+	}
+	if _, err := s.GetPullRequest("pr-3"); err != nil {
+		t.Errorf("pr-3 after a failed item: %v", err)
+	}
+}
+
+func TestLoadAwareSelectionSpreadsLoad(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("backend", "", member("author", true), member("r1", true), member("r2", true), member("r3", true))
+	st.policies["backend"] = models.TeamPolicy{ReviewerCount: intPtr(1)}
+	// у r1 уже много открытых ревью
+	st.open["r1"] = 10
+
+	s := newTestService()
+	assigned := map[string]int{}
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprintf("pr-%d", i)
+		pr, _, err := s.createPullRequest(st, &models.CreatePullRequestRequest{PullRequestID: id, PullRequestName: id, AuthorID: "author"}, true)
+		if err != nil {
+			t.Fatalf("createPullRequest %s: %v", id, err)
+		}
+		assigned[pr.AssignedReviewers[0]]++
+	}
+
+	if assigned["r1"] >= assigned["r2"] || assigned["r1"] >= assigned["r3"] {
+		t.Errorf("assignments %v: the loaded reviewer must get fewer", assigned)
+	}
+	for _, uid := range []string{"r2", "r3"} {
+		if d := assigned[uid] - 15; d < -6 || d > 6 {
+			t.Errorf("assignments %v: %s is far from an even share", assigned, uid)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
+	"pr-review-manager/internal/repository"
+)
+
+// codeHostProviders поддерживаемые провайдеры code host
+var codeHostProviders = map[string]bool{
+	models.CodeHostGitHub: true,
+	models.CodeHostGitLab: true,
+}
+
+// codeHostSyncTimeout ограничение времени одной передачи ревьюверов на code host с учётом повторов клиента
+const codeHostSyncTimeout = time.Minute
+
+// accountProvider проверяет, что учётные записи провайдера можно привязывать: code host или чат
+func accountProvider(provider string) bool {
+	return codeHostProviders[provider] || notifier.Supported(provider)
+}
+
+// normalizeLogin приводит логин к виду, в котором он хранится: логины GitHub, GitLab
+// и Mattermost не различают регистр, member ID Slack записывается заглавными буквами
+func normalizeLogin(provider, login string) string {
+	login = strings.TrimSpace(login)
+	if provider == notifier.ProviderSlack {
+		return strings.ToUpper(login)
+	}
+	return strings.ToLower(login)
+}
+
+// LinkAccount привязывает логин на code host или ID в чате (member ID Slack, имя
+// пользователя Mattermost) к пользователю. Повторная привязка к тому же пользователю
+// не ошибка; логин, привязанный к другому, — ACCOUNT_LINKED.
+func (s *Service) LinkAccount(req *models.LinkAccountRequest) (*models.ExternalAccount, error) {
+	if s.logger != nil {
+		s.logger.Info("LinkAccount вызван", slog.String("user_id", req.UserID), slog.String("provider", req.Provider),
+			slog.String("login", req.Login))
+	}
+	if !accountProvider(req.Provider) {
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown provider")
+	}
+	login := normalizeLogin(req.Provider, req.Login)
+	if login == "" || req.UserID == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "user_id and login are required")
+	}
+	if notifier.Supported(req.Provider) && !notifier.ValidUserID(req.Provider, login) {
+		return nil, errWithCode(models.ErrorCodeValidation, "login is not a valid "+req.Provider+" user id")
+	}
+	account := models.ExternalAccount{Provider: req.Provider, Login: login, UserID: req.UserID}
+
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if _, err := tx.GetUser(req.UserID); err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "user not found")
+		}
+		linked, err := tx.LinkAccount(account)
+		if err != nil {
+			return err
+		}
+		if !linked {
+			return errWithCode(models.ErrorCodeAccountLinked, "login is linked to another user")
+		}
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось привязать учётную запись", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed link account: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("учётная запись привязана", slog.String("user_id", req.UserID), slog.String("login", account.Login))
+	}
+	return &account, nil
+}
+
+// UnlinkAccount удаляет привязку логина на code host или ID в чате
+func (s *Service) UnlinkAccount(req *models.LinkAccountRequest) (*models.ExternalAccount, error) {
+	if s.logger != nil {
+		s.logger.Info("UnlinkAccount вызван", slog.String("provider", req.Provider), slog.String("login", req.Login))
+	}
+	if !accountProvider(req.Provider) {
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown provider")
+	}
+	account := models.ExternalAccount{Provider: req.Provider, Login: normalizeLogin(req.Provider, req.Login)}
+	userID, err := s.storage.UnlinkAccount(account.Provider, account.Login)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("привязка не найдена", slog.String("login", req.Login), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "account not found")
+	}
+	account.UserID = userID
+	return &account, nil
+}
+
+// externalPullRequestID ID pull request'а, созданного по событию code host
+func externalPullRequestID(ref models.ExternalPullRequestRef) string {
+	return fmt.Sprintf("%s:%s#%d", ref.Provider, ref.Repository, ref.Number)
+}
+
+// ApplyCodeHostEvent применяет событие code host через те же операции, что и API:
+// открытие PR создаёт pull request с назначением ревьюверов, мерж и закрытие меняют
+// статус, ревью выставляет состояние ревью рецензента. События, которые нельзя
+// сопоставить (PR создан до подключения, логин не привязан, рецензент не назначен),
+// пропускаются с указанием причины, чтобы code host не повторял доставку.
+func (s *Service) ApplyCodeHostEvent(ev *models.CodeHostEvent) (*models.CodeHostEventResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ApplyCodeHostEvent вызван", slog.String("provider", ev.Ref.Provider), slog.String("type", string(ev.Type)),
+			slog.String("repository", ev.Ref.Repository), slog.Int64("number", ev.Ref.Number))
+	}
+	prID, err := s.storage.FindPullRequestByRef(ev.Ref)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось найти PR по событию code host", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed find pr by ref: %w", err)
+	}
+
+	if ev.Type == models.CodeHostEventOpened {
+		if prID != "" {
+			return s.ignoreCodeHostEvent(prID, "pull request already exists")
+		}
+		return s.createFromCodeHost(ev)
+	}
+	if prID == "" {
+		return s.ignoreCodeHostEvent("", "pull request is not tracked")
+	}
+
+	var action string
+	switch ev.Type {
+	case models.CodeHostEventMerged:
+		action = models.CodeHostActionMerged
+		// PR уже объединён на code host: фиксируем merge без проверки одобрений
+		_, err = s.mergePullRequest(prID, 0, false)
+	case models.CodeHostEventClosed:
+		action = models.CodeHostActionClosed
+		_, err = s.ClosePullRequest(prID, 0)
+	case models.CodeHostEventReopened:
+		action = models.CodeHostActionReopened
+		_, err = s.ReopenPullRequest(prID, 0)
+	case models.CodeHostEventReview:
+		reviewerID, rerr := s.storage.ResolveAccount(ev.Ref.Provider, strings.ToLower(ev.ReviewerLogin))
+		if rerr != nil {
+			return nil, fmt.Errorf("failed resolve reviewer: %w", rerr)
+		}
+		if reviewerID == "" {
+			return s.ignoreCodeHostEvent(prID, fmt.Sprintf("reviewer login %q is not linked", ev.ReviewerLogin))
+		}
+		action = models.CodeHostActionReviewed
+		_, err = s.SubmitReview(&models.SubmitReviewRequest{PullRequestID: prID, UserID: reviewerID, State: ev.ReviewState}, 0)
+		switch ParseCodeFromError(err) {
+		case models.ErrorCodeNotAssigned, models.ErrorCodePRMerged, models.ErrorCodePRClosed:
+			return s.ignoreCodeHostEvent(prID, err.Error())
+		}
+	default:
+		return s.ignoreCodeHostEvent(prID, "unsupported event")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.CodeHostEventResponse{Action: action, PullRequestID: prID}, nil
+}
+
+// createFromCodeHost создаёт pull request по событию открытия PR на code host
+func (s *Service) createFromCodeHost(ev *models.CodeHostEvent) (*models.CodeHostEventResponse, error) {
+	authorID, err := s.storage.ResolveAccount(ev.Ref.Provider, strings.ToLower(ev.AuthorLogin))
+	if err != nil {
+		return nil, fmt.Errorf("failed resolve author: %w", err)
+	}
+	if authorID == "" {
+		return s.ignoreCodeHostEvent("", fmt.Sprintf("author login %q is not linked", ev.AuthorLogin))
+	}
+
+	req := &models.CreatePullRequestRequest{
+		PullRequestID:   externalPullRequestID(ev.Ref),
+		PullRequestName: ev.Title,
+		AuthorID:        authorID,
+		Labels:          ev.Labels,
+	}
+	var pr models.PullRequest
+	err = s.storage.WithTx(func(tx *repository.Storage) error {
+		var err error
+		if pr, _, err = s.createPullRequest(tx, req, false); err != nil {
+			return err
+		}
+		if err := tx.CreatePullRequestRef(pr.PullRequestID, ev.Ref); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvents(s.pullRequestCreatedEvents(pr)...)
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось создать PR по событию code host", slog.String("pr_id", req.PullRequestID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed create pr: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("PR создан по событию code host", slog.String("pr_id", pr.PullRequestID))
+	}
+	return &models.CodeHostEventResponse{Action: models.CodeHostActionCreated, PullRequestID: pr.PullRequestID}, nil
+}
+
+// ignoreCodeHostEvent формирует ответ о пропущенном событии code host
+func (s *Service) ignoreCodeHostEvent(prID, reason string) (*models.CodeHostEventResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("событие code host пропущено", slog.String("pr_id", prID), slog.String("reason", reason))
+	}
+	return &models.CodeHostEventResponse{Action: models.CodeHostActionIgnored, PullRequestID: prID, Reason: reason}, nil
+}
+
+// syncCodeHostReviewers передаёт на code host изменение назначения из события reviewer.*:
+// запрашивает ревью у назначенного и снимает запрос у снятого ревьювера. Изменение
+// сверяется с текущими назначениями PR, поэтому задания можно повторять и выполнять
+// в любом порядке: снятый позже ревьювер не запрашивается, назначенный снова — не снимается.
+// PR, закрытые или удалённые к моменту выполнения, и ревьюверы без привязанного логина пропускаются.
+func (s *Service) syncCodeHostReviewers(ctx context.Context, ev models.DomainEvent) error {
+	data, err := decodeReviewerEvent(ev)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadJob, err)
+	}
+	var added, removed string
+	switch ev.Type {
+	case models.EventReviewerAssigned:
+		added = data.UserID
+	case models.EventReviewerReassigned:
+		added, removed = data.UserID, data.OldUserID
+	case models.EventReviewerUnassigned:
+		removed = data.UserID
+	default:
+		return fmt.Errorf("%w: unexpected event %s", errBadJob, ev.Type)
+	}
+
+	ref, err := s.storage.GetPullRequestRef(data.PullRequestID)
+	if err != nil || ref == nil {
+		return err
+	}
+	client, ok := s.codeHosts[ref.Provider]
+	if !ok {
+		return nil
+	}
+	pr, err := s.storage.GetPullRequest(data.PullRequestID)
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+	if pr.Status != models.PRStatusOpen {
+		return nil
+	}
+	if !containsString(pr.AssignedReviewers, added) {
+		added = ""
+	}
+	if containsString(pr.AssignedReviewers, removed) {
+		removed = ""
+	}
+	if added == "" && removed == "" {
+		return nil
+	}
+	logins, err := s.storage.ListAccountLogins(ref.Provider, []string{added, removed})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, codeHostSyncTimeout)
+	defer cancel()
+	if err := client.RemoveReviewers(ctx, *ref, accountLogins(logins, []string{removed})); err != nil {
+		return fmt.Errorf("remove reviewers: %w", err)
+	}
+	request := accountLogins(logins, []string{added})
+	if err := client.RequestReviewers(ctx, *ref, request); err != nil {
+		return fmt.Errorf("request reviewers: %w", err)
+	}
+	if s.logger != nil {
+		s.logger.Info("ревьюверы переданы на code host", slog.String("pr_id", pr.PullRequestID), slog.Any("logins", request),
+			slog.String("event_id", ev.ID))
+	}
+	return nil
+}
+
+// accountLogins логины пользователей userIDs, у которых есть привязка
+func accountLogins(logins map[string]string, userIDs []string) []string {
+	var res []string
+	for _, id := range userIDs {
+		if login, ok := logins[id]; ok {
+			res = append(res, login)
+		}
+	}
+	return res
+}
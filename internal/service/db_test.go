@@ -0,0 +1,60 @@
+package service
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"testing"
+
+	"pr-review-manager/internal/repository"
+)
+
+// newDBService создаёт сервис поверх тестовой БД из TEST_DATABASE_URL с пустыми таблицами.
+// Без TEST_DATABASE_URL тест пропускается.
+func newDBService(t *testing.T, opts ...Option) (*Service, *repository.Storage) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	st, err := repository.NewStorage(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if err := st.CreateTables(slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	truncateAll(t, url)
+
+	opts = append([]Option{WithRandSource(rand.NewSource(1))}, opts...)
+	return NewService(st, nil, opts...), st
+}
+
+// truncateAll очищает все таблицы текущей схемы тестовой БД
+func truncateAll(t *testing.T, url string) {
+	t.Helper()
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	var tables sql.NullString
+	err = db.QueryRow(`
+        SELECT string_agg(quote_ident(tablename), ', ')
+        FROM pg_tables WHERE schemaname = current_schema()
+    `).Scan(&tables)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	if !tables.Valid {
+		return
+	}
+	if _, err := db.Exec(`TRUNCATE ` + tables.String + ` RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+const (
+	// outboxBatchSize число событий outbox, публикуемых за один проход
+	outboxBatchSize = 100
+	// outboxRetention время хранения опубликованных событий в outbox
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// newEvent создаёт событие сервиса с данными data
+func (s *Service) newEvent(t models.DomainEventType, data any) models.DomainEvent {
+	// данные событий — структуры моделей, их сериализация не завершается ошибкой
+	raw, _ := json.Marshal(data)
+	return models.DomainEvent{ID: newEventID(), Type: t, OccurredAt: s.now().UTC(), Data: raw}
+}
+
+// newEventID случайный ID события, по которому получатель отбрасывает повторы
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// pullRequestCreatedEvents события создания PR: pr.created и reviewer.assigned на каждого рецензента
+func (s *Service) pullRequestCreatedEvents(pr models.PullRequest) []models.DomainEvent {
+	events := []models.DomainEvent{s.newEvent(models.EventPRCreated, models.PullRequestEventData{PR: pr})}
+	for _, id := range pr.AssignedReviewers {
+		events = append(events, s.newEvent(models.EventReviewerAssigned,
+			models.ReviewerEventData{PullRequestID: pr.PullRequestID, UserID: id}))
+	}
+	return events
+}
+
+// reviewMoveEvents события reviewer.reassigned для переносов назначений и reviewer.unassigned
+// для назначений, снятых без замены
+func (s *Service) reviewMoveEvents(moves []models.ReviewMove) []models.DomainEvent {
+	var events []models.DomainEvent
+	for _, mv := range moves {
+		if mv.ToUserID == "" {
+			events = append(events, s.newEvent(models.EventReviewerUnassigned,
+				models.ReviewerEventData{PullRequestID: mv.PullRequestID, UserID: mv.FromUserID}))
+			continue
+		}
+		events = append(events, s.newEvent(models.EventReviewerReassigned,
+			models.ReviewerEventData{PullRequestID: mv.PullRequestID, UserID: mv.ToUserID, OldUserID: mv.FromUserID}))
+	}
+	return events
+}
+
+// DispatchOutbox публикует до outboxBatchSize событий из outbox и возвращает их число.
+// Каждое событие публикуется в своей транзакции вместе с отметкой о публикации: если процесс
+// прервётся до фиксации, событие будет опубликовано повторно, так что получатели получают
+// события как минимум один раз. Публикация только пишет в очереди доставок и заданий, поэтому
+// транзакция не ждёт внешних систем, а ошибка одного события не откатывает уже опубликованные.
+func (s *Service) DispatchOutbox() (int, error) {
+	published := 0
+	for published < outboxBatchSize {
+		var claimed bool
+		err := s.storage.WithTx(func(tx *repository.Storage) error {
+			events, err := tx.ClaimOutboxEvents(1)
+			if err != nil || len(events) == 0 {
+				return err
+			}
+			claimed = true
+			e := events[0]
+			if err := s.publishEvent(tx, e.Event); err != nil {
+				return fmt.Errorf("publish event %s: %w", e.Event.ID, err)
+			}
+			return tx.MarkOutboxPublished([]int64{e.ID}, s.now().UTC())
+		})
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("не удалось опубликовать событие из outbox", slog.Any("err", err))
+			}
+			return published, fmt.Errorf("failed dispatch outbox: %w", err)
+		}
+		if !claimed {
+			break
+		}
+		published++
+	}
+	return published, nil
+}
+
+// publishEvent передаёт событие получателям в транзакции outbox: ставит его в очередь
+// доставки подписчикам webhook и в очередь заданий (уведомление в чат, передача
+// ревьюверов на code host). Внешние вызовы выполняют обработчики очередей.
+func (s *Service) publishEvent(tx *repository.Storage, ev models.DomainEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.EnqueueWebhookDeliveries(ev, payload); err != nil {
+		return err
+	}
+	return s.enqueueEventJobs(tx, ev)
+}
+
+// decodeReviewerEvent данные события reviewer.*
+func decodeReviewerEvent(ev models.DomainEvent) (models.ReviewerEventData, error) {
+	var data models.ReviewerEventData
+	if err := json.Unmarshal(ev.Data, &data); err != nil {
+		return data, fmt.Errorf("decode event %s data: %w", ev.ID, err)
+	}
+	return data, nil
+}
+
+// RunOutboxDispatcher публикует события из outbox каждые interval, пока не отменён ctx.
+// Если outbox не разобран за один проход, следующий выполняется сразу; после разбора
+// удаляются опубликованные события старше outboxRetention.
+func (s *Service) RunOutboxDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.DispatchOutbox()
+		if err == nil && n == outboxBatchSize && ctx.Err() == nil {
+			continue
+		}
+		if _, err := s.storage.PurgeOutboxEvents(s.now().Add(-outboxRetention)); err != nil && s.logger != nil {
+			s.logger.Error("не удалось очистить outbox", slog.Any("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+// BeginIdempotentRequest занимает ключ идемпотентности перед выполнением запроса.
+// Возвращает nil, если запрос нужно выполнить, или сохранённый ответ для повтора.
+// Повтор с другим телом запроса даёт IDEMPOTENCY_KEY_REUSED, повтор во время
+// выполнения первого запроса — IDEMPOTENCY_KEY_IN_PROGRESS.
+func (s *Service) BeginIdempotentRequest(key models.IdempotencyKey, requestHash string) (*models.IdempotentResponse, error) {
+	stored, reserved, err := s.storage.ReserveIdempotencyKey(key, requestHash, s.idempotencyTTL)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось занять ключ идемпотентности", slog.String("key", key.Key), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+	if stored.RequestHash != "" && stored.RequestHash != requestHash {
+		return nil, errWithCode(models.ErrorCodeKeyReused, "Idempotency-Key was used with a different request")
+	}
+	if stored.StatusCode == 0 {
+		return nil, errWithCode(models.ErrorCodeKeyInProgress, "request with this Idempotency-Key is in progress")
+	}
+	if s.logger != nil {
+		s.logger.Info("повтор запроса по ключу идемпотентности", slog.String("key", key.Key), slog.String("path", key.Path))
+	}
+	return &stored, nil
+}
+
+// CompleteIdempotentRequest сохраняет ответ на запрос для повторов. Ответы с ошибкой
+// сервера не сохраняются: ключ освобождается, и повтор выполнит запрос заново.
+func (s *Service) CompleteIdempotentRequest(key models.IdempotencyKey, resp models.IdempotentResponse) {
+	var err error
+	if resp.StatusCode >= 500 {
+		err = s.storage.ReleaseIdempotencyKey(key)
+	} else {
+		err = s.storage.SaveIdempotentResponse(key, resp)
+	}
+	if err != nil && s.logger != nil {
+		s.logger.Error("не удалось сохранить ответ по ключу идемпотентности", slog.String("key", key.Key), slog.Any("err", err))
+	}
+}
+
+// PurgeIdempotencyKeys удаляет истёкшие ключи идемпотентности и возвращает их число
+func (s *Service) PurgeIdempotencyKeys() (int, error) {
+	n, err := s.storage.PurgeIdempotencyKeys()
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось удалить истёкшие ключи идемпотентности", slog.Any("err", err))
+		}
+		return 0, fmt.Errorf("failed purge idempotency keys: %w", err)
+	}
+	if n > 0 && s.logger != nil {
+		s.logger.Info("истёкшие ключи идемпотентности удалены", slog.Int("purged", n))
+	}
+	return n, nil
+}
+
+// RunIdempotencyPurger периодически удаляет истёкшие ключи идемпотентности, пока не отменён ctx.
+// Ошибки запуска логируются, следующий запуск выполняется по расписанию.
+func (s *Service) RunIdempotencyPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, _ = s.PurgeIdempotencyKeys()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestIdempotentRequestReplay(t *testing.T) {
+	s, _ := newDBService(t)
+	key := models.IdempotencyKey{Key: "k1", Method: "POST", Path: "/pullRequest/create"}
+
+	stored, err := s.BeginIdempotentRequest(key, "hash-1")
+	if err != nil || stored != nil {
+		t.Fatalf("first request: %+v, %v; want reservation", stored, err)
+	}
+	s.CompleteIdempotentRequest(key, models.IdempotentResponse{
+		StatusCode: 201, ContentType: "application/json", ETag: `"3"`, Body: []byte(`{"ok":true}`),
+	})
+
+	stored, err = s.BeginIdempotentRequest(key, "hash-1")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if stored == nil || stored.StatusCode != 201 || string(stored.Body) != `{"ok":true}` || stored.ETag != `"3"` {
+		t.Errorf("replayed response %+v, want the stored one", stored)
+	}
+}
+
+func TestIdempotentRequestMismatchedBody(t *testing.T) {
+	s, _ := newDBService(t)
+	key := models.IdempotencyKey{Key: "k1", Method: "POST", Path: "/pullRequest/create"}
+
+	if _, err := s.BeginIdempotentRequest(key, "hash-1"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	s.CompleteIdempotentRequest(key, models.IdempotentResponse{StatusCode: 201})
+
+	_, err := s.BeginIdempotentRequest(key, "hash-2")
+	if code := ParseCodeFromError(err); code != models.ErrorCodeKeyReused {
+		t.Errorf("other body: code %q, want %q", code, models.ErrorCodeKeyReused)
+	}
+}
+
+func TestIdempotentRequestConcurrentReservation(t *testing.T) {
+	s, _ := newDBService(t)
+	key := models.IdempotencyKey{Key: "k1", Method: "POST", Path: "/pullRequest/create"}
+
+	const n = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, inProgress := 0, 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stored, err := s.BeginIdempotentRequest(key, "hash-1")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && stored == nil:
+				reserved++
+			case ParseCodeFromError(err) == models.ErrorCodeKeyInProgress:
+				inProgress++
+			default:
+				t.Errorf("unexpected result %+v, %v", stored, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 1 || inProgress != n-1 {
+		t.Errorf("reserved %d, in progress %d; want 1 and %d", reserved, inProgress, n-1)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	s, _ := newDBService(t, WithIdempotencyTTL(time.Millisecond))
+	key := models.IdempotencyKey{Key: "k1", Method: "POST", Path: "/pullRequest/create"}
+
+	if _, err := s.BeginIdempotentRequest(key, "hash-1"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	s.CompleteIdempotentRequest(key, models.IdempotentResponse{StatusCode: 201})
+	time.Sleep(10 * time.Millisecond)
+
+	// истёкший ключ занимается заново и без фоновой очистки
+	stored, err := s.BeginIdempotentRequest(key, "hash-2")
+	if err != nil || stored != nil {
+		t.Fatalf("expired key: %+v, %v; want reservation", stored, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n, err := s.PurgeIdempotencyKeys(); err != nil || n != 1 {
+		t.Errorf("purged %d keys, %v; want 1", n, err)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"pr-review-manager/internal/codehost"
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
+	"pr-review-manager/internal/repository"
+)
+
+const (
+	// eventJobBatchSize число заданий, выполняемых за один проход
+	eventJobBatchSize = 10
+	// eventJobLease время, на которое взятые задания скрываются от других обработчиков;
+	// покрывает порцию заданий с наибольшим временем выполнения (codeHostSyncTimeout)
+	eventJobLease = eventJobBatchSize*codeHostSyncTimeout + 5*time.Minute
+	// eventJobRetention время хранения выполненных заданий
+	eventJobRetention = 7 * 24 * time.Hour
+)
+
+// errBadJob задание, которое нельзя выполнить ни с какой попытки (повреждённые данные события)
+var errBadJob = errors.New("malformed job")
+
+// enqueueEventJobs ставит в очередь задания обработчиков события: уведомление в чат и передачу
+// ревьюверов на code host. Вызывается при публикации события в транзакции outbox: задание
+// сохраняется тогда и только тогда, когда событие отмечается опубликованным, а выполняется
+// отдельно, с повторами.
+func (s *Service) enqueueEventJobs(tx *repository.Storage, ev models.DomainEvent) error {
+	if err := s.enqueueChatNotify(tx, ev); err != nil {
+		return err
+	}
+	switch ev.Type {
+	case models.EventReviewerAssigned, models.EventReviewerReassigned, models.EventReviewerUnassigned:
+		if len(s.codeHosts) == 0 {
+			return nil
+		}
+		data, err := decodeReviewerEvent(ev)
+		if err != nil {
+			return err
+		}
+		ref, err := tx.GetPullRequestRef(data.PullRequestID)
+		if err != nil || ref == nil {
+			return err
+		}
+		if _, ok := s.codeHosts[ref.Provider]; !ok {
+			return nil
+		}
+		return tx.EnqueueEventJob(repository.JobCodeHostSync, ev)
+	}
+	return nil
+}
+
+// enqueueChatNotify ставит в очередь уведомление о назначении из события reviewer.assigned
+// или reviewer.reassigned, если для него есть канал и ревьювер не отказался от уведомлений
+func (s *Service) enqueueChatNotify(tx *repository.Storage, ev models.DomainEvent) error {
+	if ev.Type != models.EventReviewerAssigned && ev.Type != models.EventReviewerReassigned {
+		return nil
+	}
+	data, err := decodeReviewerEvent(ev)
+	if err != nil {
+		return err
+	}
+	target, err := resolveAssignmentTarget(tx, data)
+	if err != nil || target == nil {
+		return err
+	}
+	return tx.EnqueueEventJob(repository.JobChatNotify, ev)
+}
+
+// ProcessEventJobs выполняет одну порцию заданий, срок которых наступил, и возвращает их число.
+// Неудачные задания повторяются по тем же правилам, что и доставки webhook; задания,
+// отклонённые получателем, и задания с повреждёнными данными сразу переносятся в dead-letter.
+func (s *Service) ProcessEventJobs(ctx context.Context) (int, error) {
+	now := s.now().UTC()
+	jobs, err := s.storage.ClaimEventJobs(now, now.Add(eventJobLease), eventJobBatchSize)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось получить задания", slog.Any("err", err))
+		}
+		return 0, fmt.Errorf("failed claim event jobs: %w", err)
+	}
+
+	for _, j := range jobs {
+		runErr := s.runEventJob(ctx, j)
+		if runErr == nil {
+			err = s.storage.CompleteEventJob(j.ID, s.now().UTC())
+		} else {
+			var next *time.Time
+			if j.Attempts+1 < webhookMaxAttempts && !permanentJobError(runErr) {
+				at := s.now().UTC().Add(webhookRetryDelay(j.Attempts + 1))
+				next = &at
+			}
+			if s.logger != nil {
+				s.logger.Warn("задание не выполнено", slog.Int64("job_id", j.ID), slog.String("kind", j.Kind),
+					slog.Int("attempt", j.Attempts+1), slog.Bool("dead", next == nil), slog.Any("err", runErr))
+			}
+			err = s.storage.FailEventJob(j.ID, runErr.Error(), next)
+		}
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("не удалось сохранить результат задания", slog.Int64("job_id", j.ID), slog.Any("err", err))
+			}
+			return 0, fmt.Errorf("failed record event job: %w", err)
+		}
+	}
+	return len(jobs), nil
+}
+
+// permanentJobError ошибки, при которых повтор задания не поможет: получатель отклонил запрос
+// или данные задания повреждены
+func permanentJobError(err error) bool {
+	return errors.Is(err, codehost.ErrRejected) || errors.Is(err, notifier.ErrRejected) || errors.Is(err, errBadJob)
+}
+
+// runEventJob выполняет задание его обработчиком
+func (s *Service) runEventJob(ctx context.Context, j repository.EventJob) error {
+	switch j.Kind {
+	case repository.JobCodeHostSync:
+		return s.syncCodeHostReviewers(ctx, j.Event)
+	case repository.JobChatNotify:
+		return s.notifyAssignment(ctx, j.Event)
+	default:
+		return fmt.Errorf("%w: unknown kind %q", errBadJob, j.Kind)
+	}
+}
+
+// RunEventJobDispatcher выполняет задания по событиям каждые interval, пока не отменён ctx.
+// Если очередь не разобрана за один проход, следующий выполняется сразу; после разбора
+// удаляются выполненные задания старше eventJobRetention.
+func (s *Service) RunEventJobDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.ProcessEventJobs(ctx)
+		if err == nil && n == eventJobBatchSize && ctx.Err() == nil {
+			continue
+		}
+		if _, err := s.storage.PurgeEventJobs(s.now().Add(-eventJobRetention)); err != nil && s.logger != nil {
+			s.logger.Error("не удалось очистить задания", slog.Any("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
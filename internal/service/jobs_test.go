@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/codehost"
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
+)
+
+// fakeCodeHost записывает вызовы клиента code host и возвращает ошибки из очереди errs
+type fakeCodeHost struct {
+	mu    sync.Mutex
+	calls []string
+	errs  []error
+}
+
+func (f *fakeCodeHost) RequestReviewers(_ context.Context, ref models.ExternalPullRequestRef, logins []string) error {
+	return f.record("request", ref, logins)
+}
+
+func (f *fakeCodeHost) RemoveReviewers(_ context.Context, ref models.ExternalPullRequestRef, logins []string) error {
+	return f.record("remove", ref, logins)
+}
+
+func (f *fakeCodeHost) record(op string, ref models.ExternalPullRequestRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	for _, l := range logins {
+		f.calls = append(f.calls, fmt.Sprintf("%s %s#%d %s", op, ref.Repository, ref.Number, l))
+	}
+	return nil
+}
+
+// take возвращает записанные вызовы в порядке сортировки и очищает их
+func (f *fakeCodeHost) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	sort.Strings(calls)
+	return calls
+}
+
+// codeHostTeam создаёт команду с привязанными логинами GitHub и PR, открытый на GitHub
+func codeHostTeam(t *testing.T, s *Service, members ...string) {
+	t.Helper()
+	team := models.Team{TeamName: "backend"}
+	for _, id := range members {
+		team.Members = append(team.Members, member(id, true))
+	}
+	if _, err := s.AddTeam(&team, false); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	for _, id := range members {
+		if _, err := s.LinkAccount(&models.LinkAccountRequest{UserID: id, Provider: models.CodeHostGitHub, Login: "gh-" + id}); err != nil {
+			t.Fatalf("LinkAccount %s: %v", id, err)
+		}
+	}
+	resp, err := s.ApplyCodeHostEvent(&models.CodeHostEvent{
+		Type:        models.CodeHostEventOpened,
+		Ref:         models.ExternalPullRequestRef{Provider: models.CodeHostGitHub, Repository: "acme/api", Number: 7},
+		Title:       "feature",
+		AuthorLogin: "gh-" + members[0],
+	})
+	if err != nil || resp.Action != models.CodeHostActionCreated {
+		t.Fatalf("ApplyCodeHostEvent: %+v, %v", resp, err)
+	}
+}
+
+// runJobs публикует outbox и выполняет задания, срок которых наступил
+func runJobs(t *testing.T, s *Service) int {
+	t.Helper()
+	if _, err := s.DispatchOutbox(); err != nil {
+		t.Fatalf("DispatchOutbox: %v", err)
+	}
+	n, err := s.ProcessEventJobs(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessEventJobs: %v", err)
+	}
+	return n
+}
+
+func TestCodeHostSyncFollowsReviewerEvents(t *testing.T) {
+	host := &fakeCodeHost{}
+	s, _ := newDBService(t, WithCodeHost(models.CodeHostGitHub, host))
+	codeHostTeam(t, s, "author", "r1", "r2")
+
+	if n := runJobs(t, s); n != 2 {
+		t.Fatalf("ran %d jobs after create, want 2", n)
+	}
+	if got, want := host.take(), []string{"request acme/api#7 gh-r1", "request acme/api#7 gh-r2"}; !equalStrings(got, want) {
+		t.Fatalf("calls after create %v, want %v", got, want)
+	}
+
+	// r1 уходит из команды, заменить его некем: запрос ревью снимается на code host
+	if _, err := s.RemoveTeamMember(&models.RemoveTeamMemberRequest{TeamName: "backend", UserID: "r1"}, 0); err != nil {
+		t.Fatalf("RemoveTeamMember: %v", err)
+	}
+	if n := runJobs(t, s); n != 1 {
+		t.Fatalf("ran %d jobs after release, want 1", n)
+	}
+	if got, want := host.take(), []string{"remove acme/api#7 gh-r1"}; !equalStrings(got, want) {
+		t.Fatalf("calls after release %v, want %v", got, want)
+	}
+
+	// выполненные задания не повторяются
+	if n := runJobs(t, s); n != 0 {
+		t.Fatalf("ran %d jobs on an empty outbox, want 0", n)
+	}
+}
+
+func TestCodeHostSyncRetriesAndDeadLetters(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	host := &fakeCodeHost{errs: []error{errors.New("github unavailable"), nil, fmt.Errorf("%w: status 422", codehost.ErrRejected)}}
+	s, _ := newDBService(t, WithCodeHost(models.CodeHostGitHub, host), WithClock(func() time.Time { return now }))
+	codeHostTeam(t, s, "author", "r1")
+
+	// первая попытка не удалась: задание отложено и до срока не выполняется
+	if n := runJobs(t, s); n != 1 {
+		t.Fatalf("ran %d jobs, want 1", n)
+	}
+	if got := host.take(); len(got) != 0 {
+		t.Fatalf("calls after failure %v, want none", got)
+	}
+	if n := runJobs(t, s); n != 0 {
+		t.Fatalf("ran %d jobs before the retry delay, want 0", n)
+	}
+
+	now = now.Add(webhookRetryDelay(1))
+	if n := runJobs(t, s); n != 1 {
+		t.Fatalf("ran %d jobs after the retry delay, want 1", n)
+	}
+	if got, want := host.take(), []string{"request acme/api#7 gh-r1"}; !equalStrings(got, want) {
+		t.Fatalf("calls after retry %v, want %v", got, want)
+	}
+
+	// отказ code host не повторяется
+	if _, err := s.RemoveTeamMember(&models.RemoveTeamMemberRequest{TeamName: "backend", UserID: "r1"}, 0); err != nil {
+		t.Fatalf("RemoveTeamMember: %v", err)
+	}
+	if n := runJobs(t, s); n != 1 {
+		t.Fatalf("ran %d jobs after release, want 1", n)
+	}
+	now = now.Add(webhookRetryMax)
+	if n := runJobs(t, s); n != 0 {
+		t.Fatalf("ran %d jobs after rejection, want 0: the job must be dead", n)
+	}
+}
+
+func TestDispatchOutboxDoesNotWaitForChat(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var chatUp atomic.Bool
+	var sent atomic.Int32
+	chat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !chatUp.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sent.Add(1)
+	}))
+	defer chat.Close()
+
+	s, st := newDBService(t, WithNotifier(notifier.New(chat.Client())), WithClock(func() time.Time { return now }))
+	batchTeam(t, s)
+	if _, err := s.SetNotificationChannel(&models.SetNotificationChannelRequest{TeamName: "backend", Provider: notifier.ProviderSlack, WebhookURL: chat.URL}); err != nil {
+		t.Fatalf("SetNotificationChannel: %v", err)
+	}
+	for _, id := range []string{"pr-1", "pr-2"} {
+		if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: id, PullRequestName: id, AuthorID: "author"}, false); err != nil {
+			t.Fatalf("CreatePullRequest %s: %v", id, err)
+		}
+	}
+
+	// чат недоступен: события всё равно публикуются, уведомления ждут в очереди
+	n, err := s.DispatchOutbox()
+	if err != nil {
+		t.Fatalf("DispatchOutbox: %v", err)
+	}
+	if n == 0 || len(pendingEventTypes(t, st)) != 0 {
+		t.Fatalf("published %d events, pending %v; want the outbox drained", n, pendingEventTypes(t, st))
+	}
+	jobs, err := s.ProcessEventJobs(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessEventJobs: %v", err)
+	}
+	if jobs == 0 || sent.Load() != 0 {
+		t.Fatalf("ran %d jobs, sent %d; want failed notifications", jobs, sent.Load())
+	}
+
+	// после восстановления чата каждое уведомление отправляется один раз
+	chatUp.Store(true)
+	now = now.Add(webhookRetryDelay(1))
+	if n := runJobs(t, s); n != jobs {
+		t.Fatalf("retried %d jobs, want %d", n, jobs)
+	}
+	if int(sent.Load()) != jobs {
+		t.Fatalf("sent %d notifications, want %d", sent.Load(), jobs)
+	}
+	now = now.Add(webhookRetryMax)
+	if n := runJobs(t, s); n != 0 || int(sent.Load()) != jobs {
+		t.Fatalf("ran %d more jobs, sent %d; want no resends", n, sent.Load())
+	}
+}
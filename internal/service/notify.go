@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
+	"pr-review-manager/internal/repository"
+)
+
+// notifyTimeout ограничение времени отправки одного уведомления в чат
+const notifyTimeout = 15 * time.Second
+
+// SetNotificationChannel настраивает канал чата, в который отправляются уведомления
+// о назначениях на PR команды и её подкоманд без собственного канала
+func (s *Service) SetNotificationChannel(req *models.SetNotificationChannelRequest) (*models.NotificationChannelResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetNotificationChannel вызван", slog.String("team_name", req.TeamName), slog.String("provider", req.Provider))
+	}
+	if !notifier.Supported(req.Provider) {
+		return nil, errWithCode(models.ErrorCodeValidation, "provider must be slack or mattermost")
+	}
+	u, err := url.Parse(req.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "webhook_url must be an absolute http(s) URL")
+	}
+	if _, err := s.storage.GetTeamParent(req.TeamName); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("команда не найдена", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
+	}
+
+	ch := models.NotificationChannel{TeamName: req.TeamName, Provider: req.Provider, WebhookURL: req.WebhookURL, Channel: req.Channel}
+	if err := s.storage.SetNotificationChannel(ch); err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось сохранить канал уведомлений", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set notification channel: %w", err)
+	}
+	return &models.NotificationChannelResponse{NotificationChannel: ch}, nil
+}
+
+// DeleteNotificationChannel удаляет собственный канал уведомлений команды
+func (s *Service) DeleteNotificationChannel(teamName string) (*models.NotificationChannelResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("DeleteNotificationChannel вызван", slog.String("team_name", teamName))
+	}
+	ch, err := s.storage.DeleteNotificationChannel(teamName)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("канал уведомлений не найден", slog.String("team_name", teamName), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "notification channel not found")
+	}
+	return &models.NotificationChannelResponse{NotificationChannel: ch}, nil
+}
+
+// GetNotificationChannel получает канал, в который уходят уведомления команды: собственный
+// или ближайшей родительской команды (team_name в ответе указывает, чей это канал)
+func (s *Service) GetNotificationChannel(teamName string) (*models.NotificationChannelResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetNotificationChannel вызван", slog.String("team_name", teamName))
+	}
+	if _, err := s.storage.GetTeamParent(teamName); err != nil {
+		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
+	}
+	ch, err := resolveNotificationChannel(s.storage, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed resolve notification channel: %w", err)
+	}
+	if ch == nil {
+		return nil, errWithCode(models.ErrorCodeNotFound, "notification channel not configured")
+	}
+	return &models.NotificationChannelResponse{NotificationChannel: *ch}, nil
+}
+
+// SetUserNotifications включает или отключает уведомления пользователя о назначениях
+func (s *Service) SetUserNotifications(req *models.SetUserNotificationsRequest) (*models.UserNotificationsResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetUserNotifications вызван", slog.String("user_id", req.UserID), slog.Bool("enabled", req.Enabled))
+	}
+	if _, err := s.storage.GetUser(req.UserID); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("пользователь не найден", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
+	}
+	if err := s.storage.SetNotificationsEnabled(req.UserID, req.Enabled); err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить настройку уведомлений", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set user notifications: %w", err)
+	}
+	return &models.UserNotificationsResponse{UserID: req.UserID, Enabled: req.Enabled}, nil
+}
+
+// resolveNotificationChannel ищет канал уведомлений команды или ближайшей родительской команды;
+// nil, если канал не задан ни в одной команде цепочки
+func resolveNotificationChannel(st *repository.Storage, teamName string) (*models.NotificationChannel, error) {
+	visited := map[string]bool{}
+	for name := teamName; name != "" && !visited[name] && len(visited) < maxTeamDepth; {
+		visited[name] = true
+		ch, err := st.GetNotificationChannel(name)
+		if err != nil || ch != nil {
+			return ch, err
+		}
+		if name, err = st.GetTeamParent(name); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// assignmentTarget канал команды и данные сообщения о назначении ревьювера
+type assignmentTarget struct {
+	channel  models.NotificationChannel
+	pr       models.PullRequest
+	reviewer models.User
+}
+
+// resolveAssignmentTarget находит канал, в который сообщается о назначении из события.
+// nil, если пользователь отказался от уведомлений, PR или пользователь уже удалены
+// (PR мог быть перенесён в архив) или канал не задан.
+func resolveAssignmentTarget(st *repository.Storage, data models.ReviewerEventData) (*assignmentTarget, error) {
+	enabled, err := st.NotificationsEnabled(data.UserID)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	pr, err := st.GetPullRequest(data.PullRequestID)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	reviewer, err := st.GetUser(data.UserID)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	teamName := pr.TeamName
+	if teamName == "" {
+		teamName = reviewer.TeamName
+	}
+	ch, err := resolveNotificationChannel(st, teamName)
+	if err != nil || ch == nil {
+		return nil, err
+	}
+	return &assignmentTarget{channel: *ch, pr: pr, reviewer: reviewer}, nil
+}
+
+// notifyAssignment отправляет в канал команды PR сообщение о назначении ревьювера из события
+// reviewer.assigned или reviewer.reassigned. Пользователи упоминаются по учётным записям
+// в чате, привязанным через /users/linkAccount. Канал и отказ от уведомлений проверяются заново
+// при каждой попытке; ошибка возвращается, чтобы задание было повторено, а сообщение,
+// отклонённое чатом (notifier.ErrRejected), в очередь не возвращается.
+func (s *Service) notifyAssignment(ctx context.Context, ev models.DomainEvent) error {
+	data, err := decodeReviewerEvent(ev)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadJob, err)
+	}
+	target, err := resolveAssignmentTarget(s.storage, data)
+	if err != nil || target == nil {
+		return err
+	}
+
+	ch := target.channel
+	chatIDs, err := s.storage.ListAccountLogins(ch.Provider, []string{target.pr.AuthorID, data.UserID, data.OldUserID})
+	if err != nil {
+		return err
+	}
+	chatUser := func(userID string) notifier.User {
+		return notifier.User{Name: s.username(s.storage, userID), ChatID: chatIDs[userID]}
+	}
+	a := notifier.Assignment{
+		PullRequestID:   target.pr.PullRequestID,
+		PullRequestName: target.pr.PullRequestName,
+		Author:          chatUser(target.pr.AuthorID),
+		Reviewer:        chatUser(data.UserID),
+	}
+	if data.OldUserID != "" {
+		a.PreviousReviewer = chatUser(data.OldUserID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	if err := s.notifier.NotifyAssignment(ctx, ch.Provider, ch.WebhookURL, ch.Channel, a); err != nil {
+		return fmt.Errorf("notify %s: %w", ch.TeamName, err)
+	}
+	if s.logger != nil {
+		s.logger.Info("уведомление о назначении отправлено", slog.String("pr_id", target.pr.PullRequestID), slog.String("user_id", data.UserID),
+			slog.String("team_name", ch.TeamName))
+	}
+	return nil
+}
+
+// username имя пользователя для сообщения; если пользователь не найден — его ID
+func (s *Service) username(st *repository.Storage, userID string) string {
+	u, err := st.GetUser(userID)
+	if err != nil || u.Username == "" {
+		return userID
+	}
+	return u.Username
+}
+
+// ignoreNotFound подавляет ошибку отсутствующей записи
+func ignoreNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
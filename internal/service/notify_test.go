@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
+)
+
+// chatServer incoming webhook, сохраняющий тексты полученных сообщений
+type chatServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	texts []string
+}
+
+func newChatServer(t *testing.T) *chatServer {
+	t.Helper()
+	c := &chatServer{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			t.Errorf("decode payload %s: %v", raw, err)
+		}
+		c.mu.Lock()
+		c.texts = append(c.texts, payload["text"])
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// take возвращает полученные тексты в порядке сортировки и очищает их
+func (c *chatServer) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	texts := c.texts
+	c.texts = nil
+	sort.Strings(texts)
+	return texts
+}
+
+// notifyTeams создаёт команду platform с каналом Slack и подкоманду backend без канала
+func notifyTeams(t *testing.T, s *Service, chatURL string) {
+	t.Helper()
+	if _, err := s.AddTeam(&models.Team{TeamName: "platform", Members: []models.TeamMember{member("lead", true)}}, false); err != nil {
+		t.Fatalf("AddTeam platform: %v", err)
+	}
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", ParentTeam: "platform", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	req := &models.SetNotificationChannelRequest{TeamName: "platform", Provider: notifier.ProviderSlack, WebhookURL: chatURL}
+	if _, err := s.SetNotificationChannel(req); err != nil {
+		t.Fatalf("SetNotificationChannel: %v", err)
+	}
+}
+
+func TestNotifyAssignmentUsesParentChannelAndSlackIDs(t *testing.T) {
+	chat := newChatServer(t)
+	s, _ := newDBService(t, WithNotifier(notifier.New(chat.Client())))
+	notifyTeams(t, s, chat.URL)
+	if _, err := s.LinkAccount(&models.LinkAccountRequest{UserID: "r1", Provider: notifier.ProviderSlack, Login: "u01reviewer"}); err != nil {
+		t.Fatalf("LinkAccount: %v", err)
+	}
+
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "Add cache", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if n := runJobs(t, s); n != 2 {
+		t.Fatalf("ran %d jobs, want 2", n)
+	}
+	want := []string{
+		"<@U01REVIEWER> was assigned to review *Add cache* (pr-1) (author: author)",
+		"r2 was assigned to review *Add cache* (pr-1) (author: author)",
+	}
+	if got := chat.take(); !equalStrings(got, want) {
+		t.Errorf("messages %q, want %q", got, want)
+	}
+}
+
+func TestNotifyAssignmentSkipsOptedOutReviewer(t *testing.T) {
+	chat := newChatServer(t)
+	s, _ := newDBService(t, WithNotifier(notifier.New(chat.Client())))
+	notifyTeams(t, s, chat.URL)
+	if _, err := s.SetUserNotifications(&models.SetUserNotificationsRequest{UserID: "r1", Enabled: false}); err != nil {
+		t.Fatalf("SetUserNotifications: %v", err)
+	}
+
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "Add cache", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if n := runJobs(t, s); n != 1 {
+		t.Fatalf("ran %d jobs, want 1: no job for the opted-out reviewer", n)
+	}
+	if got, want := chat.take(), []string{"r2 was assigned to review *Add cache* (pr-1) (author: author)"}; !equalStrings(got, want) {
+		t.Errorf("messages %q, want %q", got, want)
+	}
+}
+
+func TestLinkChatAccountValidatesID(t *testing.T) {
+	s, _ := newDBService(t)
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{member("u1", true)}}, false); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	for _, tc := range []struct {
+		provider, login string
+		wantCode        string
+	}{
+		{notifier.ProviderSlack, "<!channel>", models.ErrorCodeValidation},
+		{notifier.ProviderMattermost, "channel", models.ErrorCodeValidation},
+		{notifier.ProviderMattermost, "Alice", ""},
+	} {
+		_, err := s.LinkAccount(&models.LinkAccountRequest{UserID: "u1", Provider: tc.provider, Login: tc.login})
+		if code := ParseCodeFromError(err); code != tc.wantCode {
+			t.Errorf("link %s %q: err %v, want code %q", tc.provider, tc.login, err, tc.wantCode)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// AddOrganization создаёт организацию с собственными настройками,
+// которые наследуют отнесённые к ней команды и их подкоманды
+func (s *Service) AddOrganization(req *models.AddOrganizationRequest) (*models.OrganizationResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("AddOrganization вызван", slog.String("org_name", req.OrgName))
+	}
+	if req.OrgName == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "org_name is required")
+	}
+	if err := validatePolicy(req.Policy); err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := tx.CreateOrganization(req.OrgName); err != nil {
+			if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "23505") {
+				return errWithCode(models.ErrorCodeOrgExists, "org_name already exists")
+			}
+			return err
+		}
+		if err := tx.SetOrganizationPolicy(req.OrgName, req.Policy); err != nil {
+			return err
+		}
+		o, err := tx.GetOrganization(req.OrgName)
+		if err != nil {
+			return err
+		}
+		org = o
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось создать организацию", slog.String("org_name", req.OrgName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось создать организацию", slog.String("org_name", req.OrgName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed add organization: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("организация создана", slog.String("org_name", req.OrgName))
+	}
+	return &models.OrganizationResponse{Organization: org}, nil
+}
+
+// GetOrganization возвращает организацию с её настройками и командами
+func (s *Service) GetOrganization(orgName string) (*models.OrganizationResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetOrganization вызван", slog.String("org_name", orgName))
+	}
+	org, err := s.storage.GetOrganization(orgName)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("организация не найдена", slog.String("org_name", orgName), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "organization not found")
+	}
+	return &models.OrganizationResponse{Organization: org}, nil
+}
+
+// SetOrganizationPolicy заменяет собственные настройки организации; незаданные настройки
+// команд организации берутся из значений по умолчанию
+func (s *Service) SetOrganizationPolicy(req *models.SetOrganizationPolicyRequest) (*models.OrganizationResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetOrganizationPolicy вызван", slog.String("org_name", req.OrgName))
+	}
+	if err := validatePolicy(req.Policy); err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if _, err := tx.GetOrganization(req.OrgName); err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "organization not found")
+		}
+		if err := tx.SetOrganizationPolicy(req.OrgName, req.Policy); err != nil {
+			return err
+		}
+		o, err := tx.GetOrganization(req.OrgName)
+		if err != nil {
+			return err
+		}
+		org = o
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось изменить настройки организации", slog.String("org_name", req.OrgName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить настройки организации", slog.String("org_name", req.OrgName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set organization policy: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("настройки организации изменены", slog.String("org_name", req.OrgName))
+	}
+	return &models.OrganizationResponse{Organization: org}, nil
+}
+
+// SetTeamOrganization относит команду к организации (пустой org_name убирает связь).
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) SetTeamOrganization(req *models.SetTeamOrganizationRequest, ifMatch int64) (*models.TeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetTeamOrganization вызван", slog.String("team_name", req.TeamName), slog.String("org_name", req.OrgName))
+	}
+
+	var team models.Team
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		if req.OrgName != "" {
+			if _, err := tx.GetOrganization(req.OrgName); err != nil {
+				return errWithCode(models.ErrorCodeNotFound, "organization not found")
+			}
+		}
+		if err := tx.SetTeamOrganization(req.TeamName, req.OrgName); err != nil {
+			return err
+		}
+		t, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		team = t
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось изменить организацию команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить организацию команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set team organization: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("организация команды изменена", slog.String("team_name", req.TeamName), slog.String("org_name", req.OrgName))
+	}
+	return &models.TeamResponse{Team: team}, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// maxTeamDepth ограничивает глубину иерархии команд при разрешении настроек
+const maxTeamDepth = 32
+
+// SetTeamParent делает команду дочерней для parent_team (пустое значение делает её корневой).
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) SetTeamParent(req *models.SetTeamParentRequest, ifMatch int64) (*models.TeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetTeamParent вызван", slog.String("team_name", req.TeamName), slog.String("parent_team", req.ParentTeam))
+	}
+
+	var team models.Team
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		// поднимаемся от нового родителя к корню: команда не должна оказаться своим предком
+		for name, depth := req.ParentTeam, 0; name != ""; depth++ {
+			if name == req.TeamName || depth >= maxTeamDepth {
+				return errWithCode(models.ErrorCodeValidation, "parent_team would create a cycle")
+			}
+			parent, err := tx.GetTeamParent(name)
+			if err != nil {
+				return errWithCode(models.ErrorCodeNotFound, "parent team not found")
+			}
+			name = parent
+		}
+		if err := tx.SetTeamParent(req.TeamName, req.ParentTeam); err != nil {
+			return err
+		}
+		t, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		team = t
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось изменить родителя команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить родителя команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set team parent: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("родитель команды изменён", slog.String("team_name", req.TeamName), slog.String("parent_team", req.ParentTeam))
+	}
+	return &models.TeamResponse{Team: team}, nil
+}
+
+// SetTeamPolicy заменяет собственные настройки команды; незаданные настройки наследуются.
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) SetTeamPolicy(req *models.SetTeamPolicyRequest, ifMatch int64) (*models.TeamPolicyResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetTeamPolicy вызван", slog.String("team_name", req.TeamName))
+	}
+	if err := validatePolicy(req.Policy); err != nil {
+		return nil, err
+	}
+
+	var resp *models.TeamPolicyResponse
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		if err := tx.SetTeamPolicy(req.TeamName, req.Policy); err != nil {
+			return err
+		}
+		effective, err := resolvePolicy(tx, req.TeamName)
+		if err != nil {
+			return err
+		}
+		resp = &models.TeamPolicyResponse{Own: req.Policy, Effective: effective}
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось изменить настройки команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить настройки команды", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed set team policy: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("настройки команды изменены", slog.String("team_name", req.TeamName))
+	}
+	return resp, nil
+}
+
+// GetTeamPolicy возвращает собственные и итоговые (с учётом наследования) настройки команды
+func (s *Service) GetTeamPolicy(teamName string) (*models.TeamPolicyResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetTeamPolicy вызван", slog.String("team_name", teamName))
+	}
+	if _, err := s.storage.GetTeamParent(teamName); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("команда не найдена", slog.String("team_name", teamName), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
+	}
+	own, err := s.storage.GetTeamPolicy(teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed get team policy: %w", err)
+	}
+	effective, err := resolvePolicy(s.storage, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed resolve team policy: %w", err)
+	}
+	return &models.TeamPolicyResponse{Own: own, Effective: effective}, nil
+}
+
+// validatePolicy проверяет, что заданные настройки не отрицательны
+func validatePolicy(p models.TeamPolicy) error {
+	for _, v := range []*int{p.ReviewerCount, p.RequiredApprovals, p.MaxOpenReviews} {
+		if v != nil && *v < 0 {
+			return errWithCode(models.ErrorCodeValidation, "policy values must not be negative")
+		}
+	}
+	return nil
+}
+
+// resolvePolicy вычисляет итоговые настройки команды: каждая настройка берётся
+// у ближайшей команды в цепочке к корню, где она задана, затем у организации
+// ближайшей отнесённой к ней команды цепочки, иначе — значение по умолчанию
+func resolvePolicy(st reviewerStore, teamName string) (models.EffectivePolicy, error) {
+	eff := models.EffectivePolicy{
+		TeamName: teamName,
+		Chain:    []string{},
+		Sources:  map[string]string{},
+	}
+	var reviewerCount, requiredApprovals, maxOpenReviews *int
+
+	visited := map[string]bool{}
+	for name := teamName; name != "" && !visited[name] && len(eff.Chain) < maxTeamDepth; {
+		visited[name] = true
+		eff.Chain = append(eff.Chain, name)
+
+		p, err := st.GetTeamPolicy(name)
+		if err != nil {
+			return eff, err
+		}
+		if reviewerCount == nil && p.ReviewerCount != nil {
+			reviewerCount = p.ReviewerCount
+			eff.Sources["reviewer_count"] = name
+		}
+		if requiredApprovals == nil && p.RequiredApprovals != nil {
+			requiredApprovals = p.RequiredApprovals
+			eff.Sources["required_approvals"] = name
+		}
+		if maxOpenReviews == nil && p.MaxOpenReviews != nil {
+			maxOpenReviews = p.MaxOpenReviews
+			eff.Sources["max_open_reviews"] = name
+		}
+
+		if eff.Organization == "" {
+			org, err := st.GetTeamOrganization(name)
+			if err != nil {
+				return eff, err
+			}
+			eff.Organization = org
+		}
+
+		parent, err := st.GetTeamParent(name)
+		if err != nil {
+			return eff, err
+		}
+		name = parent
+	}
+
+	if eff.Organization != "" {
+		p, err := st.GetOrganizationPolicy(eff.Organization)
+		if err != nil {
+			return eff, err
+		}
+		source := models.PolicySourceOrgPrefix + eff.Organization
+		if reviewerCount == nil && p.ReviewerCount != nil {
+			reviewerCount = p.ReviewerCount
+			eff.Sources["reviewer_count"] = source
+		}
+		if requiredApprovals == nil && p.RequiredApprovals != nil {
+			requiredApprovals = p.RequiredApprovals
+			eff.Sources["required_approvals"] = source
+		}
+		if maxOpenReviews == nil && p.MaxOpenReviews != nil {
+			maxOpenReviews = p.MaxOpenReviews
+			eff.Sources["max_open_reviews"] = source
+		}
+	}
+
+	eff.ReviewerCount = policyValue(reviewerCount, models.DefaultReviewerCount, "reviewer_count", eff.Sources)
+	eff.RequiredApprovals = policyValue(requiredApprovals, models.DefaultRequiredApprovals, "required_approvals", eff.Sources)
+	eff.MaxOpenReviews = policyValue(maxOpenReviews, models.DefaultMaxOpenReviews, "max_open_reviews", eff.Sources)
+	return eff, nil
+}
+
+// policyValue возвращает заданное значение настройки или значение по умолчанию
+func policyValue(v *int, def int, key string, sources map[string]string) int {
+	if v != nil {
+		return *v
+	}
+	sources[key] = models.PolicySourceDefault
+	return def
+}
+
+// eligibleCandidates отбирает кандидатов в ревьюверы среди участников команды
+// с учётом лимита открытых ревью из итоговых настроек команды
+func eligibleCandidates(st reviewerStore, members []models.TeamMember, policy models.EffectivePolicy, exclude map[string]string) ([]models.TeamMember, []models.ExcludedCandidate, error) {
+	candidates, excluded := collectCandidates(members, exclude)
+	if policy.MaxOpenReviews <= 0 || len(candidates) == 0 {
+		return candidates, excluded, nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, m := range candidates {
+		ids = append(ids, m.UserID)
+	}
+	counts, err := st.ListOpenReviewCounts(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	available := []models.TeamMember{}
+	for _, m := range candidates {
+		if counts[m.UserID] >= policy.MaxOpenReviews {
+			excluded = append(excluded, models.ExcludedCandidate{UserID: m.UserID, Reason: models.ExclusionReasonAtCapacity})
+			continue
+		}
+		available = append(available, m)
+	}
+	return available, excluded, nil
+}
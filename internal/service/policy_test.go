@@ -0,0 +1,154 @@
+package service
+
+import (
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestResolvePolicyInheritance(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("org", "")
+	st.addTeam("backend", "org")
+	st.addTeam("payments", "backend")
+	st.policies["org"] = models.TeamPolicy{ReviewerCount: intPtr(3), MaxOpenReviews: intPtr(5)}
+	st.policies["payments"] = models.TeamPolicy{ReviewerCount: intPtr(1)}
+
+	eff, err := resolvePolicy(st, "payments")
+	if err != nil {
+		t.Fatalf("resolvePolicy: %v", err)
+	}
+	if eff.ReviewerCount != 1 || eff.MaxOpenReviews != 5 || eff.RequiredApprovals != models.DefaultRequiredApprovals {
+		t.Errorf("effective policy %+v", eff)
+	}
+	if want := []string{"payments", "backend", "org"}; !equalStrings(eff.Chain, want) {
+		t.Errorf("chain %v, want %v", eff.Chain, want)
+	}
+	wantSources := map[string]string{
+		"reviewer_count":     "payments",
+		"max_open_reviews":   "org",
+		"required_approvals": models.PolicySourceDefault,
+	}
+	for key, src := range wantSources {
+		if eff.Sources[key] != src {
+			t.Errorf("source of %s = %q, want %q", key, eff.Sources[key], src)
+		}
+	}
+}
+
+func TestEligibleCandidatesCapacity(t *testing.T) {
+	st := newMemStore()
+	st.open["busy"] = 2
+	st.open["free"] = 1
+	members := []models.TeamMember{member("busy", true), member("free", true), member("author", true)}
+
+	candidates, excluded, err := eligibleCandidates(st, members, models.EffectivePolicy{MaxOpenReviews: 2},
+		map[string]string{"author": models.ExclusionReasonAuthor})
+	if err != nil {
+		t.Fatalf("eligibleCandidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].UserID != "free" {
+		t.Errorf("candidates %+v, want only free", candidates)
+	}
+	if len(excluded) != 2 {
+		t.Errorf("excluded %+v, want author and busy", excluded)
+	}
+}
+
+func TestMergeRequiresApprovals(t *testing.T) {
+	s, _ := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	_, err = s.SetTeamPolicy(&models.SetTeamPolicyRequest{TeamName: "backend", Policy: models.TeamPolicy{RequiredApprovals: intPtr(1)}}, 0)
+	if err != nil {
+		t.Fatalf("SetTeamPolicy: %v", err)
+	}
+	created, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+
+	_, err = s.MergePullRequest("pr-1", 0)
+	if code := ParseCodeFromError(err); code != models.ErrorCodeNotApproved {
+		t.Fatalf("merge without approvals: code %q, want %q", code, models.ErrorCodeNotApproved)
+	}
+
+	_, err = s.SubmitReview(&models.SubmitReviewRequest{
+		PullRequestID: "pr-1", UserID: created.PR.AssignedReviewers[0], State: models.ReviewStateApproved,
+	}, 0)
+	if err != nil {
+		t.Fatalf("SubmitReview: %v", err)
+	}
+	merged, err := s.MergePullRequest("pr-1", 0)
+	if err != nil {
+		t.Fatalf("merge with approval: %v", err)
+	}
+	if merged.PR.Status != models.PRStatusMerged {
+		t.Errorf("status %s, want MERGED", merged.PR.Status)
+	}
+}
+
+func TestResolvePolicyOrganization(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("engineering", "")
+	st.addTeam("backend", "engineering")
+	st.orgs["engineering"] = "acme"
+	st.orgRules["acme"] = models.TeamPolicy{ReviewerCount: intPtr(4), RequiredApprovals: intPtr(2)}
+	st.policies["engineering"] = models.TeamPolicy{ReviewerCount: intPtr(3)}
+
+	eff, err := resolvePolicy(st, "backend")
+	if err != nil {
+		t.Fatalf("resolvePolicy: %v", err)
+	}
+	if eff.Organization != "acme" {
+		t.Errorf("organization %q, want acme", eff.Organization)
+	}
+	// настройка команды-предка важнее настройки организации
+	if eff.ReviewerCount != 3 || eff.Sources["reviewer_count"] != "engineering" {
+		t.Errorf("reviewer_count %d from %q, want 3 from engineering", eff.ReviewerCount, eff.Sources["reviewer_count"])
+	}
+	if eff.RequiredApprovals != 2 || eff.Sources["required_approvals"] != models.PolicySourceOrgPrefix+"acme" {
+		t.Errorf("required_approvals %d from %q, want 2 from org:acme", eff.RequiredApprovals, eff.Sources["required_approvals"])
+	}
+	if eff.Sources["max_open_reviews"] != models.PolicySourceDefault {
+		t.Errorf("max_open_reviews source %q, want default", eff.Sources["max_open_reviews"])
+	}
+}
+
+func TestOrganizationPolicyInherited(t *testing.T) {
+	s, _ := newDBService(t)
+
+	if _, err := s.AddOrganization(&models.AddOrganizationRequest{OrgName: "acme", Policy: models.TeamPolicy{MaxOpenReviews: intPtr(7)}}); err != nil {
+		t.Fatalf("AddOrganization: %v", err)
+	}
+	if _, err := s.AddOrganization(&models.AddOrganizationRequest{OrgName: "acme"}); ParseCodeFromError(err) != models.ErrorCodeOrgExists {
+		t.Errorf("duplicate organization: %v, want %s", err, models.ErrorCodeOrgExists)
+	}
+	if _, err := s.AddTeam(&models.Team{TeamName: "engineering", Organization: "acme"}, false); err != nil {
+		t.Fatalf("AddTeam engineering: %v", err)
+	}
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend", ParentTeam: "engineering"}, false); err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+
+	resp, err := s.GetTeamPolicy("backend")
+	if err != nil {
+		t.Fatalf("GetTeamPolicy: %v", err)
+	}
+	if resp.Effective.MaxOpenReviews != 7 || resp.Effective.Organization != "acme" {
+		t.Errorf("effective policy %+v, want max_open_reviews 7 from acme", resp.Effective)
+	}
+
+	org, err := s.GetOrganization("acme")
+	if err != nil {
+		t.Fatalf("GetOrganization: %v", err)
+	}
+	if !equalStrings(org.Organization.Teams, []string{"engineering"}) {
+		t.Errorf("organization teams %v, want [engineering]", org.Organization.Teams)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// UpdatePullRequest изменяет название, автора и метки PR. Изменение применяется,
+// только если версия PR совпадает с версией из запроса, иначе VERSION_CONFLICT:
+// так два редактора не перезаписывают изменения друг друга. Версию можно передать
+// и в If-Match (ifMatch), несовпадение с ней даёт PRECONDITION_FAILED.
+// Если новый автор был ревьювером OPEN PR, его место занимает другой участник команды.
+func (s *Service) UpdatePullRequest(req *models.UpdatePullRequestRequest, ifMatch int64) (*models.PullRequestResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("UpdatePullRequest вызван", slog.String("pr_id", req.PullRequestID), slog.Int64("version", req.Version))
+	}
+	if req.Version <= 0 && ifMatch <= 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "version or If-Match is required")
+	}
+	if req.PullRequestName != nil && strings.TrimSpace(*req.PullRequestName) == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "pull_request_name must not be empty")
+	}
+	var labels []string
+	if req.Labels != nil {
+		l, err := normalizeLabels(*req.Labels)
+		if err != nil {
+			return nil, err
+		}
+		labels = l
+	}
+
+	var pr models.PullRequest
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		current, err := tx.GetPullRequest(req.PullRequestID)
+		if err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "pr not found")
+		}
+		if ifMatch > 0 && current.Version != ifMatch {
+			return errWithCode(models.ErrorCodePrecondition, "pr version does not match If-Match")
+		}
+		if req.Version > 0 && current.Version != req.Version {
+			return errWithCode(models.ErrorCodeVersionConflict, "pr was modified, reload and retry")
+		}
+
+		if req.PullRequestName != nil {
+			current.PullRequestName = *req.PullRequestName
+		}
+		authorChanged := false
+		if req.AuthorID != nil && *req.AuthorID != current.AuthorID {
+			if _, err := tx.GetUser(*req.AuthorID); err != nil {
+				return errWithCode(models.ErrorCodeNotFound, "author not found")
+			}
+			current.AuthorID = *req.AuthorID
+			authorChanged = true
+		}
+		if req.Labels != nil {
+			current.Labels = labels
+		}
+
+		if err := tx.UpdatePullRequestMeta(&current); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return errWithCode(models.ErrorCodeVersionConflict, "pr was modified, reload and retry")
+			}
+			return err
+		}
+		// новый автор не может ревьюить свой PR: его назначение получает другой участник команды
+		if authorChanged && current.Status == models.PRStatusOpen && containsString(current.AssignedReviewers, current.AuthorID) {
+			if _, err := s.replaceAuthorReview(tx, current); err != nil {
+				return err
+			}
+		}
+		pr, err = tx.GetPullRequest(current.PullRequestID)
+		return err
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось изменить PR", slog.String("pr_id", req.PullRequestID), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить PR", slog.String("pr_id", req.PullRequestID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed update pr: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("PR изменён", slog.String("pr_id", pr.PullRequestID), slog.Int64("version", pr.Version))
+	}
+	return &models.PullRequestResponse{PR: pr}, nil
+}
+
+// ClosePullRequest закрывает OPEN pull request без мержа (статус CLOSED).
+// Повторное закрытие возвращает текущее состояние; объединённый PR закрыть нельзя.
+func (s *Service) ClosePullRequest(prID string, ifMatch int64) (*models.PullRequestResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ClosePullRequest вызван", slog.String("pr_id", prID))
+	}
+	return s.changePullRequestStatus(prID, models.PRStatusClosed, ifMatch)
+}
+
+// ReopenPullRequest снова открывает закрытый pull request; рецензенты и их состояние ревью сохраняются.
+// Повторное открытие возвращает текущее состояние; объединённый PR открыть нельзя.
+func (s *Service) ReopenPullRequest(prID string, ifMatch int64) (*models.PullRequestResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ReopenPullRequest вызван", slog.String("pr_id", prID))
+	}
+	return s.changePullRequestStatus(prID, models.PRStatusOpen, ifMatch)
+}
+
+// changePullRequestStatus переводит PR между статусами OPEN и CLOSED и записывает
+// в outbox событие pr.closed или pr.reopened в той же транзакции
+func (s *Service) changePullRequestStatus(prID string, status models.PRStatus, ifMatch int64) (*models.PullRequestResponse, error) {
+	pr, err := s.storage.GetPullRequest(prID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("не удалось получить PR", slog.String("pr_id", prID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "pr not found")
+	}
+	if ifMatch > 0 && pr.Version != ifMatch {
+		return nil, errWithCode(models.ErrorCodePrecondition, "pr version does not match If-Match")
+	}
+	if pr.Status == status {
+		return &models.PullRequestResponse{PR: pr}, nil
+	}
+	if pr.Status == models.PRStatusMerged {
+		return nil, errWithCode(models.ErrorCodePRMerged, "pr is already merged")
+	}
+
+	pr.Status = status
+	pr.ClosedAt = time.Time{}
+	if status == models.PRStatusClosed {
+		pr.ClosedAt = s.now().UTC()
+	}
+	eventType := models.EventPRReopened
+	if status == models.PRStatusClosed {
+		eventType = models.EventPRClosed
+	}
+	err = s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := tx.UpdatePullRequest(&pr); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvents(s.newEvent(eventType, models.PullRequestEventData{PR: pr}))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, errWithCode(models.ErrorCodeVersionConflict, "pr was modified concurrently, retry")
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось изменить статус PR", slog.String("pr_id", prID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed update pr: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("статус PR изменён", slog.String("pr_id", prID), slog.String("status", string(status)))
+	}
+	return &models.PullRequestResponse{PR: pr}, nil
+}
+
+// SubmitReview выставляет состояние ревью рецензента OPEN pull request'а
+func (s *Service) SubmitReview(req *models.SubmitReviewRequest, ifMatch int64) (*models.PullRequestResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SubmitReview вызван", slog.String("pr_id", req.PullRequestID), slog.String("user_id", req.UserID),
+			slog.String("state", string(req.State)))
+	}
+	switch req.State {
+	case models.ReviewStatePending, models.ReviewStateApproved, models.ReviewStateChangesRequested:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "state must be PENDING, APPROVED or CHANGES_REQUESTED")
+	}
+
+	var pr models.PullRequest
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		current, err := tx.GetPullRequest(req.PullRequestID)
+		if err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "pr not found")
+		}
+		if ifMatch > 0 && current.Version != ifMatch {
+			return errWithCode(models.ErrorCodePrecondition, "pr version does not match If-Match")
+		}
+		switch current.Status {
+		case models.PRStatusMerged:
+			return errWithCode(models.ErrorCodePRMerged, "cannot review merged PR")
+		case models.PRStatusClosed:
+			return errWithCode(models.ErrorCodePRClosed, "cannot review closed PR")
+		}
+		assigned, err := tx.SetReviewState(current.PullRequestID, req.UserID, req.State)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return errWithCode(models.ErrorCodeNotAssigned, "reviewer is not assigned to this PR")
+		}
+		pr, err = tx.GetPullRequest(current.PullRequestID)
+		return err
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось сохранить ревью", slog.String("pr_id", req.PullRequestID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed submit review: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("ревью сохранено", slog.String("pr_id", pr.PullRequestID), slog.String("user_id", req.UserID))
+	}
+	return &models.PullRequestResponse{PR: pr}, nil
+}
+
+// normalizeLabels убирает пробелы по краям и повторы меток, сохраняя порядок
+func normalizeLabels(labels []string) ([]string, error) {
+	result := make([]string, 0, len(labels))
+	for _, l := range labels {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			return nil, errWithCode(models.ErrorCodeValidation, "labels must not be empty")
+		}
+		if !containsString(result, l) {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+func TestUpdatePullRequestReplacesNewAuthorReview(t *testing.T) {
+	s, _ := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true), member("r3", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	created, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	want := len(created.PR.AssignedReviewers)
+	newAuthor := created.PR.AssignedReviewers[0]
+
+	updated, err := s.UpdatePullRequest(&models.UpdatePullRequestRequest{
+		PullRequestID: "pr-1", AuthorID: &newAuthor, Version: created.PR.Version,
+	}, 0)
+	if err != nil {
+		t.Fatalf("UpdatePullRequest: %v", err)
+	}
+	if updated.PR.AuthorID != newAuthor {
+		t.Errorf("author %s, want %s", updated.PR.AuthorID, newAuthor)
+	}
+	if containsString(updated.PR.AssignedReviewers, newAuthor) {
+		t.Errorf("new author %s still reviews %v", newAuthor, updated.PR.AssignedReviewers)
+	}
+	if len(updated.PR.AssignedReviewers) != want {
+		t.Errorf("reviewers %v, want %d: the slot must be refilled", updated.PR.AssignedReviewers, want)
+	}
+}
+
+func TestGetReviewPRsPagesEqualSortKeys(t *testing.T) {
+	// все PR создаются в один момент: границы страниц держатся на pull_request_id
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, _ := newDBService(t, WithClock(func() time.Time { return now }))
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("pr-%d", i)
+		if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: id, PullRequestName: id, AuthorID: "author"}, false); err != nil {
+			t.Fatalf("CreatePullRequest %s: %v", id, err)
+		}
+		ids = append(ids, id)
+	}
+
+	for _, order := range []string{models.SortOrderAsc, models.SortOrderDesc} {
+		want := append([]string(nil), ids...)
+		if order == models.SortOrderDesc {
+			sort.Sort(sort.Reverse(sort.StringSlice(want)))
+		}
+
+		var got []string
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			resp, err := s.GetReviewPRs("r1", models.ReviewQueueFilter{
+				Sort: models.PRSortCreatedAt, Order: order, Limit: 2, Cursor: cursor,
+			})
+			if err != nil {
+				t.Fatalf("%s page %d: %v", order, page, err)
+			}
+			for _, pr := range resp.PullRequests {
+				got = append(got, pr.PullRequestID)
+			}
+			if cursor = resp.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if !equalStrings(got, want) {
+			t.Errorf("%s: pages %v, want %v", order, got, want)
+		}
+	}
+}
+
+// pendingEventTypes возвращает типы неопубликованных событий outbox в порядке записи
+func pendingEventTypes(t *testing.T, st *repository.Storage) []models.DomainEventType {
+	t.Helper()
+	var types []models.DomainEventType
+	err := st.WithTx(func(tx *repository.Storage) error {
+		events, err := tx.ClaimOutboxEvents(1000)
+		for _, e := range events {
+			types = append(types, e.Event.Type)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ClaimOutboxEvents: %v", err)
+	}
+	return types
+}
+
+func TestCloseReopenWriteOutboxEvents(t *testing.T) {
+	s, st := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if _, err := s.ClosePullRequest("pr-1", 0); err != nil {
+		t.Fatalf("ClosePullRequest: %v", err)
+	}
+	// повторное закрытие ничего не меняет и событие не пишет
+	if _, err := s.ClosePullRequest("pr-1", 0); err != nil {
+		t.Fatalf("ClosePullRequest again: %v", err)
+	}
+	if _, err := s.ReopenPullRequest("pr-1", 0); err != nil {
+		t.Fatalf("ReopenPullRequest: %v", err)
+	}
+
+	types := pendingEventTypes(t, st)
+	want := []models.DomainEventType{models.EventPRCreated, models.EventReviewerAssigned, models.EventPRClosed, models.EventPRReopened}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("outbox events %v, want %v", types, want)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// RebalanceTeam перераспределяет назначения на OPEN pull request'ы команды так,
+// чтобы нагрузка активных участников не превышала среднюю больше чем на threshold.
+// Назначения с уже выставленным состоянием ревью не переносятся, а получатель переноса
+// не должен превысить max_open_reviews из итоговых настроек команды.
+// В режиме dry_run возвращает план переносов, не применяя его.
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) RebalanceTeam(req *models.RebalanceTeamRequest, ifMatch int64) (*models.RebalanceTeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("RebalanceTeam вызван", slog.String("team_name", req.TeamName), slog.Bool("dry_run", req.DryRun))
+	}
+
+	threshold := models.DefaultRebalanceThreshold
+	if req.Threshold != nil {
+		if *req.Threshold < 0 {
+			return nil, errWithCode(models.ErrorCodeValidation, "threshold must not be negative")
+		}
+		threshold = *req.Threshold
+	}
+
+	var resp *models.RebalanceTeamResponse
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		team, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Warn("команда не найдена", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return errWithCode(models.ErrorCodeNotFound, "team not found")
+		}
+		policy, err := resolvePolicy(tx, req.TeamName)
+		if err != nil {
+			return fmt.Errorf("failed resolve team policy: %w", err)
+		}
+
+		prs, err := tx.ListOpenPRsByTeam(req.TeamName)
+		if err != nil {
+			return fmt.Errorf("failed list open prs: %w", err)
+		}
+		prIDs := make([]string, 0, len(prs))
+		for _, pr := range prs {
+			prIDs = append(prIDs, pr.PullRequestID)
+		}
+		submitted, err := tx.ListSubmittedReviews(prIDs)
+		if err != nil {
+			return fmt.Errorf("failed list submitted reviews: %w", err)
+		}
+		userIDs := make([]string, 0, len(team.Members))
+		for _, m := range team.Members {
+			userIDs = append(userIDs, m.UserID)
+		}
+		open, err := tx.ListOpenReviewCounts(userIDs)
+		if err != nil {
+			return fmt.Errorf("failed count open reviews: %w", err)
+		}
+
+		average, moves, loads := planRebalance(team.Members, prs, submitted, threshold, reviewCapacity{max: policy.MaxOpenReviews, open: open})
+		resp = &models.RebalanceTeamResponse{
+			TeamName:  req.TeamName,
+			Average:   average,
+			Threshold: threshold,
+			DryRun:    req.DryRun,
+			Moves:     moves,
+			Loads:     loads,
+		}
+		if req.DryRun {
+			return nil
+		}
+
+		for _, mv := range moves {
+			if err := tx.MoveReviewer(mv.PullRequestID, mv.FromUserID, mv.ToUserID); err != nil {
+				return fmt.Errorf("failed move reviewer on %s: %w", mv.PullRequestID, err)
+			}
+		}
+		return tx.AddOutboxEvents(s.reviewMoveEvents(moves)...)
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось перераспределить ревью", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed rebalance team: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("ревью перераспределены", slog.String("team_name", req.TeamName), slog.Int("moves", len(resp.Moves)), slog.Bool("dry_run", req.DryRun))
+	}
+	return resp, nil
+}
+
+// reviewCapacity лимит открытых ревью на пользователя; open — текущее число назначений
+// на OPEN PR во всех командах. max <= 0 означает отсутствие лимита.
+type reviewCapacity struct {
+	max  int
+	open map[string]int
+}
+
+// full сообщает, что пользователь не может получить ещё одно назначение
+func (c reviewCapacity) full(userID string) bool {
+	return c.max > 0 && c.open[userID] >= c.max
+}
+
+// planRebalance строит план переносов назначений между активными участниками команды.
+// submitted — рецензенты PR, уже выставившие состояние ревью; их назначения не переносятся.
+// Возвращает среднюю нагрузку, список переносов и нагрузку участников после их применения.
+func planRebalance(members []models.TeamMember, prs []models.PullRequest, submitted map[string]map[string]bool, threshold float64, capacity reviewCapacity) (float64, []models.ReviewMove, map[string]int) {
+	loads := map[string]int{}
+	for _, m := range members {
+		if m.IsActive {
+			loads[m.UserID] = 0
+		}
+	}
+	moves := []models.ReviewMove{}
+	if len(loads) == 0 {
+		return 0, moves, loads
+	}
+	open := map[string]int{}
+	for uid, n := range capacity.open {
+		open[uid] = n
+	}
+	capacity.open = open
+
+	// переносимые PR'ы каждого участника в порядке создания
+	byUser := map[string][]*models.PullRequest{}
+	total := 0
+	for i := range prs {
+		pr := &prs[i]
+		for _, rid := range pr.AssignedReviewers {
+			if _, ok := loads[rid]; !ok {
+				continue
+			}
+			loads[rid]++
+			total++
+			if !submitted[pr.PullRequestID][rid] {
+				byUser[rid] = append(byUser[rid], pr)
+			}
+		}
+	}
+	average := float64(total) / float64(len(loads))
+	limit := average + threshold
+
+	users := make([]string, 0, len(loads))
+	for uid := range loads {
+		users = append(users, uid)
+	}
+
+	for {
+		// перегруженные участники, самые загруженные первыми
+		sort.Slice(users, func(i, j int) bool {
+			if loads[users[i]] != loads[users[j]] {
+				return loads[users[i]] > loads[users[j]]
+			}
+			return users[i] < users[j]
+		})
+
+		moved := false
+		for _, src := range users {
+			if float64(loads[src]) <= limit {
+				break
+			}
+			if mv, ok := moveOne(src, users, loads, byUser, capacity); ok {
+				moves = append(moves, mv)
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return average, moves, loads
+}
+
+// moveOne переносит самое новое назначение src на наименее загруженного подходящего участника.
+// Перенос допустим, только если он строго уменьшает разницу нагрузок и получатель не упирается в лимит.
+func moveOne(src string, users []string, loads map[string]int, byUser map[string][]*models.PullRequest, capacity reviewCapacity) (models.ReviewMove, bool) {
+	assigned := byUser[src]
+	for i := len(assigned) - 1; i >= 0; i-- {
+		pr := assigned[i]
+
+		dst := ""
+		for _, uid := range users {
+			if uid == src || uid == pr.AuthorID || loads[uid]+1 >= loads[src] || capacity.full(uid) {
+				continue
+			}
+			if containsString(pr.AssignedReviewers, uid) {
+				continue
+			}
+			if dst == "" || loads[uid] < loads[dst] || (loads[uid] == loads[dst] && uid < dst) {
+				dst = uid
+			}
+		}
+		if dst == "" {
+			continue
+		}
+
+		for j, rid := range pr.AssignedReviewers {
+			if rid == src {
+				pr.AssignedReviewers[j] = dst
+			}
+		}
+		byUser[src] = append(assigned[:i:i], assigned[i+1:]...)
+		byUser[dst] = append(byUser[dst], pr)
+		loads[src]--
+		loads[dst]++
+		capacity.open[src]--
+		capacity.open[dst]++
+		return models.ReviewMove{PullRequestID: pr.PullRequestID, FromUserID: src, ToUserID: dst}, true
+	}
+	return models.ReviewMove{}, false
+}
+
+// containsString проверяет, есть ли значение в срезе
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+// openPRs создаёт n OPEN PR автора author с одним рецензентом reviewer
+func openPRs(prefix, author, reviewer string, n int) []models.PullRequest {
+	prs := make([]models.PullRequest, 0, n)
+	for i := 0; i < n; i++ {
+		prs = append(prs, models.PullRequest{
+			PullRequestID:     fmt.Sprintf("%s-%d", prefix, i),
+			AuthorID:          author,
+			Status:            models.PRStatusOpen,
+			AssignedReviewers: []string{reviewer},
+		})
+	}
+	return prs
+}
+
+func TestPlanRebalanceSpreadsLoad(t *testing.T) {
+	members := []models.TeamMember{member("a", true), member("b", true), member("c", true)}
+	prs := openPRs("pr", "author", "a", 6)
+
+	_, moves, loads := planRebalance(members, prs, nil, 0, reviewCapacity{})
+	if len(moves) != 4 {
+		t.Fatalf("moves %+v, want 4", moves)
+	}
+	for _, uid := range []string{"a", "b", "c"} {
+		if loads[uid] != 2 {
+			t.Errorf("load of %s = %d, want 2", uid, loads[uid])
+		}
+	}
+}
+
+func TestPlanRebalanceRespectsMaxOpenReviews(t *testing.T) {
+	members := []models.TeamMember{member("a", true), member("b", true), member("c", true)}
+	prs := openPRs("pr", "author", "a", 6)
+	// у b уже есть открытые ревью в другой команде
+	capacity := reviewCapacity{max: 2, open: map[string]int{"a": 6, "b": 2}}
+
+	_, moves, loads := planRebalance(members, prs, nil, 0, capacity)
+	for _, mv := range moves {
+		if mv.ToUserID == "b" {
+			t.Errorf("move %+v exceeds max_open_reviews of b", mv)
+		}
+	}
+	if loads["c"] != 2 {
+		t.Errorf("load of c = %d, want 2", loads["c"])
+	}
+	if capacity.open["a"] != 6 {
+		t.Error("planRebalance modified caller's open review counts")
+	}
+}
+
+func TestPlanRebalanceKeepsSubmittedReviews(t *testing.T) {
+	members := []models.TeamMember{member("a", true), member("b", true)}
+	prs := openPRs("pr", "author", "a", 4)
+	submitted := map[string]map[string]bool{
+		"pr-0": {"a": true},
+		"pr-1": {"a": true},
+		"pr-2": {"a": true},
+	}
+
+	_, moves, _ := planRebalance(members, prs, submitted, 0, reviewCapacity{})
+	if len(moves) != 1 || moves[0].PullRequestID != "pr-3" {
+		t.Errorf("moves %+v, want only pr-3", moves)
+	}
+}
+
+func TestPlanRebalanceSkipsAuthor(t *testing.T) {
+	members := []models.TeamMember{member("a", true), member("b", true)}
+	prs := openPRs("pr", "b", "a", 4)
+
+	_, moves, _ := planRebalance(members, prs, nil, 0, reviewCapacity{})
+	if len(moves) != 0 {
+		t.Errorf("moves %+v, want none: b is the author", moves)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+
+	"pr-review-manager/internal/models"
+)
+
+// memberWeight возвращает вес кандидата, подставляя вес по умолчанию для неположительных значений
+func memberWeight(m models.TeamMember) float64 {
+	if m.ReviewWeight <= 0 {
+		return models.DefaultReviewWeight
+	}
+	return m.ReviewWeight
+}
+
+// pickWeighted выбирает до n разных кандидатов систематической выборкой с вероятностями,
+// пропорциональными весу: кандидат попадает в выборку с вероятностью n·w/W (W — сумма
+// весов), поэтому доля назначений каждого участника сходится к его доле веса и при n > 1.
+// Кандидаты, для которых n·w/W ≥ 1, выбираются всегда (см. inclusionProbabilities).
+func pickWeighted(rnd *rand.Rand, candidates []models.TeamMember, n int) []models.TeamMember {
+	pool := make([]models.TeamMember, len(candidates))
+	copy(pool, candidates)
+
+	if n > len(pool) {
+		n = len(pool)
+	}
+	picked := make([]models.TeamMember, 0, n)
+	if n <= 0 {
+		return picked
+	}
+	// систематическая выборка зависит от порядка кандидатов: перемешиваем, чтобы совместные
+	// вероятности выбора пар не определялись порядком участников в команде
+	rnd.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	// отрезки длиной в вероятность кандидата укладываются подряд на [0, n);
+	// выбираются кандидаты, в отрезки которых попали точки u, u+1, …, u+n-1
+	probs := inclusionProbabilities(pool, n)
+	u := rnd.Float64()
+	from := 0.0
+	for i, m := range pool {
+		to := from + probs[i]
+		if i == len(pool)-1 {
+			// сумма вероятностей равна n; убираем ошибку округления
+			to = float64(n)
+		}
+		if math.Ceil(to-u) > math.Ceil(from-u) {
+			picked = append(picked, m)
+		}
+		from = to
+	}
+	return picked
+}
+
+// inclusionProbabilities вероятности попадания кандидатов в выборку из n: n·w/W.
+// Кандидаты, у которых она не меньше 1, выбираются наверняка, а оставшиеся места
+// распределяются между остальными пропорционально весу.
+func inclusionProbabilities(pool []models.TeamMember, n int) []float64 {
+	probs := make([]float64, len(pool))
+	certain := make([]bool, len(pool))
+	left := float64(n)
+	for changed := true; changed; {
+		changed = false
+		total := 0.0
+		for i, m := range pool {
+			if !certain[i] {
+				total += memberWeight(m)
+			}
+		}
+		for i, m := range pool {
+			if certain[i] {
+				probs[i] = 1
+				continue
+			}
+			probs[i] = left * memberWeight(m) / total
+			if probs[i] >= 1 {
+				certain[i], changed = true, true
+				left--
+			}
+		}
+	}
+	return probs
+}
+
+// weightByLoad делит вес каждого кандидата на 1 + число его открытых ревью,
+// чтобы менее загруженные участники выбирались чаще
+func weightByLoad(st reviewerStore, candidates []models.TeamMember) ([]models.TeamMember, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	ids := make([]string, 0, len(candidates))
+	for _, m := range candidates {
+		ids = append(ids, m.UserID)
+	}
+	counts, err := st.ListOpenReviewCounts(ids)
+	if err != nil {
+		return nil, err
+	}
+	weighted := make([]models.TeamMember, 0, len(candidates))
+	for _, m := range candidates {
+		m.ReviewWeight = memberWeight(m) / float64(1+counts[m.UserID])
+		weighted = append(weighted, m)
+	}
+	return weighted, nil
+}
+
+// collectCandidates отбирает активных участников команды, не попавших в exclude.
+// exclude сопоставляет user_id с причиной исключения; для остальных
+// исключённых участников причиной считается неактивность.
+func collectCandidates(members []models.TeamMember, exclude map[string]string) ([]models.TeamMember, []models.ExcludedCandidate) {
+	candidates := []models.TeamMember{}
+	excluded := []models.ExcludedCandidate{}
+	for _, m := range members {
+		if reason, ex := exclude[m.UserID]; ex {
+			excluded = append(excluded, models.ExcludedCandidate{UserID: m.UserID, Reason: reason})
+			continue
+		}
+		if !m.IsActive {
+			excluded = append(excluded, models.ExcludedCandidate{UserID: m.UserID, Reason: models.ExclusionReasonInactive})
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+	return candidates, excluded
+}
+
+// chooseReviewers выбирает до n ревьюверов из кандидатов и описывает принятое решение
+func (s *Service) chooseReviewers(candidates []models.TeamMember, excluded []models.ExcludedCandidate, n int) ([]string, *models.AssignmentExplanation) {
+	s.rndMu.Lock()
+	picked := pickWeighted(s.rnd, candidates, n)
+	s.rndMu.Unlock()
+
+	chosen := []string{}
+	for _, m := range picked {
+		chosen = append(chosen, m.UserID)
+	}
+
+	explanation := &models.AssignmentExplanation{
+		Strategy:   models.AssignmentStrategyWeightedRandom,
+		Candidates: make([]string, 0, len(candidates)),
+		Excluded:   excluded,
+		Chosen:     chosen,
+	}
+	for _, m := range candidates {
+		explanation.Candidates = append(explanation.Candidates, m.UserID)
+	}
+
+	if s.logger != nil {
+		s.logger.Debug("выбор ревьюверов",
+			slog.String("strategy", explanation.Strategy),
+			slog.Any("candidates", explanation.Candidates),
+			slog.Any("excluded", explanation.Excluded),
+			slog.Any("chosen", explanation.Chosen))
+	}
+	return chosen, explanation
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestPickWeightedFrequency(t *testing.T) {
+	candidates := []models.TeamMember{
+		{UserID: "u1", ReviewWeight: 1},
+		{UserID: "u2", ReviewWeight: 2},
+		{UserID: "u3", ReviewWeight: 3},
+		{UserID: "u4", ReviewWeight: 4},
+	}
+	rnd := rand.New(rand.NewSource(42))
+
+	const draws = 100000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		picked := pickWeighted(rnd, candidates, 1)
+		if len(picked) != 1 {
+			t.Fatalf("picked %d candidates, want 1", len(picked))
+		}
+		counts[picked[0].UserID]++
+	}
+
+	total := 0.0
+	for _, m := range candidates {
+		total += m.ReviewWeight
+	}
+	for _, m := range candidates {
+		want := m.ReviewWeight / total
+		got := float64(counts[m.UserID]) / draws
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("%s: frequency %.4f, want %.4f ± 0.01", m.UserID, got, want)
+		}
+	}
+}
+
+func TestPickWeightedInclusionProportionalToWeight(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		weights []float64
+		n       int
+		want    []float64
+	}{
+		// при назначении двух ревьюверов доля назначений пропорциональна весу: 2·w/W
+		{"two of four", []float64{1, 2, 3, 4}, 2, []float64{0.2, 0.4, 0.6, 0.8}},
+		// 2·8/10 > 1: тяжёлый кандидат выбирается всегда, второе место делят остальные
+		{"certain candidate", []float64{1, 1, 8}, 2, []float64{0.5, 0.5, 1}},
+		// 3·4/10 > 1: остальные два места делятся в пропорции весов 1:1:2:2
+		{"three of five", []float64{1, 1, 2, 2, 4}, 3, []float64{1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			candidates := make([]models.TeamMember, len(tc.weights))
+			for i, w := range tc.weights {
+				candidates[i] = models.TeamMember{UserID: fmt.Sprintf("u%d", i+1), ReviewWeight: w}
+			}
+			rnd := rand.New(rand.NewSource(42))
+
+			const draws = 100000
+			counts := map[string]int{}
+			for i := 0; i < draws; i++ {
+				picked := pickWeighted(rnd, candidates, tc.n)
+				if len(picked) != tc.n {
+					t.Fatalf("picked %d candidates, want %d", len(picked), tc.n)
+				}
+				for _, m := range picked {
+					counts[m.UserID]++
+				}
+			}
+			for i, m := range candidates {
+				got := float64(counts[m.UserID]) / draws
+				if math.Abs(got-tc.want[i]) > 0.01 {
+					t.Errorf("%s: inclusion rate %.4f, want %.4f ± 0.01", m.UserID, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPickWeightedDistinct(t *testing.T) {
+	candidates := []models.TeamMember{
+		{UserID: "u1", ReviewWeight: 1},
+		{UserID: "u2", ReviewWeight: 0},
+		{UserID: "u3", ReviewWeight: 5},
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		picked := pickWeighted(rnd, candidates, 5)
+		if len(picked) != len(candidates) {
+			t.Fatalf("picked %d candidates, want %d", len(picked), len(candidates))
+		}
+		seen := map[string]bool{}
+		for _, m := range picked {
+			if seen[m.UserID] {
+				t.Fatalf("candidate %s picked twice", m.UserID)
+			}
+			seen[m.UserID] = true
+		}
+	}
+}
+
+func TestPickWeightedDefaultWeight(t *testing.T) {
+	// неположительный вес считается весом по умолчанию
+	candidates := []models.TeamMember{
+		{UserID: "u1", ReviewWeight: 0},
+		{UserID: "u2", ReviewWeight: models.DefaultReviewWeight},
+	}
+	rnd := rand.New(rand.NewSource(7))
+
+	const draws = 50000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[pickWeighted(rnd, candidates, 1)[0].UserID]++
+	}
+	if got := float64(counts["u1"]) / draws; math.Abs(got-0.5) > 0.01 {
+		t.Errorf("u1: frequency %.4f, want 0.5 ± 0.01", got)
+	}
+}
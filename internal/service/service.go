@@ -1,30 +1,109 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"pr-review-manager/internal/codehost"
 	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/notifier"
 	"pr-review-manager/internal/repository"
 )
 
 type Service struct {
-	storage *repository.Storage
-	rnd     *rand.Rand
-	logger  *slog.Logger
+	storage        *repository.Storage
+	rndMu          sync.Mutex // rand.Rand не потокобезопасен, а сервис обслуживает параллельные запросы
+	rnd            *rand.Rand
+	now            func() time.Time
+	explain        bool
+	idempotencyTTL time.Duration
+	codeHosts      map[string]codehost.Client
+	webhookClient  *http.Client
+	notifier       *notifier.Notifier
+	logger         *slog.Logger
 }
 
-func NewService(stor *repository.Storage, logger *slog.Logger) *Service {
-	return &Service{
-		storage: stor,
-		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
-		logger:  logger,
+// Option настраивает Service при создании
+type Option func(*Service)
+
+// WithRandSource задаёт источник случайности для выбора ревьюверов (для воспроизводимых тестов)
+func WithRandSource(src rand.Source) Option {
+	return func(s *Service) {
+		s.rnd = rand.New(src)
+	}
+}
+
+// WithClock задаёт функцию текущего времени вместо time.Now
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// WithExplain включает отладочный режим, в котором ответы на создание PR
+// и переназначение содержат объяснение выбора ревьюверов
+func WithExplain(enabled bool) Option {
+	return func(s *Service) {
+		s.explain = enabled
+	}
+}
+
+// WithIdempotencyTTL задаёт время хранения ответов на запросы с Idempotency-Key
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.idempotencyTTL = ttl
+	}
+}
+
+// WithCodeHost подключает клиент code host provider, через который назначения ревьюверов
+// на PR, созданных по событиям этого code host, передаются обратно на code host
+// заданиями по событиям reviewer.* (см. RunEventJobDispatcher)
+func WithCodeHost(provider string, c codehost.Client) Option {
+	return func(s *Service) {
+		if s.codeHosts == nil {
+			s.codeHosts = map[string]codehost.Client{}
+		}
+		s.codeHosts[provider] = c
+	}
+}
+
+// WithWebhookClient задаёт HTTP-клиент для доставки webhook подписчикам
+func WithWebhookClient(c *http.Client) Option {
+	return func(s *Service) {
+		s.webhookClient = c
+	}
+}
+
+// WithNotifier задаёт отправителя уведомлений о назначениях в чат
+func WithNotifier(n *notifier.Notifier) Option {
+	return func(s *Service) {
+		s.notifier = n
 	}
 }
 
+func NewService(stor *repository.Storage, logger *slog.Logger, opts ...Option) *Service {
+	s := &Service{
+		storage:        stor,
+		rnd:            rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:            time.Now,
+		idempotencyTTL: models.DefaultIdempotencyTTL,
+		webhookClient:  &http.Client{Timeout: 10 * time.Second},
+		notifier:       notifier.New(nil),
+		logger:         logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 // errWithCode создаёт ошибку с кодом ошибки
 func errWithCode(code, msg string) error {
 	return &models.ErrorResponse{
@@ -35,11 +114,21 @@ func errWithCode(code, msg string) error {
 	}
 }
 
+// isDomainError проверяет, что ошибка создана errWithCode
+func isDomainError(err error) bool {
+	var er *models.ErrorResponse
+	return errors.As(err, &er)
+}
+
 // ParseCodeFromError извлекает код ошибки из ошибки сервиса
 func ParseCodeFromError(err error) string {
 	if err == nil {
 		return ""
 	}
+	var er *models.ErrorResponse
+	if errors.As(err, &er) {
+		return er.ErrDetail.Code
+	}
 	parts := strings.SplitN(err.Error(), ": ", 2)
 	if len(parts) >= 2 {
 		return parts[0]
@@ -47,65 +136,173 @@ func ParseCodeFromError(err error) string {
 	return ""
 }
 
-// AddTeam создаёт новую команду и добавляет пользователей
-func (s *Service) AddTeam(team *models.Team) (*models.TeamResponse, error) {
+// AddTeam создаёт новую команду и добавляет пользователей в одной транзакции.
+// Новые пользователи создаются с данными из запроса; у существующих меняются только
+// членство и основная команда. Пользователи из других команд переносятся только
+// при allowMove; их назначения на OPEN PR прежней команды передаются её участникам,
+// как при исключении из команды.
+func (s *Service) AddTeam(team *models.Team, allowMove bool) (*models.TeamMembershipResponse, error) {
 	if s.logger != nil {
-		s.logger.Info("AddTeam вызван", slog.String("team_name", team.TeamName))
+		s.logger.Info("AddTeam вызван", slog.String("team_name", team.TeamName), slog.Bool("allow_move", allowMove))
 	}
 
-	// пытаемся создать команду
-	if err := s.storage.CreateTeam(*team); err != nil {
-		if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "23505") {
+	// проверяем веса ревьюверов до создания команды
+	for _, m := range team.Members {
+		if m.ReviewWeight < 0 {
 			if s.logger != nil {
-				s.logger.Warn("команда уже существует", slog.String("team_name", team.TeamName))
+				s.logger.Warn("отрицательный вес ревьювера", slog.String("user_id", m.UserID), slog.Float64("review_weight", m.ReviewWeight))
 			}
-			return nil, errWithCode(models.ErrorCodeTeamExists, "team_name already exists")
-		}
-		if s.logger != nil {
-			s.logger.Error("не удалось создать команду", slog.Any("err", err))
+			return nil, errWithCode(models.ErrorCodeValidation, "review_weight must be positive")
 		}
-		return nil, fmt.Errorf("failed create team: %w", err)
 	}
 
-	// добавляем пользователей команды
-	for _, m := range team.Members {
-		u := models.User{
-			UserID:   m.UserID,
-			Username: m.Username,
-			TeamName: team.TeamName,
-			IsActive: m.IsActive,
+	resp := &models.TeamMembershipResponse{ReleasedReviews: []models.ReviewMove{}}
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		// не забираем пользователей из других команд без явного разрешения;
+		// данные существующих пользователей не меняются, в ответ попадают сохранённые
+		moving := map[string]string{}
+		existingUsers := map[string]models.User{}
+		for i, m := range team.Members {
+			existing, err := tx.GetUser(m.UserID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err != nil {
+				if m.ReviewWeight == 0 {
+					team.Members[i].ReviewWeight = models.DefaultReviewWeight
+				}
+				continue
+			}
+			existingUsers[m.UserID] = existing
+			team.Members[i] = models.TeamMember{UserID: existing.UserID, Username: existing.Username,
+				IsActive: existing.IsActive, ReviewWeight: existing.ReviewWeight}
+			if existing.TeamName == "" || existing.TeamName == team.TeamName {
+				continue
+			}
+			if !allowMove {
+				if s.logger != nil {
+					s.logger.Warn("пользователь состоит в другой команде", slog.String("user_id", m.UserID), slog.String("team", existing.TeamName))
+				}
+				return errWithCode(models.ErrorCodeUserInOtherTeam, fmt.Sprintf("user %s belongs to team %s", m.UserID, existing.TeamName))
+			}
+			moving[m.UserID] = existing.TeamName
+		}
+
+		if team.Organization != "" {
+			if _, err := tx.GetOrganization(team.Organization); err != nil {
+				return errWithCode(models.ErrorCodeNotFound, "organization not found")
+			}
 		}
-		if err := s.storage.UpsertUser(u); err != nil {
+
+		// пытаемся создать команду
+		if err := tx.CreateTeam(*team); err != nil {
+			if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "23505") {
+				if s.logger != nil {
+					s.logger.Warn("команда уже существует", slog.String("team_name", team.TeamName))
+				}
+				return errWithCode(models.ErrorCodeTeamExists, "team_name already exists")
+			}
+			if strings.Contains(err.Error(), "foreign key constraint") || strings.Contains(err.Error(), "23503") {
+				if s.logger != nil {
+					s.logger.Warn("родительская команда не найдена", slog.String("parent_team", team.ParentTeam))
+				}
+				return errWithCode(models.ErrorCodeNotFound, "parent team not found")
+			}
+			return fmt.Errorf("failed create team: %w", err)
+		}
+
+		// создаём новых пользователей; существующие, как в AddTeamMember, только получают
+		// членство и эту команду основной
+		for _, m := range team.Members {
+			u, found := existingUsers[m.UserID]
+			if !found {
+				u = models.User{
+					UserID:       m.UserID,
+					Username:     m.Username,
+					IsActive:     m.IsActive,
+					ReviewWeight: m.ReviewWeight,
+				}
+			}
+			u.TeamName = team.TeamName
+			if found {
+				if err := tx.UpdateUser(u); err != nil {
+					return fmt.Errorf("failed update user %s: %w", u.UserID, err)
+				}
+			} else if err := tx.UpsertUser(u); err != nil {
+				return fmt.Errorf("failed upsert user %s: %w", u.UserID, err)
+			}
+			// при переносе пользователь покидает прежнюю основную команду и её ревью
+			if oldTeamName, ok := moving[u.UserID]; ok {
+				if err := tx.RemoveMembership(oldTeamName, u.UserID); err != nil {
+					return fmt.Errorf("failed remove membership %s: %w", u.UserID, err)
+				}
+				oldTeam, err := tx.GetTeam(oldTeamName)
+				if err != nil {
+					return err
+				}
+				released, err := s.releaseOpenReviews(tx, u.UserID, oldTeamName, &oldTeam)
+				if err != nil {
+					return err
+				}
+				resp.ReleasedReviews = append(resp.ReleasedReviews, released...)
+			}
 			if s.logger != nil {
-				s.logger.Error("не удалось добавить пользователя", slog.String("user_id", u.UserID), slog.Any("err", err))
+				s.logger.Debug("пользователь добавлен", slog.String("user_id", u.UserID))
 			}
-			_ = s.storage.DeleteTeam(team.TeamName)
-			return nil, fmt.Errorf("failed upsert user %s: %w", u.UserID, err)
+		}
+
+		version, err := tx.LockTeamVersion(team.TeamName)
+		if err != nil {
+			return err
+		}
+		team.Version = version
+		resp.Team = *team
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
 		}
 		if s.logger != nil {
-			s.logger.Debug("пользователь добавлен", slog.String("user_id", u.UserID))
+			s.logger.Error("не удалось создать команду", slog.String("team_name", team.TeamName), slog.Any("err", err))
 		}
+		return nil, fmt.Errorf("failed add team: %w", err)
 	}
 
 	if s.logger != nil {
-		s.logger.Info("команда создана", slog.String("team_name", team.TeamName))
+		s.logger.Info("команда создана", slog.String("team_name", team.TeamName), slog.Int("released", len(resp.ReleasedReviews)))
 	}
-	return &models.TeamResponse{Team: *team}, nil
+	return resp, nil
 }
 
-// GetTeam получает информацию о команде по названию
-func (s *Service) GetTeam(teamName string) (*models.TeamResponse, error) {
+// GetTeam получает информацию о команде по названию со страницей участников
+func (s *Service) GetTeam(teamName string, page models.PageRequest) (*models.TeamResponse, error) {
 	if s.logger != nil {
 		s.logger.Info("GetTeam вызван", slog.String("team_name", teamName))
 	}
-	team, err := s.storage.GetTeam(teamName)
+	limit, err := pageLimit(page.Limit)
+	if err != nil {
+		return nil, err
+	}
+	team, err := s.storage.GetTeamInfo(teamName)
 	if err != nil {
 		if s.logger != nil {
 			s.logger.Warn("команда не найдена", slog.String("team_name", teamName), slog.Any("err", err))
 		}
 		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
 	}
-	return &models.TeamResponse{Team: team}, nil
+	members, next, err := s.storage.ListTeamMembers(teamName, limit, page.Cursor)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось получить участников команды", slog.String("team_name", teamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed list team members: %w", err)
+	}
+	team.Members = members
+	return &models.TeamResponse{Team: team, NextCursor: next}, nil
 }
 
 // SetUserActive изменяет статус активности пользователя
@@ -121,8 +318,9 @@ func (s *Service) SetUserActive(userID string, isActive bool) (*models.UserRespo
 		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
 	}
 
+	deactivated := u.IsActive && !isActive
 	u.IsActive = isActive
-	if err := s.storage.UpdateUser(u); err != nil {
+	if err := s.updateUser(u, deactivated); err != nil {
 		if s.logger != nil {
 			s.logger.Error("не удалось обновить пользователя", slog.String("user_id", userID), slog.Any("err", err))
 		}
@@ -135,112 +333,210 @@ func (s *Service) SetUserActive(userID string, isActive bool) (*models.UserRespo
 	return &models.UserResponse{User: u}, nil
 }
 
-// CreatePullRequest создаёт новый pull request и назначает рецензентов
-func (s *Service) CreatePullRequest(req *models.CreatePullRequestRequest) (*models.PullRequestResponse, error) {
+// SetUserReviewWeight изменяет вес пользователя при случайном выборе ревьюверов
+func (s *Service) SetUserReviewWeight(userID string, weight float64) (*models.UserResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("SetUserReviewWeight вызван", slog.String("user_id", userID), slog.Float64("review_weight", weight))
+	}
+	if weight <= 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "review_weight must be positive")
+	}
+	u, err := s.storage.GetUser(userID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("пользователь не найден", slog.String("user_id", userID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
+	}
+
+	u.ReviewWeight = weight
+	if err := s.storage.UpdateUser(u); err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось обновить пользователя", slog.String("user_id", userID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed update user: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("вес ревьювера обновлён", slog.String("user_id", userID), slog.Float64("review_weight", weight))
+	}
+	return &models.UserResponse{User: u}, nil
+}
+
+// CreatePullRequest создаёт новый pull request и назначает рецензентов из команды PR
+// (указанной в запросе или основной команды автора) по её итоговым настройкам. При explain ответ содержит объяснение выбора ревьюверов.
+func (s *Service) CreatePullRequest(req *models.CreatePullRequestRequest, explain bool) (*models.PullRequestResponse, error) {
 	if s.logger != nil {
 		s.logger.Info("CreatePullRequest вызван", slog.String("pr_id", req.PullRequestID), slog.String("author", req.AuthorID))
 	}
 
+	var pr models.PullRequest
+	var explanation *models.AssignmentExplanation
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		var err error
+		if pr, explanation, err = s.createPullRequest(tx, req, false); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvents(s.pullRequestCreatedEvents(pr)...)
+	})
+	if err != nil {
+		if isDomainError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed create pr: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("PR создан", slog.String("pr_id", pr.PullRequestID))
+	}
+	resp := &models.PullRequestResponse{PR: pr}
+	if explain || s.explain {
+		resp.Explain = explanation
+	}
+	return resp, nil
+}
+
+// createPullRequest создаёт PR и назначает рецензентов в хранилище st (обычно в транзакции).
+// При loadAware вес кандидата уменьшается пропорционально числу его открытых ревью.
+func (s *Service) createPullRequest(st reviewerStore, req *models.CreatePullRequestRequest, loadAware bool) (models.PullRequest, *models.AssignmentExplanation, error) {
+	var pr models.PullRequest
+	if req.PullRequestID == "" || req.AuthorID == "" {
+		return pr, nil, errWithCode(models.ErrorCodeValidation, "pull_request_id and author_id are required")
+	}
+	labels, err := normalizeLabels(req.Labels)
+	if err != nil {
+		return pr, nil, err
+	}
+
 	// проверяем что PR не существует
-	if _, err := s.storage.GetPullRequest(req.PullRequestID); err == nil {
+	if _, err := st.GetPullRequest(req.PullRequestID); err == nil {
 		if s.logger != nil {
 			s.logger.Warn("PR уже существует", slog.String("pr_id", req.PullRequestID))
 		}
-		return nil, errWithCode(models.ErrorCodePRExists, "PR id already exists")
+		return pr, nil, errWithCode(models.ErrorCodePRExists, "PR id already exists")
+	}
+	// ID архивных PR тоже заняты: иначе повторный перенос в архив упрётся в дубликат
+	archived, err := st.PullRequestArchived(req.PullRequestID)
+	if err != nil {
+		return pr, nil, err
+	}
+	if archived {
+		if s.logger != nil {
+			s.logger.Warn("PR уже в архиве", slog.String("pr_id", req.PullRequestID))
+		}
+		return pr, nil, errWithCode(models.ErrorCodePRExists, "PR id already exists in archive")
 	}
 
-	author, err := s.storage.GetUser(req.AuthorID)
+	author, err := st.GetUser(req.AuthorID)
 	if err != nil {
 		if s.logger != nil {
 			s.logger.Warn("автор не найден", slog.String("author", req.AuthorID), slog.Any("err", err))
 		}
-		return nil, errWithCode(models.ErrorCodeNotFound, "author not found")
+		return pr, nil, errWithCode(models.ErrorCodeNotFound, "author not found")
 	}
 
-	team, err := s.storage.GetTeam(author.TeamName)
+	// команда PR: указанная в запросе (автор должен в ней состоять) или основная команда автора
+	teamName := author.TeamName
+	if req.TeamName != "" {
+		if !containsString(author.Teams, req.TeamName) {
+			if s.logger != nil {
+				s.logger.Warn("автор не состоит в команде", slog.String("author", req.AuthorID), slog.String("team", req.TeamName))
+			}
+			return pr, nil, errWithCode(models.ErrorCodeValidation, "author is not a member of team_name")
+		}
+		teamName = req.TeamName
+	}
+
+	team, err := st.GetTeam(teamName)
 	if err != nil {
 		if s.logger != nil {
-			s.logger.Warn("команда автора не найдена", slog.String("team", author.TeamName), slog.Any("err", err))
+			s.logger.Warn("команда автора не найдена", slog.String("team", teamName), slog.Any("err", err))
 		}
-		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
+		return pr, nil, errWithCode(models.ErrorCodeNotFound, "team not found")
 	}
 
-	// собираем активных кандидатов (исключая автора)
-	candidates := []models.TeamMember{}
-	for _, m := range team.Members {
-		if !m.IsActive {
-			continue
+	policy, err := resolvePolicy(st, teamName)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось получить настройки команды", slog.String("team", teamName), slog.Any("err", err))
 		}
-		if m.UserID == author.UserID {
-			continue
+		return pr, nil, fmt.Errorf("failed resolve team policy: %w", err)
+	}
+
+	// собираем активных кандидатов (исключая автора и достигших лимита открытых ревью)
+	candidates, excluded, err := eligibleCandidates(st, team.Members, policy, map[string]string{
+		author.UserID: models.ExclusionReasonAuthor,
+	})
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось собрать кандидатов", slog.String("team", teamName), slog.Any("err", err))
 		}
-		candidates = append(candidates, m)
+		return pr, nil, fmt.Errorf("failed collect candidates: %w", err)
 	}
 
 	if s.logger != nil {
 		s.logger.Debug("кандидаты собраны", slog.Int("count", len(candidates)))
 	}
 
-	assigned := []string{}
-	// перемешиваем и выбираем до 2 человек
-	if len(candidates) > 0 {
-		// Fisher–Yates shuffle
-		for i := len(candidates) - 1; i > 0; i-- {
-			j := s.rnd.Intn(i + 1)
-			candidates[i], candidates[j] = candidates[j], candidates[i]
-		}
-		limit := 2
-		if len(candidates) < 2 {
-			limit = len(candidates)
-		}
-		for i := 0; i < limit; i++ {
-			assigned = append(assigned, candidates[i].UserID)
+	// выбираем ревьюверов (по умолчанию до 2) с вероятностью, пропорциональной весу
+	strategy := models.AssignmentStrategyWeightedRandom
+	if loadAware {
+		if candidates, err = weightByLoad(st, candidates); err != nil {
+			return pr, nil, fmt.Errorf("failed count open reviews: %w", err)
 		}
+		strategy = models.AssignmentStrategyLoadAware
 	}
+	assigned, explanation := s.chooseReviewers(candidates, excluded, policy.ReviewerCount)
+	explanation.Strategy = strategy
 
 	if s.logger != nil {
 		s.logger.Info("рецензенты назначены", slog.String("pr_id", req.PullRequestID), slog.Any("assigned", assigned))
 	}
 
-	now := time.Now().UTC()
-	pr := models.PullRequest{
+	now := s.now().UTC()
+	pr = models.PullRequest{
 		PullRequestID:     req.PullRequestID,
 		PullRequestName:   req.PullRequestName,
 		AuthorID:          req.AuthorID,
+		TeamName:          teamName,
 		Status:            models.PRStatusOpen,
 		AssignedReviewers: assigned,
+		Labels:            labels,
+		Version:           1,
 		CreatedAt:         now,
 	}
 
 	// создаём запись PR
-	if err := s.storage.CreatePullRequest(pr); err != nil {
+	if err := st.CreatePullRequest(pr); err != nil {
 		if s.logger != nil {
 			s.logger.Error("не удалось создать PR", slog.String("pr_id", pr.PullRequestID), slog.Any("err", err))
 		}
-		return nil, fmt.Errorf("failed create pr: %w", err)
+		return pr, nil, fmt.Errorf("failed create pr: %w", err)
 	}
 
 	// добавляем рецензентов
 	for _, reviewerID := range assigned {
-		if err := s.storage.AssignReviewer(pr.PullRequestID, reviewerID); err != nil {
+		if err := st.AssignReviewer(pr.PullRequestID, reviewerID); err != nil {
 			if s.logger != nil {
 				s.logger.Error("не удалось назначить рецензента", slog.String("pr_id", pr.PullRequestID), slog.String("reviewer", reviewerID), slog.Any("err", err))
 			}
-			return nil, fmt.Errorf("failed assign reviewer %s: %w", reviewerID, err)
+			return pr, nil, fmt.Errorf("failed assign reviewer %s: %w", reviewerID, err)
 		}
 	}
-
-	if s.logger != nil {
-		s.logger.Info("PR создан", slog.String("pr_id", pr.PullRequestID))
-	}
-	return &models.PullRequestResponse{PR: pr}, nil
+	return pr, explanation, nil
 }
 
-// MergePullRequest объединяет pull request (меняет статус на MERGED)
-func (s *Service) MergePullRequest(prID string) (*models.PullRequestResponse, error) {
+// MergePullRequest объединяет pull request (меняет статус на MERGED).
+// PR должен набрать required_approvals одобрений из итоговых настроек его команды.
+func (s *Service) MergePullRequest(prID string, ifMatch int64) (*models.PullRequestResponse, error) {
 	if s.logger != nil {
 		s.logger.Info("MergePullRequest вызван", slog.String("pr_id", prID))
 	}
+	return s.mergePullRequest(prID, ifMatch, true)
+}
+
+// mergePullRequest объединяет pull request; при checkApprovals проверяет число одобрений
+func (s *Service) mergePullRequest(prID string, ifMatch int64, checkApprovals bool) (*models.PullRequestResponse, error) {
 
 	pr, err := s.storage.GetPullRequest(prID)
 	if err != nil {
@@ -249,6 +545,9 @@ func (s *Service) MergePullRequest(prID string) (*models.PullRequestResponse, er
 		}
 		return nil, errWithCode(models.ErrorCodeNotFound, "pr not found")
 	}
+	if ifMatch > 0 && pr.Version != ifMatch {
+		return nil, errWithCode(models.ErrorCodePrecondition, "pr version does not match If-Match")
+	}
 
 	// идемпотентность: если уже объединён, возвращаем текущее состояние
 	if pr.Status == models.PRStatusMerged {
@@ -257,10 +556,36 @@ func (s *Service) MergePullRequest(prID string) (*models.PullRequestResponse, er
 		}
 		return &models.PullRequestResponse{PR: pr}, nil
 	}
+	if pr.Status == models.PRStatusClosed {
+		return nil, errWithCode(models.ErrorCodePRClosed, "cannot merge closed PR, reopen it first")
+	}
 
 	pr.Status = models.PRStatusMerged
-	pr.MergedAt = time.Now().UTC()
-	if err := s.storage.UpdatePullRequest(pr); err != nil {
+	pr.MergedAt = s.now().UTC()
+	err = s.storage.WithTx(func(tx *repository.Storage) error {
+		if checkApprovals {
+			if err := s.checkApprovals(tx, pr); err != nil {
+				return err
+			}
+		}
+		if err := tx.UpdatePullRequest(&pr); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvents(s.newEvent(models.EventPRMerged, models.PullRequestEventData{PR: pr}))
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("PR не может быть объединён", slog.String("pr_id", prID), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if s.logger != nil {
+				s.logger.Warn("PR изменён параллельно при мерже", slog.String("pr_id", prID))
+			}
+			return nil, errWithCode(models.ErrorCodeVersionConflict, "pr was modified concurrently, retry")
+		}
 		if s.logger != nil {
 			s.logger.Error("не удалось обновить PR", slog.String("pr_id", prID), slog.Any("err", err))
 		}
@@ -273,8 +598,41 @@ func (s *Service) MergePullRequest(prID string) (*models.PullRequestResponse, er
 	return &models.PullRequestResponse{PR: pr}, nil
 }
 
-// ReassignReviewer заменяет одного рецензента на случайного активного из его команды
-func (s *Service) ReassignReviewer(prID, oldUserID string) (*models.PullRequest, string, error) {
+// checkApprovals проверяет, что PR набрал required_approvals одобрений
+// из итоговых настроек команды PR (для PR без команды — основной команды автора)
+func (s *Service) checkApprovals(tx *repository.Storage, pr models.PullRequest) error {
+	teamName := pr.TeamName
+	if teamName == "" {
+		author, err := tx.GetUser(pr.AuthorID)
+		if err != nil {
+			return err
+		}
+		teamName = author.TeamName
+	}
+	if teamName == "" {
+		return nil
+	}
+	policy, err := resolvePolicy(tx, teamName)
+	if err != nil {
+		return err
+	}
+	if policy.RequiredApprovals <= 0 {
+		return nil
+	}
+	approvals, err := tx.CountApprovals(pr.PullRequestID)
+	if err != nil {
+		return err
+	}
+	if approvals < policy.RequiredApprovals {
+		return errWithCode(models.ErrorCodeNotApproved,
+			fmt.Sprintf("pr has %d of %d required approvals", approvals, policy.RequiredApprovals))
+	}
+	return nil
+}
+
+// ReassignReviewer заменяет одного рецензента на случайного (с учётом веса) активного участника команды PR.
+// При explain ответ содержит объяснение выбора нового ревьювера.
+func (s *Service) ReassignReviewer(prID, oldUserID string, explain bool, ifMatch int64) (*models.ReassignPullRequestResponse, error) {
 	if s.logger != nil {
 		s.logger.Info("ReassignReviewer вызван", slog.String("pr_id", prID), slog.String("old_reviewer", oldUserID))
 	}
@@ -284,14 +642,20 @@ func (s *Service) ReassignReviewer(prID, oldUserID string) (*models.PullRequest,
 		if s.logger != nil {
 			s.logger.Warn("не удалось получить PR", slog.String("pr_id", prID), slog.Any("err", err))
 		}
-		return nil, "", errWithCode(models.ErrorCodeNotFound, "pr not found")
+		return nil, errWithCode(models.ErrorCodeNotFound, "pr not found")
+	}
+	if ifMatch > 0 && pr.Version != ifMatch {
+		return nil, errWithCode(models.ErrorCodePrecondition, "pr version does not match If-Match")
 	}
 
 	if pr.Status == models.PRStatusMerged {
 		if s.logger != nil {
 			s.logger.Warn("не можно переназначить уже объединённый PR", slog.String("pr_id", prID))
 		}
-		return nil, "", errWithCode(models.ErrorCodePRMerged, "cannot reassign on merged PR")
+		return nil, errWithCode(models.ErrorCodePRMerged, "cannot reassign on merged PR")
+	}
+	if pr.Status == models.PRStatusClosed {
+		return nil, errWithCode(models.ErrorCodePRClosed, "cannot reassign on closed PR")
 	}
 
 	// проверяем что oldUserID назначен рецензентом
@@ -306,79 +670,220 @@ func (s *Service) ReassignReviewer(prID, oldUserID string) (*models.PullRequest,
 		if s.logger != nil {
 			s.logger.Warn("рецензент не назначен на PR", slog.String("pr_id", prID), slog.String("reviewer", oldUserID))
 		}
-		return nil, "", errWithCode(models.ErrorCodeNotAssigned, "reviewer is not assigned to this PR")
+		return nil, errWithCode(models.ErrorCodeNotAssigned, "reviewer is not assigned to this PR")
 	}
 
-	// находим команду рецензента
+	// кандидаты берутся из команды PR, а для PR без команды — из основной команды рецензента
 	oldUser, err := s.storage.GetUser(oldUserID)
 	if err != nil {
 		if s.logger != nil {
 			s.logger.Warn("пользователь не найден", slog.String("user_id", oldUserID), slog.Any("err", err))
 		}
-		return nil, "", errWithCode(models.ErrorCodeNotFound, "user not found")
+		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
+	}
+	teamName := pr.TeamName
+	if teamName == "" {
+		teamName = oldUser.TeamName
 	}
 
-	team, err := s.storage.GetTeam(oldUser.TeamName)
+	team, err := s.storage.GetTeam(teamName)
 	if err != nil {
 		if s.logger != nil {
-			s.logger.Warn("команда не найдена", slog.String("team", oldUser.TeamName), slog.Any("err", err))
+			s.logger.Warn("команда не найдена", slog.String("team", teamName), slog.Any("err", err))
 		}
-		return nil, "", errWithCode(models.ErrorCodeNotFound, "team not found")
+		return nil, errWithCode(models.ErrorCodeNotFound, "team not found")
 	}
 
 	// кандидаты: активные члены команды кроме текущих рецензентов и автора
-	candidates := []models.TeamMember{}
-	exclude := map[string]struct{}{oldUserID: {}}
+	exclude := map[string]string{}
 	for _, rid := range pr.AssignedReviewers {
-		exclude[rid] = struct{}{}
+		exclude[rid] = models.ExclusionReasonAlreadyAssigned
 	}
-	exclude[pr.AuthorID] = struct{}{}
-
-	for _, m := range team.Members {
-		if !m.IsActive {
-			continue
+	exclude[pr.AuthorID] = models.ExclusionReasonAuthor
+	policy, err := resolvePolicy(s.storage, teamName)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось получить настройки команды", slog.String("team", teamName), slog.Any("err", err))
 		}
-		if _, ex := exclude[m.UserID]; ex {
-			continue
+		return nil, fmt.Errorf("failed resolve team policy: %w", err)
+	}
+	candidates, excluded, err := eligibleCandidates(s.storage, team.Members, policy, exclude)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось собрать кандидатов", slog.String("team", teamName), slog.Any("err", err))
 		}
-		candidates = append(candidates, m)
+		return nil, fmt.Errorf("failed collect candidates: %w", err)
 	}
 
 	if len(candidates) == 0 {
 		if s.logger != nil {
 			s.logger.Warn("нет подходящего замены для рецензента", slog.String("pr_id", prID))
 		}
-		return nil, "", errWithCode(models.ErrorCodeNoCandidate, "no active replacement candidate in team")
+		return nil, errWithCode(models.ErrorCodeNoCandidate, "no active replacement candidate in team")
 	}
 
-	// выбираем случайного кандидата
-	idx := s.rnd.Intn(len(candidates))
-	newReviewer := candidates[idx].UserID
+	// выбираем случайного кандидата с учётом веса
+	chosen, explanation := s.chooseReviewers(candidates, excluded, 1)
+	newReviewer := chosen[0]
 
 	// заменяем в памяти
 	pr.AssignedReviewers[found] = newReviewer
 
-	// сохраняем в БД
-	if err := s.storage.UpdatePullRequest(pr); err != nil {
+	// сохраняем в БД вместе с событием, если PR не изменился с момента чтения
+	err = s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := tx.UpdatePullRequest(&pr); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvents(s.newEvent(models.EventReviewerReassigned,
+			models.ReviewerEventData{PullRequestID: prID, UserID: newReviewer, OldUserID: oldUserID}))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if s.logger != nil {
+				s.logger.Warn("PR изменён параллельно при переназначении", slog.String("pr_id", prID))
+			}
+			return nil, errWithCode(models.ErrorCodeVersionConflict, "pr was modified concurrently, retry")
+		}
 		if s.logger != nil {
 			s.logger.Error("не удалось обновить PR при переназначении", slog.String("pr_id", prID), slog.Any("err", err))
 		}
-		return nil, "", fmt.Errorf("failed update pr: %w", err)
+		return nil, fmt.Errorf("failed update pr: %w", err)
 	}
 
 	if s.logger != nil {
 		s.logger.Info("рецензент переназначен", slog.String("pr_id", prID), slog.String("new_reviewer", newReviewer))
 	}
-	return &pr, newReviewer, nil
+	resp := &models.ReassignPullRequestResponse{
+		PR:         pr,
+		ReplacedBy: newReviewer,
+	}
+	if explain || s.explain {
+		resp.Explain = explanation
+	}
+	return resp, nil
+}
+
+// GetPullRequest получает pull request со списком рецензентов
+func (s *Service) GetPullRequest(prID string) (*models.PullRequestResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetPullRequest вызван", slog.String("pr_id", prID))
+	}
+	pr, err := s.storage.GetPullRequest(prID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("PR не найден", slog.String("pr_id", prID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "pr not found")
+	}
+	return &models.PullRequestResponse{PR: pr}, nil
 }
 
-// GetReviewPRs получает список PR'ов, на которых пользователь назначен рецензентом
-func (s *Service) GetReviewPRs(userID string) (*models.UserReviewResponse, error) {
+// ListPullRequests получает страницу pull request'ов по фильтру.
+// По умолчанию сортирует по created_at по убыванию.
+func (s *Service) ListPullRequests(filter models.PullRequestFilter) (*models.PullRequestListResponse, error) {
 	if s.logger != nil {
-		s.logger.Info("GetReviewPRs вызван", slog.String("user_id", userID))
+		s.logger.Info("ListPullRequests вызван", slog.String("status", string(filter.Status)),
+			slog.String("author_id", filter.AuthorID), slog.String("reviewer_id", filter.ReviewerID),
+			slog.String("team_name", filter.TeamName))
+	}
+	switch filter.Status {
+	case "", models.PRStatusOpen, models.PRStatusMerged, models.PRStatusClosed:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown status")
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.PRSortCreatedAt
+	case models.PRSortCreatedAt, models.PRSortMergedAt, models.PRSortID:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown sort")
+	}
+	switch filter.Order {
+	case "":
+		filter.Order = models.SortOrderDesc
+	case models.SortOrderAsc, models.SortOrderDesc:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "order must be asc or desc")
+	}
+	limit, err := pageLimit(filter.Limit)
+	if err != nil {
+		return nil, err
 	}
-	prs, err := s.storage.ListPRsByReviewer(userID)
+	filter.Limit = limit
+
+	prs, next, err := s.storage.ListPullRequests(filter)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось получить список PR", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed list pull requests: %w", err)
+	}
+	return &models.PullRequestListResponse{PullRequests: prs, NextCursor: next}, nil
+}
+
+// pageLimit проверяет размер страницы, подставляя размер по умолчанию
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return models.DefaultPageLimit, nil
+	}
+	if limit < 0 || limit > models.MaxPageLimit {
+		return 0, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("limit must be between 1 and %d", models.MaxPageLimit))
+	}
+	return limit, nil
+}
+
+// GetReviewPRs получает страницу очереди ревью пользователя: PR, на которых он назначен
+// рецензентом, с фильтрами по статусу и минимальному возрасту.
+// По умолчанию сортирует по pull_request_id по возрастанию.
+func (s *Service) GetReviewPRs(userID string, filter models.ReviewQueueFilter) (*models.UserReviewResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetReviewPRs вызван", slog.String("user_id", userID), slog.String("status", string(filter.Status)))
+	}
+	switch filter.Status {
+	case "", models.PRStatusOpen, models.PRStatusMerged, models.PRStatusClosed:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown status")
+	}
+	if filter.MinAge < 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "min_age must not be negative")
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.PRSortID
+	case models.PRSortCreatedAt, models.PRSortID:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "unknown sort")
+	}
+	switch filter.Order {
+	case "":
+		filter.Order = models.SortOrderAsc
+	case models.SortOrderAsc, models.SortOrderDesc:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "order must be asc or desc")
+	}
+	limit, err := pageLimit(filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.PullRequestFilter{
+		Status: filter.Status,
+		Sort:   filter.Sort,
+		Order:  filter.Order,
+		Limit:  limit,
+		Cursor: filter.Cursor,
+	}
+	if filter.MinAge > 0 {
+		query.CreatedTo = s.now().Add(-filter.MinAge)
+	}
+	prs, next, err := s.storage.ListPRsByReviewer(userID, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
 		if s.logger != nil {
 			s.logger.Warn("не удалось получить PR'ы рецензента", slog.String("user_id", userID), slog.Any("err", err))
 		}
@@ -387,20 +892,29 @@ func (s *Service) GetReviewPRs(userID string) (*models.UserReviewResponse, error
 	return &models.UserReviewResponse{
 		UserID:       userID,
 		PullRequests: prs,
+		NextCursor:   next,
 	}, nil
 }
 
-// GetUserStats возвращает статистику по назначениям для пользователей
-func (s *Service) GetUserStats() (*models.StatsUserResponse, error) {
+// GetUserStats возвращает страницу статистики по назначениям для пользователей,
+// упорядоченных по user_id
+func (s *Service) GetUserStats(page models.PageRequest) (*models.StatsUserResponse, error) {
 	if s.logger != nil {
 		s.logger.Info("GetUserStats called")
 	}
-	stats, err := s.storage.ListUserReviewCounts()
+	limit, err := pageLimit(page.Limit)
 	if err != nil {
+		return nil, err
+	}
+	stats, next, err := s.storage.ListUserReviewCounts(limit, page.Cursor)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
 		if s.logger != nil {
 			s.logger.Error("failed to get user stats", slog.Any("err", err))
 		}
 		return nil, fmt.Errorf("failed to get user stats: %w", err)
 	}
-	return &models.StatsUserResponse{Stats: stats}, nil
+	return &models.StatsUserResponse{Stats: stats, NextCursor: next}, nil
 }
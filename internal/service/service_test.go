@@ -0,0 +1,151 @@
+package service
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func newTestService() *Service {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return NewService(nil, nil,
+		WithRandSource(rand.NewSource(1)),
+		WithClock(func() time.Time { return now }))
+}
+
+func member(id string, active bool) models.TeamMember {
+	return models.TeamMember{UserID: id, Username: id, IsActive: active, ReviewWeight: models.DefaultReviewWeight}
+}
+
+func TestCreatePullRequestSelectsEligibleReviewers(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("org", "")
+	st.addTeam("backend", "org",
+		member("author", true),
+		member("inactive", false),
+		member("busy", true),
+		member("r1", true),
+		member("r2", true),
+		member("r3", true),
+	)
+	st.policies["org"] = models.TeamPolicy{ReviewerCount: intPtr(3), MaxOpenReviews: intPtr(1)}
+	st.open["busy"] = 1
+
+	s := newTestService()
+	pr, explanation, err := s.createPullRequest(st, &models.CreatePullRequestRequest{
+		PullRequestID: "pr-1", PullRequestName: "Add feature", AuthorID: "author",
+	}, false)
+	if err != nil {
+		t.Fatalf("createPullRequest: %v", err)
+	}
+
+	got := append([]string(nil), pr.AssignedReviewers...)
+	sort.Strings(got)
+	if want := []string{"r1", "r2", "r3"}; !equalStrings(got, want) {
+		t.Errorf("assigned %v, want %v", got, want)
+	}
+	if stored := st.prs["pr-1"]; len(stored.AssignedReviewers) != 3 || stored.Status != models.PRStatusOpen {
+		t.Errorf("stored pr %+v", stored)
+	}
+
+	reasons := map[string]string{}
+	for _, ex := range explanation.Excluded {
+		reasons[ex.UserID] = ex.Reason
+	}
+	want := map[string]string{
+		"author":   models.ExclusionReasonAuthor,
+		"inactive": models.ExclusionReasonInactive,
+		"busy":     models.ExclusionReasonAtCapacity,
+	}
+	for id, reason := range want {
+		if reasons[id] != reason {
+			t.Errorf("%s excluded as %q, want %q", id, reasons[id], reason)
+		}
+	}
+}
+
+func TestCreatePullRequestRejectsExistingID(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("backend", "", member("author", true), member("r1", true))
+	st.prs["open"] = models.PullRequest{PullRequestID: "open"}
+	st.archived["old"] = true
+
+	s := newTestService()
+	for _, id := range []string{"open", "old"} {
+		_, _, err := s.createPullRequest(st, &models.CreatePullRequestRequest{PullRequestID: id, PullRequestName: id, AuthorID: "author"}, false)
+		if code := ParseCodeFromError(err); code != models.ErrorCodePRExists {
+			t.Errorf("%s: code %q, want %q", id, code, models.ErrorCodePRExists)
+		}
+	}
+}
+
+func TestCreatePullRequestRequiresAuthorTeam(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("backend", "", member("author", true), member("r1", true))
+	st.addTeam("frontend", "", member("f1", true))
+
+	s := newTestService()
+	_, _, err := s.createPullRequest(st, &models.CreatePullRequestRequest{
+		PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author", TeamName: "frontend",
+	}, false)
+	if code := ParseCodeFromError(err); code != models.ErrorCodeValidation {
+		t.Errorf("code %q, want %q", code, models.ErrorCodeValidation)
+	}
+	if _, ok := st.prs["pr-1"]; ok {
+		t.Error("pr created despite validation error")
+	}
+}
+
+func TestCreatePullRequestLoadAware(t *testing.T) {
+	st := newMemStore()
+	st.addTeam("backend", "", member("author", true), member("r1", true), member("r2", true))
+
+	s := newTestService()
+	_, explanation, err := s.createPullRequest(st, &models.CreatePullRequestRequest{
+		PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author",
+	}, true)
+	if err != nil {
+		t.Fatalf("createPullRequest: %v", err)
+	}
+	if explanation.Strategy != models.AssignmentStrategyLoadAware {
+		t.Errorf("strategy %q, want %q", explanation.Strategy, models.AssignmentStrategyLoadAware)
+	}
+}
+
+func TestChooseReviewersConcurrent(t *testing.T) {
+	s := newTestService()
+	candidates := []models.TeamMember{member("u1", true), member("u2", true), member("u3", true)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if chosen, _ := s.chooseReviewers(candidates, nil, 2); len(chosen) != 2 {
+					t.Errorf("chosen %v, want 2 reviewers", chosen)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// reviewerStore операции хранилища, нужные для выбора ревьюверов и создания PR.
+// *repository.Storage (и транзакция WithTx) удовлетворяет интерфейсу, а в тестах
+// его заменяет хранилище в памяти.
+type reviewerStore interface {
+	GetPullRequest(prID string) (models.PullRequest, error)
+	PullRequestArchived(prID string) (bool, error)
+	CreatePullRequest(pr models.PullRequest) error
+	AssignReviewer(prID, userID string) error
+	GetUser(userID string) (models.User, error)
+	GetTeam(teamName string) (models.Team, error)
+	GetTeamParent(teamName string) (string, error)
+	GetTeamPolicy(teamName string) (models.TeamPolicy, error)
+	GetTeamOrganization(teamName string) (string, error)
+	GetOrganizationPolicy(orgName string) (models.TeamPolicy, error)
+	ListOpenReviewCounts(userIDs []string) (map[string]int, error)
+}
+
+var _ reviewerStore = (*repository.Storage)(nil)
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+
+	"pr-review-manager/internal/models"
+)
+
+// memStore хранилище в памяти для тестов логики выбора ревьюверов
+type memStore struct {
+	prs      map[string]models.PullRequest
+	archived map[string]bool
+	users    map[string]models.User
+	teams    map[string]models.Team
+	parents  map[string]string
+	policies map[string]models.TeamPolicy
+	orgs     map[string]string
+	orgRules map[string]models.TeamPolicy
+	open     map[string]int
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		prs:      map[string]models.PullRequest{},
+		archived: map[string]bool{},
+		users:    map[string]models.User{},
+		teams:    map[string]models.Team{},
+		parents:  map[string]string{},
+		policies: map[string]models.TeamPolicy{},
+		orgs:     map[string]string{},
+		orgRules: map[string]models.TeamPolicy{},
+		open:     map[string]int{},
+	}
+}
+
+// addTeam добавляет команду и её участников; основной командой участника
+// становится первая команда, в которую он добавлен
+func (m *memStore) addTeam(name, parent string, members ...models.TeamMember) {
+	m.teams[name] = models.Team{TeamName: name, ParentTeam: parent, Members: members}
+	m.parents[name] = parent
+	for _, tm := range members {
+		u, ok := m.users[tm.UserID]
+		if !ok {
+			u = models.User{UserID: tm.UserID, Username: tm.Username, TeamName: name, IsActive: tm.IsActive, ReviewWeight: tm.ReviewWeight}
+		}
+		u.Teams = append(u.Teams, name)
+		m.users[tm.UserID] = u
+	}
+}
+
+func (m *memStore) GetPullRequest(prID string) (models.PullRequest, error) {
+	pr, ok := m.prs[prID]
+	if !ok {
+		return pr, fmt.Errorf("pr not found: %w", sql.ErrNoRows)
+	}
+	return pr, nil
+}
+
+func (m *memStore) PullRequestArchived(prID string) (bool, error) {
+	return m.archived[prID], nil
+}
+
+func (m *memStore) CreatePullRequest(pr models.PullRequest) error {
+	pr.AssignedReviewers = nil
+	m.prs[pr.PullRequestID] = pr
+	return nil
+}
+
+func (m *memStore) AssignReviewer(prID, userID string) error {
+	pr := m.prs[prID]
+	pr.AssignedReviewers = append(pr.AssignedReviewers, userID)
+	m.prs[prID] = pr
+	m.open[userID]++
+	return nil
+}
+
+func (m *memStore) GetUser(userID string) (models.User, error) {
+	u, ok := m.users[userID]
+	if !ok {
+		return u, fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	return u, nil
+}
+
+func (m *memStore) GetTeam(teamName string) (models.Team, error) {
+	t, ok := m.teams[teamName]
+	if !ok {
+		return t, fmt.Errorf("team not found: %w", sql.ErrNoRows)
+	}
+	return t, nil
+}
+
+func (m *memStore) GetTeamParent(teamName string) (string, error) {
+	parent, ok := m.parents[teamName]
+	if !ok {
+		return "", fmt.Errorf("team not found: %w", sql.ErrNoRows)
+	}
+	return parent, nil
+}
+
+func (m *memStore) GetTeamPolicy(teamName string) (models.TeamPolicy, error) {
+	return m.policies[teamName], nil
+}
+
+func (m *memStore) GetTeamOrganization(teamName string) (string, error) {
+	return m.orgs[teamName], nil
+}
+
+func (m *memStore) GetOrganizationPolicy(orgName string) (models.TeamPolicy, error) {
+	return m.orgRules[orgName], nil
+}
+
+func (m *memStore) ListOpenReviewCounts(userIDs []string) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, id := range userIDs {
+		if n := m.open[id]; n > 0 {
+			counts[id] = n
+		}
+	}
+	return counts, nil
+}
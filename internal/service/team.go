@@ -0,0 +1,478 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// Правила изменения состава команд:
+//   - пользователь может состоять в нескольких командах, одна из них основная;
+//   - добавление в команду не меняет данные существующего пользователя
+//     (username, is_active, review_weight), они меняются только через UpdateUser;
+//   - участник, покидающий команду, остаётся в остальных своих командах
+//     (основной становится одна из них) или в системе без команды;
+//   - его назначения на OPEN PR этой команды снимаются и по возможности передаются
+//     активному участнику той же команды (не автору и не уже назначенному);
+//   - при удалении команды замену искать не среди кого, назначения просто снимаются;
+//   - PR, где пользователь автор, и история MERGED PR не меняются.
+
+// UpdateTeam переименовывает команду. ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) UpdateTeam(req *models.UpdateTeamRequest, ifMatch int64) (*models.TeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("UpdateTeam вызван", slog.String("team_name", req.TeamName), slog.String("new_team_name", req.NewTeamName))
+	}
+	if req.NewTeamName == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "new_team_name is required")
+	}
+
+	var team models.Team
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		if err := tx.RenameTeam(req.TeamName, req.NewTeamName); err != nil {
+			if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "23505") {
+				return errWithCode(models.ErrorCodeTeamExists, "team_name already exists")
+			}
+			return err
+		}
+		t, err := tx.GetTeam(req.NewTeamName)
+		if err != nil {
+			return err
+		}
+		team = t
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось переименовать команду", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось переименовать команду", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed rename team: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("команда переименована", slog.String("team_name", req.TeamName), slog.String("new_team_name", req.NewTeamName))
+	}
+	return &models.TeamResponse{Team: team}, nil
+}
+
+// AddTeamMember добавляет пользователя в команду (создаёт его при необходимости).
+// Данные из запроса (username, is_active, review_weight) используются только при создании
+// пользователя: существующий пользователь лишь получает членство, а его данные меняются
+// через UpdateUser, где учитываются побочные эффекты деактивации. Основной командой
+// становится эта команда, если у пользователя её ещё нет.
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) AddTeamMember(req *models.AddTeamMemberRequest, ifMatch int64) (*models.TeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("AddTeamMember вызван", slog.String("team_name", req.TeamName), slog.String("user_id", req.Member.UserID))
+	}
+	if req.Member.UserID == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "member.user_id is required")
+	}
+	if req.Member.ReviewWeight < 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "review_weight must be positive")
+	}
+	if req.Member.ReviewWeight == 0 {
+		req.Member.ReviewWeight = models.DefaultReviewWeight
+	}
+
+	var team models.Team
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		existing, err := tx.GetUser(req.Member.UserID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			if existing.TeamName == "" {
+				existing.TeamName = req.TeamName
+				if err := tx.UpdateUser(existing); err != nil {
+					return err
+				}
+			} else if err := tx.AddMembership(req.TeamName, existing.UserID); err != nil {
+				return err
+			}
+			t, err := tx.GetTeam(req.TeamName)
+			if err != nil {
+				return err
+			}
+			team = t
+			return nil
+		}
+
+		u := models.User{
+			UserID:       req.Member.UserID,
+			Username:     req.Member.Username,
+			TeamName:     req.TeamName,
+			IsActive:     req.Member.IsActive,
+			ReviewWeight: req.Member.ReviewWeight,
+		}
+		if err := tx.UpsertUser(u); err != nil {
+			return err
+		}
+		t, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		team = t
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось добавить участника", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось добавить участника", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed add team member: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("участник добавлен", slog.String("team_name", req.TeamName), slog.String("user_id", req.Member.UserID))
+	}
+	return &models.TeamResponse{Team: team}, nil
+}
+
+// RemoveTeamMember исключает пользователя из команды и передаёт его открытые ревью другим участникам.
+// ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) RemoveTeamMember(req *models.RemoveTeamMemberRequest, ifMatch int64) (*models.TeamMembershipResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("RemoveTeamMember вызван", slog.String("team_name", req.TeamName), slog.String("user_id", req.UserID))
+	}
+
+	var resp *models.TeamMembershipResponse
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		team, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		u, err := tx.GetUser(req.UserID)
+		if err != nil || !containsString(u.Teams, req.TeamName) {
+			return errWithCode(models.ErrorCodeNotFound, "user is not a member of this team")
+		}
+
+		if err := tx.RemoveMembership(req.TeamName, req.UserID); err != nil {
+			return err
+		}
+		if team.Version, err = tx.LockTeamVersion(req.TeamName); err != nil {
+			return err
+		}
+		if u.TeamName == req.TeamName {
+			if err := s.promotePrimaryTeam(tx, u); err != nil {
+				return err
+			}
+		}
+
+		remaining := []models.TeamMember{}
+		for _, m := range team.Members {
+			if m.UserID != req.UserID {
+				remaining = append(remaining, m)
+			}
+		}
+		team.Members = remaining
+
+		released, err := s.releaseOpenReviews(tx, req.UserID, req.TeamName, &team)
+		if err != nil {
+			return err
+		}
+		resp = &models.TeamMembershipResponse{Team: team, ReleasedReviews: released}
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось исключить участника", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось исключить участника", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed remove team member: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("участник исключён", slog.String("team_name", req.TeamName), slog.String("user_id", req.UserID), slog.Int("released", len(resp.ReleasedReviews)))
+	}
+	return resp, nil
+}
+
+// DeleteTeam удаляет команду; участники остаются в других своих командах или без команды,
+// их открытые ревью на PR этой команды снимаются. ifMatch — ожидаемая версия команды (0 — без проверки).
+func (s *Service) DeleteTeam(req *models.DeleteTeamRequest, ifMatch int64) (*models.DeleteTeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("DeleteTeam вызван", slog.String("team_name", req.TeamName))
+	}
+
+	resp := &models.DeleteTeamResponse{
+		TeamName:        req.TeamName,
+		DetachedUsers:   []string{},
+		ReleasedReviews: []models.ReviewMove{},
+	}
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := checkTeamVersion(tx, req.TeamName, ifMatch); err != nil {
+			return err
+		}
+		team, err := tx.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		for _, m := range team.Members {
+			released, err := s.releaseOpenReviews(tx, m.UserID, req.TeamName, nil)
+			if err != nil {
+				return err
+			}
+			resp.DetachedUsers = append(resp.DetachedUsers, m.UserID)
+			resp.ReleasedReviews = append(resp.ReleasedReviews, released...)
+		}
+		// users.team_name обнуляется внешним ключом ON DELETE SET NULL,
+		// членства удаляются каскадно
+		if err := tx.DeleteTeam(req.TeamName); err != nil {
+			return err
+		}
+		for _, m := range team.Members {
+			u, err := tx.GetUser(m.UserID)
+			if err != nil {
+				return err
+			}
+			if u.TeamName == "" {
+				if err := s.promotePrimaryTeam(tx, u); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось удалить команду", slog.String("team_name", req.TeamName), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось удалить команду", slog.String("team_name", req.TeamName), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed delete team: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("команда удалена", slog.String("team_name", req.TeamName), slog.Int("detached", len(resp.DetachedUsers)))
+	}
+	return resp, nil
+}
+
+// MoveUserTeam переводит пользователя из основной команды в другую. При reassign_reviews
+// его назначения на OPEN PR прежней команды передаются её участникам.
+func (s *Service) MoveUserTeam(req *models.MoveUserTeamRequest) (*models.MoveUserTeamResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("MoveUserTeam вызван", slog.String("user_id", req.UserID), slog.String("team_name", req.TeamName), slog.Bool("reassign_reviews", req.ReassignReviews))
+	}
+
+	resp := &models.MoveUserTeamResponse{ReleasedReviews: []models.ReviewMove{}}
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		u, err := tx.GetUser(req.UserID)
+		if err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "user not found")
+		}
+		if _, err := tx.GetTeam(req.TeamName); err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "team not found")
+		}
+		oldTeamName := u.TeamName
+		if oldTeamName == req.TeamName {
+			resp.User = u
+			return nil
+		}
+
+		if oldTeamName != "" {
+			if err := tx.RemoveMembership(oldTeamName, u.UserID); err != nil {
+				return err
+			}
+		}
+		u.TeamName = req.TeamName
+		if err := tx.UpdateUser(u); err != nil {
+			return err
+		}
+		moved, err := tx.GetUser(u.UserID)
+		if err != nil {
+			return err
+		}
+		resp.User = moved
+
+		if !req.ReassignReviews {
+			return nil
+		}
+		if oldTeamName == "" {
+			return nil
+		}
+		oldTeam, err := tx.GetTeam(oldTeamName)
+		if err != nil {
+			return err
+		}
+		released, err := s.releaseOpenReviews(tx, req.UserID, oldTeamName, &oldTeam)
+		if err != nil {
+			return err
+		}
+		resp.ReleasedReviews = released
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось перевести пользователя", slog.String("user_id", req.UserID), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось перевести пользователя", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed move user: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("пользователь переведён", slog.String("user_id", req.UserID), slog.String("team_name", req.TeamName), slog.Int("released", len(resp.ReleasedReviews)))
+	}
+	return resp, nil
+}
+
+// checkTeamVersion блокирует строку команды до конца транзакции и сверяет её версию
+// с ожидаемой из If-Match (0 — без проверки)
+func checkTeamVersion(tx *repository.Storage, teamName string, ifMatch int64) error {
+	version, err := tx.LockTeamVersion(teamName)
+	if err != nil {
+		return errWithCode(models.ErrorCodeNotFound, "team not found")
+	}
+	if ifMatch > 0 && version != ifMatch {
+		return errWithCode(models.ErrorCodePrecondition, "team version does not match If-Match")
+	}
+	return nil
+}
+
+// promotePrimaryTeam делает основной одну из оставшихся команд пользователя (или оставляет его без команды)
+func (s *Service) promotePrimaryTeam(tx *repository.Storage, u models.User) error {
+	teams, err := tx.ListUserTeams(u.UserID)
+	if err != nil {
+		return err
+	}
+	u.TeamName = ""
+	if len(teams) > 0 {
+		u.TeamName = teams[0]
+	}
+	return tx.UpdateUser(u)
+}
+
+// releaseOpenReviews снимает пользователя с OPEN PR команды teamName (всех PR, если она пуста).
+// Если team задана, каждое назначение передаётся случайному подходящему её участнику,
+// иначе просто снимается.
+func (s *Service) releaseOpenReviews(tx *repository.Storage, userID, teamName string, team *models.Team) ([]models.ReviewMove, error) {
+	prs, err := tx.ListOpenPRsByReviewer(userID, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy models.EffectivePolicy
+	if team != nil {
+		if policy, err = resolvePolicy(tx, team.TeamName); err != nil {
+			return nil, err
+		}
+	}
+
+	released := []models.ReviewMove{}
+	for _, pr := range prs {
+		move, err := s.releaseReview(tx, pr, userID, team, policy)
+		if err != nil {
+			return nil, err
+		}
+		released = append(released, move)
+	}
+	if err := tx.AddOutboxEvents(s.reviewMoveEvents(released)...); err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+// releaseReview снимает пользователя с OPEN PR и, если team задана, передаёт назначение
+// случайному подходящему её участнику. Событие в outbox не пишется.
+func (s *Service) releaseReview(tx *repository.Storage, pr models.PullRequest, userID string, team *models.Team, policy models.EffectivePolicy) (models.ReviewMove, error) {
+	move := models.ReviewMove{PullRequestID: pr.PullRequestID, FromUserID: userID}
+	if team != nil {
+		exclude := map[string]string{}
+		for _, rid := range pr.AssignedReviewers {
+			exclude[rid] = models.ExclusionReasonAlreadyAssigned
+		}
+		exclude[pr.AuthorID] = models.ExclusionReasonAuthor
+		candidates, excluded, err := eligibleCandidates(tx, team.Members, policy, exclude)
+		if err != nil {
+			return move, err
+		}
+		if len(candidates) > 0 {
+			chosen, _ := s.chooseReviewers(candidates, excluded, 1)
+			move.ToUserID = chosen[0]
+		}
+	}
+
+	var err error
+	if move.ToUserID != "" {
+		err = tx.MoveReviewer(pr.PullRequestID, userID, move.ToUserID)
+	} else {
+		err = tx.RemoveReviewer(pr.PullRequestID, userID)
+	}
+	return move, err
+}
+
+// replaceAuthorReview снимает автора OPEN PR с ревью этого PR и передаёт назначение
+// подходящему участнику команды PR (основной команды автора, если у PR её нет).
+// Нужна после смены автора: автор не может ревьюить свой PR, а PR не должен
+// терять ревьювера.
+func (s *Service) replaceAuthorReview(tx *repository.Storage, pr models.PullRequest) (models.ReviewMove, error) {
+	teamName := pr.TeamName
+	if teamName == "" {
+		author, err := tx.GetUser(pr.AuthorID)
+		if err != nil {
+			return models.ReviewMove{}, err
+		}
+		teamName = author.TeamName
+	}
+
+	var team *models.Team
+	var policy models.EffectivePolicy
+	if teamName != "" {
+		t, err := tx.GetTeam(teamName)
+		if err != nil {
+			return models.ReviewMove{}, err
+		}
+		if policy, err = resolvePolicy(tx, teamName); err != nil {
+			return models.ReviewMove{}, err
+		}
+		team = &t
+	}
+
+	move, err := s.releaseReview(tx, pr, pr.AuthorID, team, policy)
+	if err != nil {
+		return move, err
+	}
+	if err := tx.AddOutboxEvents(s.reviewMoveEvents([]models.ReviewMove{move})...); err != nil {
+		return move, err
+	}
+	return move, nil
+}
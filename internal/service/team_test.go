@@ -0,0 +1,184 @@
+package service
+
+import (
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestAddTeamMemberKeepsExistingUser(t *testing.T) {
+	s, st := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		{UserID: "u1", Username: "alice", IsActive: true, ReviewWeight: 3},
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	if _, err := s.AddTeam(&models.Team{TeamName: "frontend"}, false); err != nil {
+		t.Fatalf("AddTeam frontend: %v", err)
+	}
+
+	_, err = s.AddTeamMember(&models.AddTeamMemberRequest{
+		TeamName: "frontend",
+		Member:   models.TeamMember{UserID: "u1", Username: "renamed", IsActive: false, ReviewWeight: 1},
+	}, 0)
+	if err != nil {
+		t.Fatalf("AddTeamMember: %v", err)
+	}
+
+	u, err := st.GetUser("u1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.Username != "alice" || !u.IsActive || u.ReviewWeight != 3 || u.TeamName != "backend" {
+		t.Errorf("existing user changed: %+v", u)
+	}
+	if !containsString(u.Teams, "frontend") {
+		t.Errorf("teams %v, want frontend membership", u.Teams)
+	}
+}
+
+func TestAddTeamMemberCreatesUser(t *testing.T) {
+	s, st := newDBService(t)
+
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend"}, false); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	_, err := s.AddTeamMember(&models.AddTeamMemberRequest{
+		TeamName: "backend",
+		Member:   models.TeamMember{UserID: "u2", Username: "bob", IsActive: true},
+	}, 0)
+	if err != nil {
+		t.Fatalf("AddTeamMember: %v", err)
+	}
+
+	u, err := st.GetUser("u2")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.Username != "bob" || !u.IsActive || u.ReviewWeight != models.DefaultReviewWeight || u.TeamName != "backend" {
+		t.Errorf("created user %+v", u)
+	}
+}
+
+func TestAddTeamKeepsStoredWeight(t *testing.T) {
+	s, st := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		{UserID: "u1", Username: "alice", IsActive: true, ReviewWeight: 3},
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	_, err = s.AddTeam(&models.Team{TeamName: "platform", Members: []models.TeamMember{
+		{UserID: "u1", Username: "alice", IsActive: true},
+	}}, true)
+	if err != nil {
+		t.Fatalf("AddTeam platform: %v", err)
+	}
+
+	u, err := st.GetUser("u1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.ReviewWeight != 3 {
+		t.Errorf("review_weight %v, want stored 3", u.ReviewWeight)
+	}
+}
+
+func TestAddTeamKeepsExistingUserData(t *testing.T) {
+	s, st := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		{UserID: "u1", Username: "alice", IsActive: true, ReviewWeight: 3},
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	// пользователь уже есть: данные из запроса не применяются, добавляется только членство
+	resp, err := s.AddTeam(&models.Team{TeamName: "platform", Members: []models.TeamMember{
+		{UserID: "u1", Username: "mallory", IsActive: false, ReviewWeight: 5},
+	}}, true)
+	if err != nil {
+		t.Fatalf("AddTeam platform: %v", err)
+	}
+	if got := resp.Team.Members; len(got) != 1 || got[0].Username != "alice" || !got[0].IsActive || got[0].ReviewWeight != 3 {
+		t.Errorf("response members %+v, want stored user data", got)
+	}
+
+	u, err := st.GetUser("u1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.Username != "alice" || !u.IsActive || u.ReviewWeight != 3 || u.TeamName != "platform" {
+		t.Errorf("stored user %+v, want alice, active, weight 3, team platform", u)
+	}
+	teams, err := st.ListUserTeams("u1")
+	if err != nil {
+		t.Fatalf("ListUserTeams: %v", err)
+	}
+	if !containsString(teams, "platform") {
+		t.Errorf("teams %v, want platform membership", teams)
+	}
+}
+
+func TestAddTeamAllowMoveReleasesReviews(t *testing.T) {
+	s, st := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("u1", true), member("u2", true), member("u3", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	created, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	moved := created.PR.AssignedReviewers[0]
+
+	resp, err := s.AddTeam(&models.Team{TeamName: "platform", Members: []models.TeamMember{member(moved, true)}}, true)
+	if err != nil {
+		t.Fatalf("AddTeam platform: %v", err)
+	}
+	if len(resp.ReleasedReviews) != 1 || resp.ReleasedReviews[0].FromUserID != moved || resp.ReleasedReviews[0].ToUserID == "" {
+		t.Errorf("released %+v, want pr-1 passed on from %s", resp.ReleasedReviews, moved)
+	}
+
+	pr, err := st.GetPullRequest("pr-1")
+	if err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+	if containsString(pr.AssignedReviewers, moved) || len(pr.AssignedReviewers) != 2 {
+		t.Errorf("reviewers %v after moving %s", pr.AssignedReviewers, moved)
+	}
+	u, err := st.GetUser(moved)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if u.TeamName != "platform" || containsString(u.Teams, "backend") {
+		t.Errorf("moved user %+v", u)
+	}
+}
+
+func TestAddTeamIsAtomic(t *testing.T) {
+	s, st := newDBService(t)
+
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{member("u1", true)}}, false); err != nil {
+		t.Fatalf("AddTeam backend: %v", err)
+	}
+	// новая команда с несуществующим родителем: пользователи не должны остаться в БД
+	_, err := s.AddTeam(&models.Team{TeamName: "platform", ParentTeam: "missing", Members: []models.TeamMember{
+		member("u9", true), member("u1", true),
+	}}, true)
+	if code := ParseCodeFromError(err); code != models.ErrorCodeNotFound {
+		t.Fatalf("code %q, want %q", code, models.ErrorCodeNotFound)
+	}
+	if _, err := st.GetUser("u9"); err == nil {
+		t.Error("user u9 created by a failed AddTeam")
+	}
+	if u, err := st.GetUser("u1"); err != nil || u.TeamName != "backend" {
+		t.Errorf("user u1 = %+v, %v; want unchanged in backend", u, err)
+	}
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// teamsCSVHeader колонки CSV-формата импорта и экспорта. Строка описывает членство
+// пользователя в команде; строка без user_id — команду без участников,
+// строка без team_name — пользователя без команды. primary отмечает основную команду.
+var teamsCSVHeader = []string{"team_name", "parent_team", "user_id", "username", "is_active", "review_weight", "primary"}
+
+// ExportTeams выгружает все команды, пользователей и членства
+func (s *Service) ExportTeams() (*models.TeamsExport, error) {
+	if s.logger != nil {
+		s.logger.Info("ExportTeams вызван")
+	}
+	var data models.TeamsExport
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		var err error
+		if data.Teams, err = tx.ListTeams(); err != nil {
+			return err
+		}
+		if data.Users, err = tx.ListUsers(); err != nil {
+			return err
+		}
+		data.Memberships, err = tx.ListMemberships()
+		return err
+	})
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось выгрузить команды", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed export teams: %w", err)
+	}
+	return &data, nil
+}
+
+// teamsImportPlan изменения, которые внесёт импорт
+type teamsImportPlan struct {
+	teams       []models.TeamRecord
+	parents     []models.TeamRecord
+	users       []models.User
+	memberships []models.MembershipRecord
+}
+
+// ImportTeams загружает команды, пользователей и членства с семантикой upsert:
+// отсутствующие записи создаются, существующие обновляются, ничего не удаляется.
+// Импорт применяется целиком в одной транзакции и только если в данных нет ошибок;
+// при dryRun данные только проверяются и подсчитываются изменения.
+func (s *Service) ImportTeams(data *models.TeamsExport, dryRun bool) (*models.TeamsImportResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ImportTeams вызван", slog.Int("teams", len(data.Teams)), slog.Int("users", len(data.Users)),
+			slog.Int("memberships", len(data.Memberships)), slog.Bool("dry_run", dryRun))
+	}
+
+	resp := &models.TeamsImportResponse{DryRun: dryRun, Errors: []string{}}
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		plan, err := planTeamsImport(tx, data, resp)
+		if err != nil {
+			return err
+		}
+		if dryRun || len(resp.Errors) > 0 {
+			return nil
+		}
+
+		for _, t := range plan.teams {
+			if err := tx.EnsureTeam(t.TeamName); err != nil {
+				return err
+			}
+		}
+		for _, t := range plan.parents {
+			if err := tx.SetTeamParent(t.TeamName, t.ParentTeam); err != nil {
+				return err
+			}
+		}
+		for _, u := range plan.users {
+			if err := tx.UpsertUser(u); err != nil {
+				return err
+			}
+		}
+		for _, m := range plan.memberships {
+			if err := tx.AddMembership(m.TeamName, m.UserID); err != nil {
+				return err
+			}
+		}
+		resp.Applied = true
+		return nil
+	})
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось импортировать команды", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed import teams: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("импорт команд обработан", slog.Bool("applied", resp.Applied), slog.Int("errors", len(resp.Errors)))
+	}
+	return resp, nil
+}
+
+// planTeamsImport проверяет данные импорта относительно текущего состояния БД,
+// записывает ошибки и счётчики в resp и возвращает план изменений
+func planTeamsImport(tx *repository.Storage, data *models.TeamsExport, resp *models.TeamsImportResponse) (*teamsImportPlan, error) {
+	existingTeams, err := tx.ListTeams()
+	if err != nil {
+		return nil, err
+	}
+	existingUsers, err := tx.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	existingMemberships, err := tx.ListMemberships()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &teamsImportPlan{}
+	addErr := func(format string, args ...interface{}) {
+		resp.Errors = append(resp.Errors, fmt.Sprintf(format, args...))
+	}
+
+	// команды: итоговые родители — текущие, переопределённые данными импорта
+	parents := map[string]string{}
+	for _, t := range existingTeams {
+		parents[t.TeamName] = t.ParentTeam
+	}
+	existingParents := make(map[string]string, len(parents))
+	for name, parent := range parents {
+		existingParents[name] = parent
+	}
+	seenTeams := map[string]bool{}
+	for _, t := range data.Teams {
+		if t.TeamName == "" {
+			addErr("team: team_name is required")
+			continue
+		}
+		if seenTeams[t.TeamName] {
+			addErr("team %q: duplicate", t.TeamName)
+			continue
+		}
+		seenTeams[t.TeamName] = true
+		parents[t.TeamName] = t.ParentTeam
+	}
+	for _, t := range data.Teams {
+		if t.TeamName == "" || t.ParentTeam == "" {
+			continue
+		}
+		if _, ok := parents[t.ParentTeam]; !ok {
+			addErr("team %q: parent_team %q not found", t.TeamName, t.ParentTeam)
+			continue
+		}
+		for name, depth := t.ParentTeam, 0; name != ""; depth++ {
+			if name == t.TeamName || depth >= maxTeamDepth {
+				addErr("team %q: parent_team %q would create a cycle", t.TeamName, t.ParentTeam)
+				break
+			}
+			name = parents[name]
+		}
+	}
+	for name := range seenTeams {
+		parent := parents[name]
+		old, exists := existingParents[name]
+		switch {
+		case !exists:
+			resp.Teams.Created++
+			plan.teams = append(plan.teams, models.TeamRecord{TeamName: name})
+			if parent != "" {
+				plan.parents = append(plan.parents, models.TeamRecord{TeamName: name, ParentTeam: parent})
+			}
+		case old != parent:
+			resp.Teams.Updated++
+			plan.parents = append(plan.parents, models.TeamRecord{TeamName: name, ParentTeam: parent})
+		default:
+			resp.Teams.Unchanged++
+		}
+	}
+
+	// пользователи
+	users := map[string]models.UserRecord{}
+	for _, u := range existingUsers {
+		users[u.UserID] = u
+	}
+	firstMembership := map[string]string{}
+	for _, m := range data.Memberships {
+		if _, ok := firstMembership[m.UserID]; !ok && m.TeamName != "" {
+			firstMembership[m.UserID] = m.TeamName
+		}
+	}
+	seenUsers := map[string]bool{}
+	for _, u := range data.Users {
+		if u.UserID == "" {
+			addErr("user: user_id is required")
+			continue
+		}
+		if seenUsers[u.UserID] {
+			addErr("user %q: duplicate", u.UserID)
+			continue
+		}
+		seenUsers[u.UserID] = true
+		if u.Username == "" {
+			addErr("user %q: username is required", u.UserID)
+			continue
+		}
+		if u.ReviewWeight < 0 {
+			addErr("user %q: review_weight must be positive", u.UserID)
+			continue
+		}
+		if u.ReviewWeight == 0 {
+			u.ReviewWeight = models.DefaultReviewWeight
+		}
+
+		old, exists := users[u.UserID]
+		// без team_name основная команда не меняется, у нового пользователя ей становится первое членство
+		if u.TeamName == "" {
+			if exists {
+				u.TeamName = old.TeamName
+			} else {
+				u.TeamName = firstMembership[u.UserID]
+			}
+		}
+		if _, ok := parents[u.TeamName]; u.TeamName != "" && !ok {
+			addErr("user %q: team_name %q not found", u.UserID, u.TeamName)
+			continue
+		}
+
+		switch {
+		case !exists:
+			resp.Users.Created++
+		case old != u:
+			resp.Users.Updated++
+		default:
+			resp.Users.Unchanged++
+			users[u.UserID] = u
+			continue
+		}
+		users[u.UserID] = u
+		plan.users = append(plan.users, models.User{
+			UserID:       u.UserID,
+			Username:     u.Username,
+			TeamName:     u.TeamName,
+			IsActive:     u.IsActive,
+			ReviewWeight: u.ReviewWeight,
+		})
+	}
+
+	// членства добавляются, существующие не удаляются
+	memberships := map[models.MembershipRecord]bool{}
+	for _, m := range existingMemberships {
+		memberships[m] = true
+	}
+	seenMemberships := map[models.MembershipRecord]bool{}
+	for _, m := range data.Memberships {
+		if seenMemberships[m] {
+			continue
+		}
+		seenMemberships[m] = true
+		if _, ok := parents[m.TeamName]; !ok {
+			addErr("membership %q/%q: team not found", m.TeamName, m.UserID)
+			continue
+		}
+		if _, ok := users[m.UserID]; !ok {
+			addErr("membership %q/%q: user not found", m.TeamName, m.UserID)
+			continue
+		}
+		if memberships[m] {
+			resp.Memberships.Unchanged++
+			continue
+		}
+		resp.Memberships.Created++
+		plan.memberships = append(plan.memberships, m)
+	}
+	return plan, nil
+}
+
+// DecodeTeamsExport читает снимок команд в формате json или csv
+func DecodeTeamsExport(format string, r io.Reader) (*models.TeamsExport, error) {
+	switch format {
+	case "", models.TransferFormatJSON:
+		var data models.TeamsExport
+		if err := json.NewDecoder(r).Decode(&data); err != nil {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid JSON: "+err.Error())
+		}
+		return &data, nil
+	case models.TransferFormatCSV:
+		return decodeTeamsCSV(r)
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "format must be json or csv")
+	}
+}
+
+// EncodeTeamsExport записывает снимок команд в формате json или csv
+func EncodeTeamsExport(format string, w io.Writer, data *models.TeamsExport) error {
+	switch format {
+	case "", models.TransferFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case models.TransferFormatCSV:
+		return encodeTeamsCSV(w, data)
+	default:
+		return errWithCode(models.ErrorCodeValidation, "format must be json or csv")
+	}
+}
+
+// encodeTeamsCSV записывает снимок построчно: членства, затем команды без участников
+// и пользователи без команд
+func encodeTeamsCSV(w io.Writer, data *models.TeamsExport) error {
+	parents := map[string]string{}
+	for _, t := range data.Teams {
+		parents[t.TeamName] = t.ParentTeam
+	}
+	users := map[string]models.UserRecord{}
+	for _, u := range data.Users {
+		users[u.UserID] = u
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(teamsCSVHeader); err != nil {
+		return err
+	}
+	userRow := func(u models.UserRecord) []string {
+		return []string{u.UserID, u.Username, strconv.FormatBool(u.IsActive), strconv.FormatFloat(u.ReviewWeight, 'f', -1, 64)}
+	}
+
+	withMembers := map[string]bool{}
+	withTeams := map[string]bool{}
+	for _, m := range data.Memberships {
+		u, ok := users[m.UserID]
+		if !ok {
+			continue
+		}
+		withMembers[m.TeamName] = true
+		withTeams[m.UserID] = true
+		row := append([]string{m.TeamName, parents[m.TeamName]}, userRow(u)...)
+		row = append(row, strconv.FormatBool(u.TeamName == m.TeamName))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, t := range data.Teams {
+		if !withMembers[t.TeamName] {
+			if err := cw.Write([]string{t.TeamName, t.ParentTeam, "", "", "", "", ""}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, u := range data.Users {
+		if !withTeams[u.UserID] {
+			row := append([]string{"", ""}, userRow(u)...)
+			if err := cw.Write(append(row, "")); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// decodeTeamsCSV разбирает CSV в снимок. Повторяющиеся команды и пользователи должны
+// совпадать во всех строках.
+func decodeTeamsCSV(r io.Reader) (*models.TeamsExport, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(teamsCSVHeader)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errWithCode(models.ErrorCodeValidation, "invalid CSV header: "+err.Error())
+	}
+	for i, name := range teamsCSVHeader {
+		if header[i] != name {
+			return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("invalid CSV header: column %d must be %s", i+1, name))
+		}
+	}
+
+	data := &models.TeamsExport{
+		Teams:       []models.TeamRecord{},
+		Users:       []models.UserRecord{},
+		Memberships: []models.MembershipRecord{},
+	}
+	teamIdx := map[string]int{}
+	userIdx := map[string]int{}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid CSV: "+err.Error())
+		}
+		line, _ := cr.FieldPos(0)
+		teamName, parent, userID := rec[0], rec[1], rec[2]
+
+		if teamName != "" {
+			if i, ok := teamIdx[teamName]; !ok {
+				teamIdx[teamName] = len(data.Teams)
+				data.Teams = append(data.Teams, models.TeamRecord{TeamName: teamName, ParentTeam: parent})
+			} else if data.Teams[i].ParentTeam != parent {
+				return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: conflicting parent_team for team %q", line, teamName))
+			}
+		}
+		if userID == "" {
+			continue
+		}
+
+		u := models.UserRecord{UserID: userID, Username: rec[3]}
+		if u.IsActive, err = strconv.ParseBool(rec[4]); err != nil {
+			return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: is_active must be a boolean", line))
+		}
+		if rec[5] != "" {
+			if u.ReviewWeight, err = strconv.ParseFloat(rec[5], 64); err != nil {
+				return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: review_weight must be a number", line))
+			}
+		}
+		primary := false
+		if rec[6] != "" {
+			if primary, err = strconv.ParseBool(rec[6]); err != nil {
+				return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: primary must be a boolean", line))
+			}
+		}
+		if primary && teamName != "" {
+			u.TeamName = teamName
+		}
+
+		if i, ok := userIdx[userID]; !ok {
+			userIdx[userID] = len(data.Users)
+			data.Users = append(data.Users, u)
+		} else {
+			prev := &data.Users[i]
+			if prev.Username != u.Username || prev.IsActive != u.IsActive || prev.ReviewWeight != u.ReviewWeight {
+				return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: conflicting attributes for user %q", line, userID))
+			}
+			if u.TeamName != "" {
+				if prev.TeamName != "" && prev.TeamName != u.TeamName {
+					return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("line %d: user %q has several primary teams", line, userID))
+				}
+				prev.TeamName = u.TeamName
+			}
+		}
+		if teamName != "" {
+			data.Memberships = append(data.Memberships, models.MembershipRecord{TeamName: teamName, UserID: userID})
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+// teamsSnapshot снимок с командой без участников, пользователем без команды
+// и пользователем в двух командах
+func teamsSnapshot() *models.TeamsExport {
+	return &models.TeamsExport{
+		Teams: []models.TeamRecord{
+			{TeamName: "backend", ParentTeam: "org"},
+			{TeamName: "empty", ParentTeam: "org"},
+			{TeamName: "org"},
+		},
+		Users: []models.UserRecord{
+			{UserID: "u1", Username: "alice", TeamName: "backend", IsActive: true, ReviewWeight: 1},
+			{UserID: "u2", Username: "bob, jr.", IsActive: false, ReviewWeight: 2.5},
+		},
+		Memberships: []models.MembershipRecord{
+			{TeamName: "backend", UserID: "u1"},
+			{TeamName: "org", UserID: "u1"},
+		},
+	}
+}
+
+// sortSnapshot упорядочивает записи снимка, порядок которых форматом не сохраняется
+func sortSnapshot(data *models.TeamsExport) {
+	sort.Slice(data.Teams, func(i, j int) bool { return data.Teams[i].TeamName < data.Teams[j].TeamName })
+	sort.Slice(data.Users, func(i, j int) bool { return data.Users[i].UserID < data.Users[j].UserID })
+	sort.Slice(data.Memberships, func(i, j int) bool {
+		a, b := data.Memberships[i], data.Memberships[j]
+		return a.TeamName < b.TeamName || a.TeamName == b.TeamName && a.UserID < b.UserID
+	})
+}
+
+func TestTeamsExportRoundTrip(t *testing.T) {
+	for _, format := range []string{models.TransferFormatJSON, models.TransferFormatCSV} {
+		want := teamsSnapshot()
+		var buf bytes.Buffer
+		if err := EncodeTeamsExport(format, &buf, want); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		got, err := DecodeTeamsExport(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", format, err)
+		}
+		sortSnapshot(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: round trip\n got %+v\nwant %+v", format, got, want)
+		}
+	}
+}
+
+func TestDecodeTeamsCSVConflictingUser(t *testing.T) {
+	in := "team_name,parent_team,user_id,username,is_active,review_weight,primary\n" +
+		"backend,,u1,alice,true,1,true\n" +
+		"frontend,,u1,alice,false,1,false\n"
+	if _, err := DecodeTeamsExport(models.TransferFormatCSV, bytes.NewBufferString(in)); ParseCodeFromError(err) != models.ErrorCodeValidation {
+		t.Errorf("conflicting is_active: %v, want %s", err, models.ErrorCodeValidation)
+	}
+}
+
+func TestImportTeamsDryRunLeavesDBUnchanged(t *testing.T) {
+	s, _ := newDBService(t)
+	if _, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{member("u1", true)}}, false); err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	before, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+
+	resp, err := s.ImportTeams(teamsSnapshot(), true)
+	if err != nil {
+		t.Fatalf("ImportTeams: %v", err)
+	}
+	if resp.Applied || len(resp.Errors) != 0 {
+		t.Errorf("dry run applied %v, errors %v", resp.Applied, resp.Errors)
+	}
+	if resp.Teams.Created != 2 || resp.Users.Created != 1 || resp.Users.Updated != 1 {
+		t.Errorf("planned changes teams %+v, users %+v", resp.Teams, resp.Users)
+	}
+
+	after, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("dry run changed the DB\nbefore %+v\n after %+v", before, after)
+	}
+}
+
+func TestImportTeamsRoundTrip(t *testing.T) {
+	s, _ := newDBService(t)
+
+	resp, err := s.ImportTeams(teamsSnapshot(), false)
+	if err != nil || !resp.Applied {
+		t.Fatalf("ImportTeams: %+v, %v", resp, err)
+	}
+	got, err := s.ExportTeams()
+	if err != nil {
+		t.Fatalf("ExportTeams: %v", err)
+	}
+	sortSnapshot(got)
+	if want := teamsSnapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("exported after import\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// GetUser получает пользователя по ID
+func (s *Service) GetUser(userID string) (*models.UserResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("GetUser вызван", slog.String("user_id", userID))
+	}
+	u, err := s.storage.GetUser(userID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("пользователь не найден", slog.String("user_id", userID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
+	}
+	return &models.UserResponse{User: u}, nil
+}
+
+// UpdateUser частично обновляет пользователя: имя, активность и вес ревьювера
+func (s *Service) UpdateUser(req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("UpdateUser вызван", slog.String("user_id", req.UserID))
+	}
+	if req.Username != nil && *req.Username == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "username must not be empty")
+	}
+	if req.ReviewWeight != nil && *req.ReviewWeight <= 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "review_weight must be positive")
+	}
+
+	u, err := s.storage.GetUser(req.UserID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("пользователь не найден", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "user not found")
+	}
+
+	if req.Username != nil {
+		u.Username = *req.Username
+	}
+	deactivated := false
+	if req.IsActive != nil {
+		deactivated = u.IsActive && !*req.IsActive
+		u.IsActive = *req.IsActive
+	}
+	if req.ReviewWeight != nil {
+		u.ReviewWeight = *req.ReviewWeight
+	}
+	if err := s.updateUser(u, deactivated); err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось обновить пользователя", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed update user: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("пользователь обновлён", slog.String("user_id", req.UserID))
+	}
+	return &models.UserResponse{User: u}, nil
+}
+
+// DeleteUser удаляет пользователя. PR пользователя не удаляются: если они есть,
+// авторство передаётся transfer_prs_to, иначе удаление отклоняется.
+// Назначения на OPEN PR передаются участникам команды PR или снимаются.
+func (s *Service) DeleteUser(req *models.DeleteUserRequest) (*models.DeleteUserResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("DeleteUser вызван", slog.String("user_id", req.UserID), slog.String("transfer_prs_to", req.TransferPRsTo))
+	}
+	if req.TransferPRsTo == req.UserID && req.TransferPRsTo != "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "transfer_prs_to must differ from user_id")
+	}
+
+	resp := &models.DeleteUserResponse{UserID: req.UserID, ReleasedReviews: []models.ReviewMove{}}
+	err := s.storage.WithTx(func(tx *repository.Storage) error {
+		u, err := tx.GetUser(req.UserID)
+		if err != nil {
+			return errWithCode(models.ErrorCodeNotFound, "user not found")
+		}
+
+		authored, err := tx.CountPRsByAuthor(req.UserID)
+		if err != nil {
+			return err
+		}
+		if authored > 0 {
+			if req.TransferPRsTo == "" {
+				return errWithCode(models.ErrorCodeUserHasPRs, "user has pull requests, set transfer_prs_to")
+			}
+			if _, err := tx.GetUser(req.TransferPRsTo); err != nil {
+				return errWithCode(models.ErrorCodeNotFound, "transfer_prs_to user not found")
+			}
+			n, err := tx.TransferAuthorship(req.UserID, req.TransferPRsTo)
+			if err != nil {
+				return err
+			}
+			resp.TransferredPRs = n
+		}
+
+		// назначения на PR команд пользователя передаём их участникам, остальные снимаем
+		for _, teamName := range u.Teams {
+			team, err := tx.GetTeam(teamName)
+			if err != nil {
+				return err
+			}
+			remaining := []models.TeamMember{}
+			for _, m := range team.Members {
+				if m.UserID != req.UserID {
+					remaining = append(remaining, m)
+				}
+			}
+			team.Members = remaining
+			released, err := s.releaseOpenReviews(tx, req.UserID, teamName, &team)
+			if err != nil {
+				return err
+			}
+			resp.ReleasedReviews = append(resp.ReleasedReviews, released...)
+		}
+		released, err := s.releaseOpenReviews(tx, req.UserID, "", nil)
+		if err != nil {
+			return err
+		}
+		resp.ReleasedReviews = append(resp.ReleasedReviews, released...)
+
+		if err := tx.DeleteUser(req.UserID); err != nil {
+			return err
+		}
+		if resp.TransferredPRs == 0 {
+			return nil
+		}
+
+		// новый автор не может ревьюить полученные PR: его назначения передаём другим.
+		// Делается после удаления, чтобы замену не получил удаляемый пользователь.
+		prs, err := tx.ListOpenPRsByReviewer(req.TransferPRsTo, "")
+		if err != nil {
+			return err
+		}
+		for _, pr := range prs {
+			if pr.AuthorID != req.TransferPRsTo {
+				continue
+			}
+			move, err := s.replaceAuthorReview(tx, pr)
+			if err != nil {
+				return err
+			}
+			resp.ReleasedReviews = append(resp.ReleasedReviews, move)
+		}
+		return nil
+	})
+	if err != nil {
+		if isDomainError(err) {
+			if s.logger != nil {
+				s.logger.Warn("не удалось удалить пользователя", slog.String("user_id", req.UserID), slog.Any("err", err))
+			}
+			return nil, err
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось удалить пользователя", slog.String("user_id", req.UserID), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed delete user: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("пользователь удалён", slog.String("user_id", req.UserID), slog.Int("transferred_prs", resp.TransferredPRs))
+	}
+	return resp, nil
+}
+
+// updateUser сохраняет пользователя; при деактивации в той же транзакции записывает событие user.deactivated
+func (s *Service) updateUser(u models.User, deactivated bool) error {
+	return s.storage.WithTx(func(tx *repository.Storage) error {
+		if err := tx.UpdateUser(u); err != nil {
+			return err
+		}
+		if !deactivated {
+			return nil
+		}
+		return tx.AddOutboxEvents(s.newEvent(models.EventUserDeactivated, models.UserEventData{User: u}))
+	})
+}
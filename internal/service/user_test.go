@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"pr-review-manager/internal/models"
+)
+
+func TestDeleteUserTransferReplacesNewAuthorReview(t *testing.T) {
+	s, _ := newDBService(t)
+
+	_, err := s.AddTeam(&models.Team{TeamName: "backend", Members: []models.TeamMember{
+		member("author", true), member("r1", true), member("r2", true), member("r3", true),
+	}}, false)
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	created, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	want := len(created.PR.AssignedReviewers)
+	heir := created.PR.AssignedReviewers[0]
+
+	resp, err := s.DeleteUser(&models.DeleteUserRequest{UserID: "author", TransferPRsTo: heir})
+	if err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if resp.TransferredPRs != 1 {
+		t.Errorf("transferred %d prs, want 1", resp.TransferredPRs)
+	}
+	if len(resp.ReleasedReviews) != 1 || resp.ReleasedReviews[0].FromUserID != heir || resp.ReleasedReviews[0].ToUserID == "" {
+		t.Errorf("released reviews %+v, want %s replaced on pr-1", resp.ReleasedReviews, heir)
+	}
+
+	got, err := s.GetPullRequest("pr-1")
+	if err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+	if got.PR.AuthorID != heir || containsString(got.PR.AssignedReviewers, heir) {
+		t.Errorf("pr %+v: %s must be the author and not a reviewer", got.PR, heir)
+	}
+	if len(got.PR.AssignedReviewers) != want {
+		t.Errorf("reviewers %v, want %d: the slot must be refilled", got.PR.AssignedReviewers, want)
+	}
+}
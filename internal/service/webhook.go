@@ -0,0 +1,260 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+const (
+	// webhookMaxAttempts число попыток доставки, после которого она переносится в dead-letter
+	webhookMaxAttempts = 10
+	// webhookRetryBase задержка перед первым повтором; каждая следующая вдвое больше
+	webhookRetryBase = 30 * time.Second
+	// webhookRetryMax наибольшая задержка между попытками
+	webhookRetryMax = 6 * time.Hour
+	// webhookLease время, на которое взятая доставка скрывается от других обработчиков
+	webhookLease = 5 * time.Minute
+	// webhookBatchSize число доставок, отправляемых за один проход
+	webhookBatchSize = 50
+)
+
+// CreateWebhookSubscription создаёт подписку на события сервиса
+func (s *Service) CreateWebhookSubscription(req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscriptionResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("CreateWebhookSubscription вызван", slog.String("url", req.URL), slog.Any("event_types", req.EventTypes))
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "url must be an absolute http(s) URL")
+	}
+	if req.Secret == "" {
+		return nil, errWithCode(models.ErrorCodeValidation, "secret is required")
+	}
+	if len(req.EventTypes) == 0 {
+		return nil, errWithCode(models.ErrorCodeValidation, "event_types must not be empty")
+	}
+	seen := map[models.DomainEventType]bool{}
+	var types []models.DomainEventType
+	for _, t := range req.EventTypes {
+		if !isDomainEventType(t) {
+			return nil, errWithCode(models.ErrorCodeValidation, fmt.Sprintf("unknown event type %q", t))
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+
+	sub := models.WebhookSubscription{URL: req.URL, Secret: req.Secret, EventTypes: types}
+	if err := s.storage.CreateWebhookSubscription(&sub); err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось создать подписку", slog.String("url", req.URL), slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed create webhook subscription: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("подписка создана", slog.Int64("id", sub.ID))
+	}
+	return &models.WebhookSubscriptionResponse{Subscription: sub}, nil
+}
+
+// ListWebhookSubscriptions получает страницу подписок в порядке создания
+func (s *Service) ListWebhookSubscriptions(page models.PageRequest) (*models.WebhookSubscriptionListResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ListWebhookSubscriptions вызван")
+	}
+	limit, err := pageLimit(page.Limit)
+	if err != nil {
+		return nil, err
+	}
+	subs, next, err := s.storage.ListWebhookSubscriptions(limit, page.Cursor)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось получить подписки", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed list webhook subscriptions: %w", err)
+	}
+	return &models.WebhookSubscriptionListResponse{Subscriptions: subs, NextCursor: next}, nil
+}
+
+// DeleteWebhookSubscription удаляет подписку и журнал её доставок
+func (s *Service) DeleteWebhookSubscription(id int64) (*models.WebhookSubscriptionResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("DeleteWebhookSubscription вызван", slog.Int64("id", id))
+	}
+	sub, err := s.storage.DeleteWebhookSubscription(id)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("подписка не найдена", slog.Int64("id", id), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "subscription not found")
+	}
+	return &models.WebhookSubscriptionResponse{Subscription: sub}, nil
+}
+
+// ListWebhookDeliveries получает страницу журнала доставок; со статусом DEAD — список dead-letter
+func (s *Service) ListWebhookDeliveries(filter models.WebhookDeliveryFilter) (*models.WebhookDeliveryListResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("ListWebhookDeliveries вызван", slog.Int64("subscription_id", filter.SubscriptionID), slog.String("status", filter.Status))
+	}
+	switch filter.Status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+	default:
+		return nil, errWithCode(models.ErrorCodeValidation, "status must be PENDING, DELIVERED or DEAD")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = models.DefaultDeliveryLimit
+	}
+	if filter.Limit > models.MaxDeliveryLimit {
+		filter.Limit = models.MaxDeliveryLimit
+	}
+
+	deliveries, next, err := s.storage.ListWebhookDeliveries(filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errWithCode(models.ErrorCodeValidation, "invalid cursor")
+		}
+		if s.logger != nil {
+			s.logger.Error("не удалось получить журнал доставок", slog.Any("err", err))
+		}
+		return nil, fmt.Errorf("failed list webhook deliveries: %w", err)
+	}
+	return &models.WebhookDeliveryListResponse{Deliveries: deliveries, NextCursor: next}, nil
+}
+
+// RedeliverWebhook возвращает доставку из dead-letter в очередь с новым запасом попыток
+func (s *Service) RedeliverWebhook(id int64) (*models.WebhookDeliveryResponse, error) {
+	if s.logger != nil {
+		s.logger.Info("RedeliverWebhook вызван", slog.Int64("delivery_id", id))
+	}
+	d, err := s.storage.RequeueWebhookDelivery(id, s.now().UTC())
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("доставка не найдена в dead-letter", slog.Int64("delivery_id", id), slog.Any("err", err))
+		}
+		return nil, errWithCode(models.ErrorCodeNotFound, "dead delivery not found")
+	}
+	return &models.WebhookDeliveryResponse{Delivery: d}, nil
+}
+
+// DeliverWebhooks отправляет одну порцию доставок, срок которых наступил, и возвращает их число.
+// Неудачная попытка откладывает доставку с экспоненциальной задержкой, после
+// webhookMaxAttempts попыток доставка переносится в dead-letter.
+func (s *Service) DeliverWebhooks(ctx context.Context) (int, error) {
+	now := s.now().UTC()
+	claimed, err := s.storage.ClaimWebhookDeliveries(now, now.Add(webhookLease), webhookBatchSize)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("не удалось получить доставки", slog.Any("err", err))
+		}
+		return 0, fmt.Errorf("failed claim webhook deliveries: %w", err)
+	}
+
+	for _, d := range claimed {
+		status, sendErr := s.sendWebhook(ctx, d)
+		if sendErr == nil {
+			err = s.storage.CompleteWebhookDelivery(d.ID, status, s.now().UTC())
+		} else {
+			var next *time.Time
+			if d.Attempts+1 < webhookMaxAttempts {
+				at := s.now().UTC().Add(webhookRetryDelay(d.Attempts + 1))
+				next = &at
+			}
+			if s.logger != nil {
+				s.logger.Warn("доставка webhook не удалась", slog.Int64("delivery_id", d.ID), slog.Int("attempt", d.Attempts+1),
+					slog.Bool("dead", next == nil), slog.Any("err", sendErr))
+			}
+			err = s.storage.FailWebhookDelivery(d.ID, status, sendErr.Error(), next)
+		}
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("не удалось сохранить результат доставки", slog.Int64("delivery_id", d.ID), slog.Any("err", err))
+			}
+			return 0, fmt.Errorf("failed record webhook delivery: %w", err)
+		}
+	}
+	return len(claimed), nil
+}
+
+// RunWebhookDispatcher отправляет доставки webhook каждые interval, пока не отменён ctx.
+// Если очередь не разобрана за один проход, следующий выполняется сразу.
+func (s *Service) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.DeliverWebhooks(ctx)
+		if err == nil && n == webhookBatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendWebhook отправляет доставку подписчику. Тело подписывается HMAC-SHA256 секретом
+// подписки в заголовке X-Webhook-Signature (sha256=<hex>). Возвращает код ответа.
+func (s *Service) sendWebhook(ctx context.Context, d repository.ClaimedDelivery) (int, error) {
+	mac := hmac.New(sha256.New, []byte(d.Secret))
+	mac.Write(d.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pr-review-manager-webhook")
+	req.Header.Set("X-Webhook-Event", string(d.EventType))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookRetryDelay задержка перед попыткой после attempt неудачных
+func webhookRetryDelay(attempt int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempt && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// isDomainEventType проверяет, что тип события известен
+func isDomainEventType(t models.DomainEventType) bool {
+	for _, known := range models.DomainEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pr-review-manager/internal/models"
+	"pr-review-manager/internal/repository"
+)
+
+// subscriber поднимает получателя webhook, который отвечает 500 на первые failures
+// запросов, затем 200, и проверяет подпись каждого запроса секретом secret
+func subscriber(t *testing.T, secret string, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(raw)
+		if got, want := r.Header.Get("X-Webhook-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+		}
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// subscribePRCreated подписывает url на pr.created и публикует одно такое событие
+func subscribePRCreated(t *testing.T, s *Service, url string) models.WebhookSubscription {
+	t.Helper()
+	sub, err := s.CreateWebhookSubscription(&models.CreateWebhookSubscriptionRequest{
+		URL: url, Secret: "s3cret", EventTypes: []models.DomainEventType{models.EventPRCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription: %v", err)
+	}
+	batchTeam(t, s)
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if _, err := s.DispatchOutbox(); err != nil {
+		t.Fatalf("DispatchOutbox: %v", err)
+	}
+	return sub.Subscription
+}
+
+// onlyDelivery возвращает единственную доставку журнала
+func onlyDelivery(t *testing.T, s *Service) models.WebhookDelivery {
+	t.Helper()
+	resp, err := s.ListWebhookDeliveries(models.WebhookDeliveryFilter{})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries: %v", err)
+	}
+	if len(resp.Deliveries) != 1 {
+		t.Fatalf("deliveries %+v, want one", resp.Deliveries)
+	}
+	return resp.Deliveries[0]
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, webhookRetryBase},
+		{1, webhookRetryBase},
+		{2, 2 * webhookRetryBase},
+		{3, 4 * webhookRetryBase},
+		{10, 512 * webhookRetryBase},
+		{11, webhookRetryMax},
+		{100, webhookRetryMax},
+	} {
+		if got := webhookRetryDelay(tc.attempt); got != tc.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestSendWebhookSignsPayload(t *testing.T) {
+	payload := []byte(`{"id":"ev-1","type":"pr.created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(payload)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for header, want := range map[string]string{
+			"Content-Type":        "application/json",
+			"X-Webhook-Event":     string(models.EventPRCreated),
+			"X-Webhook-Delivery":  "7",
+			"X-Webhook-Signature": "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		} {
+			if got := r.Header.Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+		if raw, _ := io.ReadAll(r.Body); string(raw) != string(payload) {
+			t.Errorf("body = %s, want %s", raw, payload)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	s := NewService(nil, nil, WithWebhookClient(srv.Client()))
+	status, err := s.sendWebhook(context.Background(), repository.ClaimedDelivery{
+		WebhookDelivery: models.WebhookDelivery{ID: 7, EventType: models.EventPRCreated, Payload: payload},
+		URL:             srv.URL,
+		Secret:          "s3cret",
+	})
+	if err == nil || status != http.StatusBadGateway {
+		t.Errorf("send: status %d, err %v; want 502 and an error", status, err)
+	}
+}
+
+func TestDeliverWebhooksRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv, calls := subscriber(t, "s3cret", 2)
+	s, _ := newDBService(t, WithWebhookClient(srv.Client()), WithClock(func() time.Time { return now }))
+	subscribePRCreated(t, s, srv.URL)
+
+	// каждая неудача откладывает доставку на вдвое больший срок, до него доставка не берётся
+	for attempt := 1; attempt <= 2; attempt++ {
+		if n, err := s.DeliverWebhooks(context.Background()); err != nil || n != 1 {
+			t.Fatalf("attempt %d: sent %d, %v; want 1", attempt, n, err)
+		}
+		d := onlyDelivery(t, s)
+		want := now.Add(webhookRetryDelay(attempt))
+		if d.Status != models.DeliveryStatusPending || d.Attempts != attempt || d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(want) {
+			t.Fatalf("after attempt %d: %+v, want PENDING with next attempt at %v", attempt, d, want)
+		}
+		if d.ResponseStatus != http.StatusInternalServerError || d.LastError == "" {
+			t.Errorf("after attempt %d: response %d, error %q; want the failure recorded", attempt, d.ResponseStatus, d.LastError)
+		}
+		now = want.Add(-time.Second)
+		if n, _ := s.DeliverWebhooks(context.Background()); n != 0 {
+			t.Fatalf("attempt %d: sent %d before the retry delay, want 0", attempt, n)
+		}
+		now = want
+	}
+	if webhookRetryDelay(2) != 2*webhookRetryDelay(1) {
+		t.Errorf("retry delays %v, %v; want doubling", webhookRetryDelay(1), webhookRetryDelay(2))
+	}
+
+	if n, err := s.DeliverWebhooks(context.Background()); err != nil || n != 1 {
+		t.Fatalf("final attempt: sent %d, %v; want 1", n, err)
+	}
+	d := onlyDelivery(t, s)
+	if d.Status != models.DeliveryStatusDelivered || d.Attempts != 3 || d.DeliveredAt == nil || d.LastError != "" {
+		t.Errorf("delivered %+v, want DELIVERED after 3 attempts", d)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("subscriber got %d requests, want 3", calls.Load())
+	}
+}
+
+func TestDeliverWebhooksDeadLetterAndRedeliver(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv, calls := subscriber(t, "s3cret", webhookMaxAttempts)
+	s, _ := newDBService(t, WithWebhookClient(srv.Client()), WithClock(func() time.Time { return now }))
+	sub := subscribePRCreated(t, s, srv.URL)
+
+	// следующая попытка назначается через webhookRetryDelay после каждой неудачи, кроме последней
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if n, err := s.DeliverWebhooks(context.Background()); err != nil || n != 1 {
+			t.Fatalf("attempt %d: sent %d, %v; want 1", attempt, n, err)
+		}
+		if attempt == webhookMaxAttempts {
+			break
+		}
+		d := onlyDelivery(t, s)
+		want := now.Add(webhookRetryDelay(attempt))
+		if d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(want) {
+			t.Fatalf("after attempt %d: next attempt %v, want %v", attempt, d.NextAttemptAt, want)
+		}
+		now = want
+	}
+	d := onlyDelivery(t, s)
+	if d.Status != models.DeliveryStatusDead || d.Attempts != webhookMaxAttempts || d.NextAttemptAt != nil {
+		t.Fatalf("after %d attempts: %+v, want DEAD", webhookMaxAttempts, d)
+	}
+	if n, _ := s.DeliverWebhooks(context.Background()); n != 0 {
+		t.Fatalf("sent %d dead deliveries, want 0", n)
+	}
+	dead, err := s.ListWebhookDeliveries(models.WebhookDeliveryFilter{SubscriptionID: sub.ID, Status: models.DeliveryStatusDead})
+	if err != nil || len(dead.Deliveries) != 1 || dead.Deliveries[0].ID != d.ID {
+		t.Fatalf("dead letters %+v, %v; want delivery %d", dead, err, d.ID)
+	}
+
+	// повторная доставка возвращает её в очередь с новым запасом попыток
+	redelivered, err := s.RedeliverWebhook(d.ID)
+	if err != nil {
+		t.Fatalf("RedeliverWebhook: %v", err)
+	}
+	if got := redelivered.Delivery; got.Status != models.DeliveryStatusPending || got.Attempts != 0 ||
+		got.NextAttemptAt == nil || !got.NextAttemptAt.Equal(now) {
+		t.Fatalf("redelivered %+v, want PENDING now with no attempts", got)
+	}
+	if _, err := s.RedeliverWebhook(d.ID); ParseCodeFromError(err) != models.ErrorCodeNotFound {
+		t.Errorf("redeliver pending: err %v, want %s", err, models.ErrorCodeNotFound)
+	}
+	if n, err := s.DeliverWebhooks(context.Background()); err != nil || n != 1 {
+		t.Fatalf("after redeliver: sent %d, %v; want 1", n, err)
+	}
+	if d := onlyDelivery(t, s); d.Status != models.DeliveryStatusDelivered || d.Attempts != 1 {
+		t.Errorf("after redeliver: %+v, want DELIVERED on the first new attempt", d)
+	}
+	if calls.Load() != webhookMaxAttempts+1 {
+		t.Errorf("subscriber got %d requests, want %d", calls.Load(), webhookMaxAttempts+1)
+	}
+}
+
+func TestWebhookListsPage(t *testing.T) {
+	s, _ := newDBService(t)
+	var subIDs []int64
+	for i := 0; i < 3; i++ {
+		sub, err := s.CreateWebhookSubscription(&models.CreateWebhookSubscriptionRequest{
+			URL: fmt.Sprintf("https://hooks.example.com/%d", i), Secret: "s3cret",
+			EventTypes: []models.DomainEventType{models.EventReviewerAssigned},
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookSubscription: %v", err)
+		}
+		subIDs = append(subIDs, sub.Subscription.ID)
+	}
+	batchTeam(t, s)
+	if _, err := s.CreatePullRequest(&models.CreatePullRequestRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "author"}, false); err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if _, err := s.DispatchOutbox(); err != nil {
+		t.Fatalf("DispatchOutbox: %v", err)
+	}
+
+	var gotSubs []int64
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		resp, err := s.ListWebhookSubscriptions(models.PageRequest{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("subscriptions page %d: %v", page, err)
+		}
+		for _, sub := range resp.Subscriptions {
+			gotSubs = append(gotSubs, sub.ID)
+		}
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if fmt.Sprint(gotSubs) != fmt.Sprint(subIDs) {
+		t.Errorf("subscription pages %v, want %v", gotSubs, subIDs)
+	}
+
+	// доставки одного события созданы одновременно: порядок внутри страницы решает id
+	all, err := s.ListWebhookDeliveries(models.WebhookDeliveryFilter{})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries: %v", err)
+	}
+	if len(all.Deliveries) < 3 || all.NextCursor != "" {
+		t.Fatalf("deliveries %d, next %q; want every subscriber on one page", len(all.Deliveries), all.NextCursor)
+	}
+	var got, want []int64
+	for _, d := range all.Deliveries {
+		want = append(want, d.ID)
+	}
+	cursor = ""
+	for page := 0; page < 20; page++ {
+		resp, err := s.ListWebhookDeliveries(models.WebhookDeliveryFilter{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("deliveries page %d: %v", page, err)
+		}
+		for _, d := range resp.Deliveries {
+			got = append(got, d.ID)
+		}
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("delivery pages %v, want %v", got, want)
+	}
+
+	if _, err := s.ListWebhookDeliveries(models.WebhookDeliveryFilter{Cursor: "bad"}); ParseCodeFromError(err) != models.ErrorCodeValidation {
+		t.Errorf("invalid delivery cursor: err %v, want %s", err, models.ErrorCodeValidation)
+	}
+	if _, err := s.ListWebhookSubscriptions(models.PageRequest{Cursor: "bad"}); ParseCodeFromError(err) != models.ErrorCodeValidation {
+		t.Errorf("invalid subscription cursor: err %v, want %s", err, models.ErrorCodeValidation)
+	}
+}